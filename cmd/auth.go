@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mikesmitty/file-search/internal/auth"
+	"github.com/mikesmitty/file-search/internal/config"
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Store the API key in the system keyring or an encrypted file",
+}
+
+// AuthStatus describes the API key that commands will use
+type AuthStatus struct {
+	Account string `json:"account"`
+	Source  string `json:"source,omitempty"`
+	Key     string `json:"key,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+
+	// Auth login
+	var loginFile bool
+	var loginNoVerify bool
+	loginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Save an API key for the active profile",
+		Long: `Save an API key for the active profile (or "default") in the system keyring,
+falling back to a passphrase-encrypted file when no keyring is available.
+The key is read from the terminal without echo, or from stdin when piped:
+
+  file-search auth login
+  echo "$KEY" | file-search auth login --profile work
+
+Keys set with --api-key, --api-key-env, GOOGLE_API_KEY/GEMINI_API_KEY or
+api_key in a config file take precedence over the stored key.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := readSecret("Gemini API key: ")
+			if err != nil {
+				return err
+			}
+			if key == "" {
+				return fmt.Errorf("no API key given")
+			}
+			auth.RegisterSecret(key)
+
+			if !loginNoVerify {
+				ctx := context.Background()
+				client, err := gemini.NewClient(ctx, key, nil)
+				if err != nil {
+					return err
+				}
+				defer client.Close()
+				if _, err := client.ListStores(ctx); err != nil {
+					return fmt.Errorf("API key was rejected (use --no-verify to save it anyway): %w", err)
+				}
+			}
+
+			account := auth.Account(activeProfile())
+			if !loginFile {
+				err := auth.KeyringSet(account, key)
+				if err == nil {
+					if !quiet {
+						fmt.Printf("Saved API key for %s to the system keyring\n", account)
+					}
+					return nil
+				}
+				fmt.Fprintf(os.Stderr, "Warning: system keyring unavailable (%v); using an encrypted file instead\n", err)
+			}
+
+			passphrase, err := newPassphrase()
+			if err != nil {
+				return err
+			}
+			path, err := keyFilePath(account)
+			if err != nil {
+				return err
+			}
+			if err := auth.SaveFile(path, key, passphrase); err != nil {
+				return err
+			}
+			if !quiet {
+				fmt.Printf("Saved encrypted API key for %s to %s\n", account, path)
+			}
+			return nil
+		},
+	}
+	loginCmd.Flags().BoolVar(&loginFile, "file", false, "Save to an encrypted file instead of the system keyring")
+	loginCmd.Flags().BoolVar(&loginNoVerify, "no-verify", false, "Save the key without checking it against the API")
+	authCmd.AddCommand(loginCmd)
+
+	// Auth logout
+	authCmd.AddCommand(&cobra.Command{
+		Use:   "logout",
+		Short: "Remove the stored API key for the active profile",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			account := auth.Account(activeProfile())
+			removed := false
+
+			err := auth.KeyringDelete(account)
+			if err == nil {
+				removed = true
+				if !quiet {
+					fmt.Printf("Removed API key for %s from the system keyring\n", account)
+				}
+			} else if !errors.Is(err, auth.ErrNotFound) && verbose {
+				fmt.Fprintf(os.Stderr, "Warning: system keyring unavailable: %v\n", err)
+			}
+
+			path, err := keyFilePath(account)
+			if err != nil {
+				return err
+			}
+			err = auth.DeleteFile(path)
+			if err == nil {
+				removed = true
+				if !quiet {
+					fmt.Printf("Removed encrypted API key %s\n", path)
+				}
+			} else if !errors.Is(err, auth.ErrNotFound) {
+				return err
+			}
+
+			if !removed {
+				return fmt.Errorf("no stored API key for %s", account)
+			}
+			return nil
+		},
+	})
+
+	// Auth status
+	authCmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show which API key commands will use and where it comes from",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			status := &AuthStatus{Account: auth.Account(activeProfile())}
+			key, source, err := resolveAPIKey(term.IsTerminal(int(os.Stdin.Fd())))
+			if err != nil {
+				status.Error = err.Error()
+			} else {
+				status.Source = source
+				status.Key = auth.Mask(key)
+			}
+			if outputFormat == "json" {
+				return printOutput(status, outputFormat)
+			}
+
+			fmt.Printf("Account: %s\n", status.Account)
+			if status.Error != "" {
+				fmt.Printf("Not logged in: %s\n", status.Error)
+				return nil
+			}
+			fmt.Printf("Source: %s\n", status.Source)
+			fmt.Printf("Key: %s\n", status.Key)
+			return nil
+		},
+	})
+}
+
+// keyFilePath returns the encrypted key file for a keyring account
+func keyFilePath(account string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return auth.FilePath(config.Dir(home), account), nil
+}
+
+// newPassphrase returns the passphrase for a new encrypted key file, from
+// FILE_SEARCH_PASSPHRASE or a confirmed terminal prompt
+func newPassphrase() (string, error) {
+	if passphrase := os.Getenv("FILE_SEARCH_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("set FILE_SEARCH_PASSPHRASE to encrypt the API key non-interactively")
+	}
+	passphrase, err := readSecret("New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase must not be empty")
+	}
+	confirm, err := readSecret("Confirm passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm != passphrase {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// readSecret reads a line from the terminal without echo, prompting on
+// stderr, or the first line of stdin when it is not a terminal
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		secret, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(secret)), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read from stdin: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
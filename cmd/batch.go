@@ -2,12 +2,28 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"time"
 )
 
+// errSkipped marks a processor error as a deliberate skip rather than a failure
+var errSkipped = errors.New("skipped")
+
+// skipped wraps err so processBatch counts it as skipped
+func skipped(err error) error {
+	return skipError{err}
+}
+
+type skipError struct{ error }
+
+func (e skipError) Unwrap() error        { return e.error }
+func (e skipError) Is(target error) bool { return target == errSkipped }
+
 // BatchOptions provides configuration for batch processing.
 type BatchOptions struct {
-	Concurrency int // Number of parallel operations (default: 5)
+	Concurrency int           // Number of parallel operations (default: 5)
+	Interval    time.Duration // Minimum time between starting operations (0 = no limit)
 	Quiet       bool
 	OnProgress  func(current, total int, file string, err error)
 }
@@ -16,7 +32,9 @@ type BatchOptions struct {
 type BatchResult struct {
 	Succeeded []string
 	Failed    map[string]error
-	Total     int
+	// Skipped holds files whose processor returned a skipped error
+	Skipped map[string]error
+	Total   int
 }
 
 // processBatch processes a slice of files concurrently.
@@ -27,6 +45,7 @@ func processBatch(ctx context.Context, files []string, processor func(ctx contex
 	result := &BatchResult{
 		Succeeded: make([]string, 0),
 		Failed:    make(map[string]error),
+		Skipped:   make(map[string]error),
 		Total:     len(files),
 	}
 
@@ -49,7 +68,17 @@ func processBatch(ctx context.Context, files []string, processor func(ctx contex
 		processedCount int
 	)
 
-	for _, file := range files {
+	// Rate limit the start of each operation
+	var ticker *time.Ticker
+	if opts.Interval > 0 {
+		ticker = time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+	}
+
+	for i, file := range files {
+		if ticker != nil && i > 0 {
+			<-ticker.C
+		}
 		inProgress <- struct{}{} // Acquire a slot
 
 		wg.Add(1)
@@ -73,7 +102,9 @@ func processBatch(ctx context.Context, files []string, processor func(ctx contex
 			}
 
 			mu.Lock()
-			if err != nil {
+			if errors.Is(err, errSkipped) {
+				result.Skipped[f] = err
+			} else if err != nil {
 				result.Failed[f] = err
 			} else {
 				result.Succeeded = append(result.Succeeded, f)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -252,3 +253,44 @@ func TestProcessBatch_ContextCancellation(t *testing.T) {
 		}
 	})
 }
+
+func TestProcessBatch_Interval(t *testing.T) {
+	files := []string{"q1", "q2", "q3"}
+	var (
+		mu     sync.Mutex
+		starts []time.Time
+	)
+	processor := func(ctx context.Context, file string) error {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		return nil
+	}
+
+	result := processBatch(context.Background(), files, processor, &BatchOptions{Concurrency: 3, Interval: 30 * time.Millisecond})
+	if len(result.Succeeded) != len(files) {
+		t.Fatalf("Expected all files to succeed, got %d", len(result.Succeeded))
+	}
+	if elapsed := starts[len(starts)-1].Sub(starts[0]); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected starts to be spaced by the interval, last started after %v", elapsed)
+	}
+}
+
+func TestProcessBatchSkipped(t *testing.T) {
+	processor := func(ctx context.Context, file string) error {
+		switch file {
+		case "dup.pdf":
+			return skipped(fmt.Errorf("dup.pdf already exists"))
+		case "bad.pdf":
+			return fmt.Errorf("failed")
+		}
+		return nil
+	}
+	result := processBatch(context.Background(), []string{"new.pdf", "dup.pdf", "bad.pdf"}, processor, &BatchOptions{Quiet: true})
+	if len(result.Succeeded) != 1 || len(result.Failed) != 1 || len(result.Skipped) != 1 {
+		t.Fatalf("got %d succeeded, %d failed, %d skipped; want 1 each", len(result.Succeeded), len(result.Failed), len(result.Skipped))
+	}
+	if err := result.Skipped["dup.pdf"]; err == nil || err.Error() != "dup.pdf already exists" {
+		t.Errorf("skip reason = %v", err)
+	}
+}
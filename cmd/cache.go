@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/cache"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the query response cache",
+	Long: `Manage the on-disk cache of query responses.
+
+The cache is off by default. Enable it with cache.enabled in the config (or
+FILE_SEARCH_CACHE=true) to answer repeated questions from stored responses,
+for both the query command and the MCP server. Answers are keyed by the
+question (ignoring case and spacing), store, model, metadata filter and
+generation settings, expire after cache.ttl (default 24h), and are discarded
+as soon as the store is updated or its document counts change.`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache size and hits",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openResponseCache()
+		if err != nil {
+			return err
+		}
+		stats, err := c.Stats()
+		if err != nil {
+			return err
+		}
+		if outputFormat == "json" {
+			return printOutput(stats, outputFormat)
+		}
+
+		state := "disabled"
+		if viper.GetBool("cache.enabled") {
+			state = "enabled, TTL " + cacheTTL().String()
+		}
+		fmt.Printf("Cache: %s (%s)\n", stats.Dir, state)
+		fmt.Printf("Entries: %d (%d expired)\n", stats.Entries, stats.Expired)
+		fmt.Printf("Size: %d bytes\n", stats.Bytes)
+		fmt.Printf("Hits: %d\n", stats.Hits)
+		if stats.Entries > 0 {
+			fmt.Printf("Oldest: %s\n", stats.Oldest.Local().Format(time.DateTime))
+			fmt.Printf("Newest: %s\n", stats.Newest.Local().Format(time.DateTime))
+		}
+		return nil
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove cached responses",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openResponseCache()
+		if err != nil {
+			return err
+		}
+		removed, err := c.Clear(cacheClearExpired)
+		if err != nil {
+			return err
+		}
+		if !quiet {
+			fmt.Printf("Removed %d cached response(s)\n", removed)
+		}
+		return nil
+	},
+}
+
+var cacheClearExpired bool
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+
+	cacheClearCmd.Flags().BoolVar(&cacheClearExpired, "expired", false, "Only remove expired entries")
+}
+
+// cacheTTL returns the configured lifetime of cached responses
+func cacheTTL() time.Duration {
+	ttl := viper.GetDuration("cache.ttl")
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return ttl
+}
+
+// openResponseCache opens the response cache whether or not it is enabled
+func openResponseCache() (*cache.Cache, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return cache.New(dir, cacheTTL()), nil
+}
+
+// responseCache returns the response cache for clients, or nil if caching
+// is disabled
+func responseCache() *cache.Cache {
+	if !viper.GetBool("cache.enabled") {
+		return nil
+	}
+	c, err := openResponseCache()
+	if err != nil {
+		return nil
+	}
+	return c
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mikesmitty/file-search/internal/chunk"
+	"github.com/mikesmitty/file-search/internal/constants"
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"github.com/mikesmitty/file-search/internal/upload"
+	"github.com/spf13/cobra"
+)
+
+var chunkCmd = &cobra.Command{
+	Use:   "chunk",
+	Short: "Inspect how documents will be chunked",
+}
+
+func init() {
+	rootCmd.AddCommand(chunkCmd)
+
+	var previewChunkSize int
+	var previewChunkOverlap int
+	var previewModel string
+	var previewShow int
+	var previewOffline bool
+	var previewNoConvert bool
+	previewCmd := &cobra.Command{
+		Use:   "preview [path]",
+		Short: "Preview white-space chunking locally before uploading",
+		Long: `Preview approximates the chunks that --chunk-size and --chunk-overlap will
+produce for a store upload, without uploading anything.
+
+Text is split on whitespace locally. Unless --offline is set, the model's token
+counter is used to calibrate whitespace tokens against model tokens, so chunk
+sizes are reported in (approximate) model tokens.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			path := args[0]
+
+			// Preview what would actually be uploaded, after conversion
+			prepareOpts := &upload.Options{}
+			if !previewNoConvert {
+				registry, err := getConverterRegistry()
+				if err != nil {
+					return err
+				}
+				prepareOpts.Converters = registry
+			}
+			prepared, err := upload.Prepare(ctx, path, filepath.Base(path), prepareOpts)
+			if err != nil {
+				return err
+			}
+			defer prepared.Cleanup()
+
+			var client *gemini.Client
+			if !previewOffline {
+				client, err = getClient(ctx)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: token calibration unavailable (%v); counting whitespace tokens only\n", err)
+				} else {
+					defer client.Close()
+				}
+			}
+
+			var previews []*chunk.Preview
+			for _, item := range prepared.Items {
+				text, err := readPreviewText(item)
+				if err != nil {
+					return err
+				}
+
+				opts := &chunk.Options{MaxTokens: previewChunkSize, Overlap: previewChunkOverlap}
+				if client != nil {
+					count := func(ctx context.Context, s string) (int, error) {
+						return client.CountTokens(ctx, previewModel, s)
+					}
+					opts.TokensPerWord, err = chunk.Calibrate(ctx, text, count)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Warning: token calibration failed for %s: %v\n", item.DisplayName, err)
+					}
+				}
+
+				p, err := chunk.NewPreview(item.DisplayName, text, opts)
+				if err != nil {
+					return err
+				}
+				// Only the first chunks are shown; the stats cover all of them
+				if previewShow >= 0 && len(p.Chunks) > previewShow {
+					p.Chunks = p.Chunks[:previewShow]
+				}
+				previews = append(previews, p)
+			}
+
+			if len(previews) == 1 {
+				return printOutput(previews[0], outputFormat)
+			}
+			return printOutput(previews, outputFormat)
+		},
+	}
+	previewCmd.Flags().IntVar(&previewChunkSize, "chunk-size", 0, "Max tokens per chunk (required)")
+	previewCmd.Flags().IntVar(&previewChunkOverlap, "chunk-overlap", 0, "Overlap tokens between chunks")
+	previewCmd.Flags().StringVar(&previewModel, "model", constants.DefaultModel, "Model used for token counting")
+	previewCmd.Flags().IntVar(&previewShow, "show", 3, "Number of chunks to show boundaries for")
+	previewCmd.Flags().BoolVar(&previewOffline, "offline", false, "Count whitespace tokens only, without calling the API")
+	previewCmd.Flags().BoolVar(&previewNoConvert, "no-convert", false, "Preview the file as-is, skipping HTML/archive/notebook conversion")
+	previewCmd.MarkFlagRequired("chunk-size")
+	previewCmd.RegisterFlagCompletionFunc("model", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getCompleter().GetModelNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+	chunkCmd.AddCommand(previewCmd)
+}
+
+// readPreviewText reads a prepared upload as text. Binary formats such as PDF
+// are chunked after server-side text extraction, so they cannot be previewed.
+func readPreviewText(item *upload.Item) (string, error) {
+	mimeType := item.MIMEType
+	if mimeType == "" {
+		mimeType = gemini.DetectMIMEType(item.Path)
+	}
+	data, err := os.ReadFile(item.Path)
+	if err != nil {
+		return "", err
+	}
+	if !isTextMIMEType(mimeType) || !utf8.Valid(data) {
+		return "", fmt.Errorf("cannot preview %s (%s): only text formats can be chunked locally", item.DisplayName, mimeType)
+	}
+	return string(data), nil
+}
+
+func isTextMIMEType(mimeType string) bool {
+	if strings.HasPrefix(mimeType, "text/") {
+		return true
+	}
+	switch mimeType {
+	case "application/json", "application/xml", "application/x-yaml", "application/typescript", "application/javascript":
+		return true
+	}
+	return false
+}
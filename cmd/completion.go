@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var completionRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Warm the completion cache",
+	Long: `List stores, files and the documents of every store and save them to the
+completion cache, so the next TAB completions don't wait on the API.
+
+Entries expire after completion_cache_ttl (default 300s); commands that
+create or delete stores, files or documents drop the affected entries.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !viper.GetBool("completion_enabled") {
+			return fmt.Errorf("completion is disabled (completion_enabled is false)")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		completer := getCompleter()
+		defer completer.Close()
+		if err := completer.Refresh(ctx); err != nil {
+			return err
+		}
+		if !quiet {
+			fmt.Println("Completion cache refreshed")
+		}
+		return nil
+	},
+}
+
+// addCompletionRefresh adds refresh to cobra's default completion command,
+// which only exists once it has been initialized
+func addCompletionRefresh() {
+	rootCmd.InitDefaultCompletionCmd()
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == "completion" {
+			if !hasSubcommand(c, completionRefreshCmd.Name()) {
+				c.AddCommand(completionRefreshCmd)
+			}
+			return
+		}
+	}
+}
+
+func hasSubcommand(parent *cobra.Command, name string) bool {
+	for _, c := range parent.Commands() {
+		if c.Name() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mikesmitty/file-search/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and edit configuration and profiles",
+	// Config commands must keep working when the config itself is broken
+	// (e.g. current_profile names a missing profile), so errors are warnings here.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if configErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", configErr)
+		}
+		return nil
+	},
+}
+
+// ConfigView is the effective configuration shown by "config view"
+type ConfigView struct {
+	Profile  string         `json:"profile,omitempty"`
+	Files    []string       `json:"files"`
+	Settings map[string]any `json:"settings"`
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+
+	// Config view
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "view",
+		Short: "Show the effective configuration after merging files and the active profile",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Never echo API keys, including profiles' own
+			settings, _ := config.Redact(viper.AllSettings()).(map[string]any)
+			view := &ConfigView{
+				Profile:  activeProfile(),
+				Files:    configFiles,
+				Settings: settings,
+			}
+			if outputFormat == "json" {
+				return printOutput(view, outputFormat)
+			}
+
+			if view.Profile != "" {
+				fmt.Printf("# Profile: %s\n", view.Profile)
+			}
+			if len(view.Files) == 0 {
+				fmt.Println("# Files: none")
+			}
+			for _, f := range view.Files {
+				fmt.Printf("# File: %s\n", f)
+			}
+			out, err := config.MarshalYAML(view.Settings)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(out))
+			return nil
+		},
+	})
+
+	// Config get
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "get [key]",
+		Short: "Print the effective value of a config key (e.g. default_model)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !viper.IsSet(args[0]) {
+				return fmt.Errorf("%s is not set", args[0])
+			}
+			value := config.Redact(viper.Get(args[0]))
+			if config.IsSecret(args[0]) {
+				value = "(set)"
+			}
+			if outputFormat == "json" {
+				return printOutput(value, outputFormat)
+			}
+			switch value.(type) {
+			case map[string]any, []any:
+				out, err := config.MarshalYAML(value)
+				if err != nil {
+					return err
+				}
+				fmt.Print(string(out))
+			default:
+				fmt.Println(value)
+			}
+			return nil
+		},
+	})
+
+	// Config set
+	var setProject bool
+	configSetCmd := &cobra.Command{
+		Use:   "set [key] [value]",
+		Short: "Set a config key in the user (or project) config file",
+		Long: `Set a config key, creating the file if needed. Nested keys use dots, and
+values are parsed as YAML, so numbers, booleans and lists keep their type:
+
+  file-search config set default_model gemini-2.5-pro
+  file-search config set profiles.work.api_key_env WORK_GEMINI_KEY
+  file-search config set profiles.work.mcp_tools "[query_knowledge_base, list_stores]"`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := configTargetFile(setProject)
+			if err != nil {
+				return err
+			}
+			if err := config.SetValue(path, args[0], args[1]); err != nil {
+				return err
+			}
+			if !quiet {
+				fmt.Printf("Set %s in %s\n", args[0], path)
+			}
+			return nil
+		},
+	}
+	configSetCmd.Flags().BoolVar(&setProject, "project", false, "Write to the project .file-search.yaml instead of the user config")
+	configCmd.AddCommand(configSetCmd)
+
+	// Config use-profile
+	var useProject bool
+	useProfileCmd := &cobra.Command{
+		Use:   "use-profile [name]",
+		Short: "Make a profile the default for subsequent commands",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return profileNames(), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if _, ok := profileSettings(name); !ok {
+				return fmt.Errorf("profile %q not found in config (available: %v)", name, profileNames())
+			}
+			path, err := configTargetFile(useProject)
+			if err != nil {
+				return err
+			}
+			if err := config.SetValue(path, "current_profile", name); err != nil {
+				return err
+			}
+			if !quiet {
+				fmt.Printf("Using profile %s (saved to %s)\n", name, path)
+			}
+			return nil
+		},
+	}
+	useProfileCmd.Flags().BoolVar(&useProject, "project", false, "Save the selection in the project .file-search.yaml")
+	configCmd.AddCommand(useProfileCmd)
+
+	rootCmd.RegisterFlagCompletionFunc("profile", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return profileNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// configTargetFile returns the config file that config set/use-profile write to:
+// the --config file if given, the nearest project file (or ./.file-search.yaml)
+// with --project, and the user-level config otherwise.
+func configTargetFile(project bool) (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if !project {
+		return config.GlobalConfigPath(home), nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	if path := config.FindProjectConfig(cwd, home); path != "" {
+		return path, nil
+	}
+	return filepath.Join(cwd, config.FileName), nil
+}
+
+// profileNames returns the names of the profiles defined in the config
+func profileNames() []string {
+	names := make([]string, 0)
+	for name := range viper.GetStringMap("profiles") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
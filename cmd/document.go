@@ -3,7 +3,9 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"github.com/mikesmitty/file-search/internal/completion"
 	"github.com/mikesmitty/file-search/internal/constants"
 	"github.com/spf13/cobra"
 )
@@ -25,8 +27,12 @@ func init() {
 		Aliases: []string{"ls"},
 		Short:   "List documents in a store",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if docListStore == "" && docListStoreID == "" {
-				return fmt.Errorf("either --store or --store-id is required")
+			storeName, storeID, err := withDefaultStore(docListStore, docListStoreID)
+			if err != nil {
+				return err
+			}
+			if storeName == "" && storeID == "" {
+				return fmt.Errorf("either --store or --store-id is required (or set a default store)")
 			}
 			ctx := context.Background()
 			client, err := getClient(ctx)
@@ -36,9 +42,8 @@ func init() {
 			defer client.Close()
 
 			// Resolve store name to ID if --store was used
-			storeID := docListStoreID
-			if docListStore != "" {
-				storeID, err = client.ResolveStoreName(ctx, docListStore)
+			if storeName != "" {
+				storeID, err = client.ResolveStoreName(ctx, storeName)
 				if err != nil {
 					return err
 				}
@@ -54,10 +59,10 @@ func init() {
 	docListCmd.Flags().StringVar(&docListStore, "store", "", "Store display name")
 	docListCmd.Flags().StringVar(&docListStoreID, "store-id", "", "Store resource ID ("+constants.StoreResourcePrefix+"xxx)")
 	docListCmd.RegisterFlagCompletionFunc("store", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+		return getCompleter().GetStoreNames(toComplete), cobra.ShellCompDirectiveNoFileComp
 	})
 	docListCmd.RegisterFlagCompletionFunc("store-id", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+		return getCompleter().GetStoreIDs(toComplete), cobra.ShellCompDirectiveNoFileComp
 	})
 	documentCmd.AddCommand(docListCmd)
 
@@ -71,11 +76,11 @@ func init() {
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			storeFlag, _ := cmd.Flags().GetString("store")
 			if storeFlag != "" {
-				return getCompleter().GetDocumentNames(storeFlag), cobra.ShellCompDirectiveNoFileComp
+				return getCompleter().GetDocumentNames(storeFlag, toComplete), cobra.ShellCompDirectiveNoFileComp
 			}
 			storeIDFlag, _ := cmd.Flags().GetString("store-id")
 			if storeIDFlag != "" {
-				return getCompleter().GetDocumentNames(storeIDFlag), cobra.ShellCompDirectiveNoFileComp
+				return getCompleter().GetDocumentNames(storeIDFlag, toComplete), cobra.ShellCompDirectiveNoFileComp
 			}
 			return []string{}, cobra.ShellCompDirectiveNoFileComp
 		},
@@ -87,12 +92,20 @@ func init() {
 			}
 			defer client.Close()
 
-			// If store is provided, resolve document name within that store
+			// If store is provided, resolve document name within that store.
+			// The default store is only consulted for bare display names.
 			docID := args[0]
-			if docGetStore != "" || docGetStoreID != "" {
-				storeRef := docGetStoreID
-				if docGetStore != "" {
-					storeRef = docGetStore
+			storeName, storeID := docGetStore, docGetStoreID
+			if !strings.Contains(docID, constants.DocumentResourcePrefix) {
+				storeName, storeID, err = withDefaultStore(storeName, storeID)
+				if err != nil {
+					return err
+				}
+			}
+			if storeName != "" || storeID != "" {
+				storeRef := storeID
+				if storeName != "" {
+					storeRef = storeName
 				}
 				docID, err = client.ResolveDocumentName(ctx, storeRef, args[0])
 				if err != nil {
@@ -107,13 +120,13 @@ func init() {
 			return printOutput(doc, outputFormat)
 		},
 	}
-	docGetCmd.Flags().StringVar(&docGetStore, "store", "", "Store display name (optional, for name resolution)")
-	docGetCmd.Flags().StringVar(&docGetStoreID, "store-id", "", "Store resource ID (optional, for name resolution)")
+	docGetCmd.Flags().StringVar(&docGetStore, "store", "", "Store display name (optional, for name resolution; defaults to the project store)")
+	docGetCmd.Flags().StringVar(&docGetStoreID, "store-id", "", "Store resource ID (optional, for name resolution; defaults to the project store)")
 	docGetCmd.RegisterFlagCompletionFunc("store", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+		return getCompleter().GetStoreNames(toComplete), cobra.ShellCompDirectiveNoFileComp
 	})
 	docGetCmd.RegisterFlagCompletionFunc("store-id", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+		return getCompleter().GetStoreIDs(toComplete), cobra.ShellCompDirectiveNoFileComp
 	})
 	documentCmd.AddCommand(docGetCmd)
 
@@ -129,11 +142,11 @@ func init() {
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			storeFlag, _ := cmd.Flags().GetString("store")
 			if storeFlag != "" {
-				return getCompleter().GetDocumentNames(storeFlag), cobra.ShellCompDirectiveNoFileComp
+				return getCompleter().GetDocumentNames(storeFlag, toComplete), cobra.ShellCompDirectiveNoFileComp
 			}
 			storeIDFlag, _ := cmd.Flags().GetString("store-id")
 			if storeIDFlag != "" {
-				return getCompleter().GetDocumentNames(storeIDFlag), cobra.ShellCompDirectiveNoFileComp
+				return getCompleter().GetDocumentNames(storeIDFlag, toComplete), cobra.ShellCompDirectiveNoFileComp
 			}
 			return []string{}, cobra.ShellCompDirectiveNoFileComp
 		},
@@ -145,12 +158,20 @@ func init() {
 			}
 			defer client.Close()
 
-			// If store is provided, resolve document name within that store
+			// If store is provided, resolve document name within that store.
+			// The default store is only consulted for bare display names.
 			docID := args[0]
-			if docDelStore != "" || docDelStoreID != "" {
-				storeRef := docDelStoreID
-				if docDelStore != "" {
-					storeRef = docDelStore
+			storeName, storeID := docDelStore, docDelStoreID
+			if !strings.Contains(docID, constants.DocumentResourcePrefix) {
+				storeName, storeID, err = withDefaultStore(storeName, storeID)
+				if err != nil {
+					return err
+				}
+			}
+			if storeName != "" || storeID != "" {
+				storeRef := storeID
+				if storeName != "" {
+					storeRef = storeName
 				}
 				docID, err = client.ResolveDocumentName(ctx, storeRef, args[0])
 				if err != nil {
@@ -162,6 +183,7 @@ func init() {
 			if err != nil {
 				return err
 			}
+			invalidateCompletions(completion.KeyStores, completion.KeyDocuments)
 			if outputFormat == "json" {
 				return printOutput(map[string]string{"status": "deleted", "document": docID}, "json")
 			}
@@ -169,14 +191,14 @@ func init() {
 			return nil
 		},
 	}
-	docDelCmd.Flags().StringVar(&docDelStore, "store", "", "Store display name (optional, for name resolution)")
-	docDelCmd.Flags().StringVar(&docDelStoreID, "store-id", "", "Store resource ID (optional, for name resolution)")
+	docDelCmd.Flags().StringVar(&docDelStore, "store", "", "Store display name (optional, for name resolution; defaults to the project store)")
+	docDelCmd.Flags().StringVar(&docDelStoreID, "store-id", "", "Store resource ID (optional, for name resolution; defaults to the project store)")
 	docDelCmd.Flags().BoolVar(&docDelForce, "force", false, "Force delete even if document contains chunks")
 	docDelCmd.RegisterFlagCompletionFunc("store", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+		return getCompleter().GetStoreNames(toComplete), cobra.ShellCompDirectiveNoFileComp
 	})
 	docDelCmd.RegisterFlagCompletionFunc("store-id", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+		return getCompleter().GetStoreIDs(toComplete), cobra.ShellCompDirectiveNoFileComp
 	})
 	documentCmd.AddCommand(docDelCmd)
 }
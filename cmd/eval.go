@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/constants"
+	"github.com/mikesmitty/file-search/internal/eval"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Evaluate retrieval and answer quality",
+}
+
+var evalRunCmd = &cobra.Command{
+	Use:   "run <suite.yaml>",
+	Short: "Run an evaluation suite",
+	Long: `Run each case of an evaluation suite and score the answers.
+
+A suite lists questions with the documents (and pages) that should ground
+them and the facts or regular expressions the answer should contain:
+
+  name: datasheets
+  store: Datasheets
+  cases:
+    - name: lm317-vin
+      question: What is the maximum input voltage of the LM317?
+      expect:
+        sources:
+          - document: lm317.pdf
+            pages: [3]
+        facts: ["40 V"]
+        patterns: ['\b40\s*V\b']
+
+The run reports the retrieval hit rate (cases where an expected source was
+retrieved), citation precision (cited sources that were expected) and answer
+pass rate, compared against --baseline when given.
+
+The command exits with an error when a case could not be run, when
+--min-pass-rate is set and fewer cases passed, or with --fail-on-regression
+when the results are worse than the baseline.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		suite, err := eval.Load(args[0])
+		if err != nil {
+			return err
+		}
+		if evalMinPassRate < 0 || evalMinPassRate > 100 {
+			return fmt.Errorf("--min-pass-rate must be between 0 and 100, got %g", evalMinPassRate)
+		}
+		var baseline *eval.Result
+		if evalBaseline != "" {
+			baseline, err = eval.LoadResult(evalBaseline)
+			if err != nil {
+				return err
+			}
+		}
+		queryOpts, err := queryDefaults()
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		client, err := getClient(ctx)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		// Flags override the suite, which overrides the default store and model
+		storeName, storeID := evalStoreName, evalStoreID
+		if storeName == "" && storeID == "" {
+			storeName = suite.Store
+		}
+		storeName, storeID, err = withDefaultStore(storeName, storeID)
+		if err != nil {
+			return err
+		}
+		store := storeName
+		if storeName != "" {
+			storeID, err = client.ResolveStoreName(ctx, storeName)
+			if err != nil {
+				return err
+			}
+		} else {
+			store = storeID
+		}
+		model := evalModel
+		if model == "" {
+			model = suite.Model
+		}
+		if model == "" {
+			model = viper.GetString("default_model")
+		}
+		if model == "" {
+			model = constants.DefaultModel
+		}
+
+		keys := make([]string, len(suite.Cases))
+		results := make([]eval.CaseResult, len(suite.Cases))
+		for i := range suite.Cases {
+			keys[i] = strconv.Itoa(i)
+		}
+		processor := func(ctx context.Context, key string) error {
+			i, _ := strconv.Atoi(key)
+			c := suite.Cases[i]
+			caseOpts := *queryOpts
+			// Every case is answered afresh so runs measure the current setup
+			caseOpts.NoCache = true
+			caseOpts.MetadataFilter = suite.MetadataFilter
+			if evalMetadataFilter != "" {
+				caseOpts.MetadataFilter = evalMetadataFilter
+			}
+			if c.MetadataFilter != "" {
+				caseOpts.MetadataFilter = c.MetadataFilter
+			}
+
+			start := time.Now()
+			resp, err := client.Query(ctx, c.Question, storeID, model, &caseOpts)
+			if err != nil {
+				results[i] = eval.Failed(c, err, time.Since(start))
+				return err
+			}
+			results[i] = eval.Score(c, resp, time.Since(start))
+			return nil
+		}
+		onProgress := func(current, total int, key string, err error) {
+			i, _ := strconv.Atoi(key)
+			status := "✓"
+			if !results[i].Passed() {
+				status = "✗"
+			}
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s %s\n", current, total, status, results[i].Name)
+		}
+
+		var interval time.Duration
+		if evalRPM > 0 {
+			interval = time.Minute / time.Duration(evalRPM)
+		}
+		processBatch(ctx, keys, processor, &BatchOptions{
+			Concurrency: evalConcurrency,
+			Interval:    interval,
+			Quiet:       quiet,
+			OnProgress:  onProgress,
+		})
+
+		result := eval.Summarize(suite.Name, store, model, results)
+		var cmp *eval.Comparison
+		if baseline != nil {
+			cmp = eval.Compare(baseline, result)
+		}
+
+		if evalJUnit != "" {
+			f, err := os.Create(evalJUnit)
+			if err != nil {
+				return err
+			}
+			if err := eval.WriteJUnit(f, result); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+		if evalSaveBaseline != "" {
+			if err := result.Save(evalSaveBaseline); err != nil {
+				return err
+			}
+		}
+
+		if outputFormat == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(struct {
+				*eval.Result
+				Comparison *eval.Comparison `json:"comparison,omitempty"`
+			}{result, cmp}); err != nil {
+				return err
+			}
+		} else {
+			if !quiet {
+				fmt.Println()
+			}
+			if err := eval.WriteSummary(os.Stdout, result, cmp); err != nil {
+				return err
+			}
+		}
+
+		m := result.Metrics
+		if m.Errors > 0 {
+			return fmt.Errorf("%d of %d cases failed to run", m.Errors, m.Cases)
+		}
+		if evalMinPassRate > 0 && m.PassRate()*100 < evalMinPassRate {
+			return fmt.Errorf("%d of %d cases passed (%.1f%%), below --min-pass-rate %g%%", m.Passed, m.Cases, m.PassRate()*100, evalMinPassRate)
+		}
+		if evalFailOnRegression && cmp != nil && cmp.Regressed(result) {
+			return fmt.Errorf("results regressed against baseline %s", evalBaseline)
+		}
+		return nil
+	},
+}
+
+var (
+	evalStoreName        string
+	evalStoreID          string
+	evalModel            string
+	evalMetadataFilter   string
+	evalBaseline         string
+	evalSaveBaseline     string
+	evalJUnit            string
+	evalConcurrency      int
+	evalRPM              int
+	evalMinPassRate      float64
+	evalFailOnRegression bool
+)
+
+func init() {
+	rootCmd.AddCommand(evalCmd)
+	evalCmd.AddCommand(evalRunCmd)
+
+	evalRunCmd.Flags().StringVar(&evalStoreName, "store", "", "Store display name (default: the suite's store, then the project store)")
+	evalRunCmd.Flags().StringVar(&evalStoreID, "store-id", "", "Store resource ID ("+constants.StoreResourcePrefix+"xxx)")
+	evalRunCmd.Flags().StringVar(&evalModel, "model", "", "Model name (default: the suite's model, then default_model)")
+	evalRunCmd.Flags().StringVar(&evalMetadataFilter, "metadata-filter", "", "Metadata filter for cases without their own")
+	evalRunCmd.Flags().StringVar(&evalBaseline, "baseline", "", "Compare against a result saved with --save-baseline")
+	evalRunCmd.Flags().StringVar(&evalSaveBaseline, "save-baseline", "", "Save the result as JSON for later comparison")
+	evalRunCmd.Flags().StringVar(&evalJUnit, "junit", "", "Write a JUnit XML report")
+	evalRunCmd.Flags().IntVar(&evalConcurrency, "concurrency", 5, "Number of parallel queries")
+	evalRunCmd.Flags().IntVar(&evalRPM, "rpm", 60, "Maximum queries started per minute (0 for no limit)")
+	evalRunCmd.Flags().Float64Var(&evalMinPassRate, "min-pass-rate", 0, "Exit with an error if fewer than this percentage of cases pass")
+	evalRunCmd.Flags().BoolVar(&evalFailOnRegression, "fail-on-regression", false, "Exit with an error if a case or metric is worse than the baseline")
+	evalRunCmd.RegisterFlagCompletionFunc("store", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getCompleter().GetStoreNames(toComplete), cobra.ShellCompDirectiveNoFileComp
+	})
+	evalRunCmd.RegisterFlagCompletionFunc("model", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getCompleter().GetModelNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+}
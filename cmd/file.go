@@ -2,12 +2,19 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/mikesmitty/file-search/internal/completion"
+	"github.com/mikesmitty/file-search/internal/config"
 	"github.com/mikesmitty/file-search/internal/constants"
+	"github.com/mikesmitty/file-search/internal/convert"
 	"github.com/mikesmitty/file-search/internal/gemini"
+	"github.com/mikesmitty/file-search/internal/split"
+	"github.com/mikesmitty/file-search/internal/upload"
 	"github.com/spf13/cobra"
 )
 
@@ -45,7 +52,7 @@ func init() {
 		Short: "Get details of a file",
 		Args:  cobra.ExactArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-			return getCompleter().GetFileNames(), cobra.ShellCompDirectiveNoFileComp
+			return getCompleter().GetFileNames(toComplete), cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
@@ -76,7 +83,7 @@ func init() {
 		Short:   "Delete a file",
 		Args:    cobra.ExactArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-			return getCompleter().GetFileNames(), cobra.ShellCompDirectiveNoFileComp
+			return getCompleter().GetFileNames(toComplete), cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
@@ -96,6 +103,7 @@ func init() {
 			if err != nil {
 				return err
 			}
+			invalidateCompletions(completion.KeyFiles)
 			if outputFormat == "json" {
 				return printOutput(map[string]string{"status": "deleted", "file": fileID}, "json")
 			}
@@ -113,6 +121,13 @@ func init() {
 	var uploadChunkOverlap int
 	var uploadMetadata []string
 	var uploadConcurrency int
+	var uploadNoConvert bool
+	var uploadSplit bool
+	var uploadSplitSize string
+	var uploadSplitPages int
+	var uploadNoStore bool
+	var uploadOnDuplicate string
+	var uploadRecordSource bool
 	uploadCmd := &cobra.Command{
 		Use:   "upload [path]...",
 		Short: "Upload and import files",
@@ -138,13 +153,80 @@ func init() {
 				}
 			}
 
-			// Resolve store name to ID if --store was used
-			storeID := uploadStoreID
-			if uploadStoreName != "" {
-				storeID, err = client.ResolveStoreName(ctx, uploadStoreName)
+			// Resolve store name to ID if --store was used. --no-store skips the
+			// default store for a plain Files API upload.
+			storeName, storeID := uploadStoreName, uploadStoreID
+			if uploadNoStore {
+				if storeName != "" || storeID != "" {
+					return fmt.Errorf("cannot use --no-store with --store or --store-id")
+				}
+			} else {
+				storeName, storeID, err = withDefaultStore(storeName, storeID)
+				if err != nil {
+					return err
+				}
+			}
+			if storeName != "" {
+				storeID, err = client.ResolveStoreName(ctx, storeName)
+				if err != nil {
+					return err
+				}
+			}
+
+			// Apply the store's configured defaults under any explicit flags
+			var storeProfile *config.StoreProfile
+			chunkSize, chunkOverlap := uploadChunkSize, uploadChunkOverlap
+			if storeID != "" {
+				_, profile, err := lookupStoreProfile(ctx, client, storeID, storeName)
 				if err != nil {
 					return err
 				}
+				if profile != nil {
+					if !cmd.Flags().Changed("chunk-size") {
+						chunkSize = profile.ChunkSize
+					}
+					if !cmd.Flags().Changed("chunk-overlap") {
+						chunkOverlap = profile.ChunkOverlap
+					}
+					metadataMap = profile.MergeMetadata(metadataMap)
+
+					var allowed []string
+					for _, path := range args {
+						if profile.Allows(path) {
+							allowed = append(allowed, path)
+						} else if !quiet {
+							fmt.Printf("[-] Skipping %s (excluded by store config)\n", path)
+						}
+					}
+					if len(allowed) == 0 {
+						return fmt.Errorf("all files were excluded by the include/exclude patterns configured for this store")
+					}
+					args = allowed
+					storeProfile = profile
+				}
+			}
+
+			onDuplicate, err := duplicatePolicy(cmd, uploadOnDuplicate, storeProfile)
+			if err != nil {
+				return err
+			}
+
+			prepareOpts := &upload.Options{RecordSource: uploadRecordSource}
+			if !cmd.Flags().Changed("record-source") && storeProfile != nil {
+				prepareOpts.RecordSource = storeProfile.RecordSource
+			}
+			if !uploadNoConvert {
+				prepareOpts.Converters, err = getConverterRegistry()
+				if err != nil {
+					return err
+				}
+			}
+			if uploadSplit {
+				maxBytes, err := parseByteSize(uploadSplitSize)
+				if err != nil {
+					return fmt.Errorf("invalid --split-size: %w", err)
+				}
+				prepareOpts.Split = &split.Options{MaxBytes: maxBytes, MaxPages: uploadSplitPages}
 			}
 
 			// Define the processor function for a single file
@@ -154,26 +236,59 @@ func init() {
 					displayName = filepath.Base(path)
 				}
 
-				if !quiet {
-					fmt.Printf("[+] Starting upload: %s\n", displayName)
+				// Convert unsupported formats and split oversized files
+				prepared, err := upload.Prepare(ctx, path, displayName, prepareOpts)
+				if err != nil {
+					return err
 				}
+				defer prepared.Cleanup()
+
+				var skips []error
+				for _, item := range prepared.Items {
+					if !quiet {
+						fmt.Printf("[+] Starting upload: %s\n", item.DisplayName)
+					}
+
+					mimeType := uploadMimeType
+					if mimeType == "" {
+						mimeType = storeProfile.MIMETypeFor(item.Path)
+					}
+					if mimeType == "" {
+						mimeType = item.MIMEType
+					}
 
-				opts := &gemini.UploadFileOptions{
-					StoreName:      storeID,
-					DisplayName:    displayName,
-					MIMEType:       uploadMimeType,
-					MaxChunkTokens: uploadChunkSize,
-					ChunkOverlap:   uploadChunkOverlap,
-					Metadata:       metadataMap,
-					Quiet:          true, // Force quiet for inner operation to prevent output interleaving
+					opts := &gemini.UploadFileOptions{
+						StoreName:      storeID,
+						DisplayName:    item.DisplayName,
+						MIMEType:       mimeType,
+						MaxChunkTokens: chunkSize,
+						ChunkOverlap:   chunkOverlap,
+						Metadata:       convert.WithProvenance(metadataMap, item.Metadata),
+						Quiet:          true, // Force quiet for inner operation to prevent output interleaving
+						OnDuplicate:    onDuplicate,
+					}
+					if _, err := client.UploadFile(ctx, item.Path, opts); errors.Is(err, gemini.ErrDuplicateSkipped) {
+						skips = append(skips, err)
+					} else if err != nil {
+						return fmt.Errorf("%s: %w", item.DisplayName, err)
+					}
 				}
-				_, err := client.UploadFile(ctx, path, opts)
-				return err
+				if len(skips) == len(prepared.Items) {
+					return skipped(errors.Join(skips...))
+				}
+				if !quiet {
+					for _, err := range skips {
+						fmt.Printf("[-] Skipped %v\n", err)
+					}
+				}
+				return nil
 			}
 
 			// Define the progress callback
 			onProgress := func(current, total int, file string, err error) {
-				if err != nil {
+				if errors.Is(err, errSkipped) {
+					fmt.Printf("[%d/%d] - Skipped: %s (%v)\n", current, total, filepath.Base(file), err)
+				} else if err != nil {
 					fmt.Printf("[%d/%d] ✗ Failed: %s (%v)\n", current, total, filepath.Base(file), err)
 				} else {
 					fmt.Printf("[%d/%d] ✓ Finished: %s\n", current, total, filepath.Base(file))
@@ -186,6 +301,9 @@ func init() {
 				Quiet:       quiet,
 				OnProgress:  onProgress,
 			})
+			if len(batchResult.Succeeded) > 0 {
+				invalidateCompletions(completion.KeyFiles, completion.KeyStores, completion.KeyDocuments)
+			}
 
 			// Print summary
 			if !quiet {
@@ -193,6 +311,9 @@ func init() {
 					fmt.Printf("\n\nSummary:\n")
 					fmt.Printf("  ✓ Succeeded: %d\n", len(batchResult.Succeeded))
 					fmt.Printf("  ✗ Failed: %d\n", len(batchResult.Failed))
+					if len(batchResult.Skipped) > 0 {
+						fmt.Printf("  - Skipped: %d\n", len(batchResult.Skipped))
+					}
 				}
 			}
 
@@ -202,11 +323,15 @@ func init() {
 				jsonResult["total"] = batchResult.Total
 				jsonResult["succeeded"] = len(batchResult.Succeeded)
 				jsonResult["failed"] = len(batchResult.Failed)
+				jsonResult["skipped"] = len(batchResult.Skipped)
 
 				filesSummary := make([]map[string]interface{}, 0, batchResult.Total)
 				for _, f := range batchResult.Succeeded {
 					filesSummary = append(filesSummary, map[string]interface{}{"file": f, "status": "success"})
 				}
+				for f, err := range batchResult.Skipped {
+					filesSummary = append(filesSummary, map[string]interface{}{"file": f, "status": "skipped", "reason": err.Error()})
+				}
 				for f, err := range batchResult.Failed {
 					filesSummary = append(filesSummary, map[string]interface{}{"file": f, "status": "failed", "error": err.Error()})
 				}
@@ -232,19 +357,52 @@ func init() {
 			return nil
 		},
 	}
-	uploadCmd.Flags().StringVar(&uploadStoreName, "store", "", "Store display name (optional)")
+	uploadCmd.Flags().StringVar(&uploadStoreName, "store", "", "Store display name (optional, defaults to the project store)")
 	uploadCmd.Flags().StringVar(&uploadStoreID, "store-id", "", "Store resource ID (optional, "+constants.StoreResourcePrefix+"xxx)")
+	uploadCmd.Flags().BoolVar(&uploadNoStore, "no-store", false, "Upload to the Files API only, ignoring any default store")
 	uploadCmd.Flags().StringVar(&uploadDisplayName, "name", "", "Display name (optional)")
 	uploadCmd.Flags().StringVar(&uploadMimeType, "mime-type", "", "MIME type (optional, e.g. text/plain, application/pdf)")
 	uploadCmd.Flags().IntVar(&uploadChunkSize, "chunk-size", 0, "Max tokens per chunk (for store uploads)")
 	uploadCmd.Flags().IntVar(&uploadChunkOverlap, "chunk-overlap", 0, "Overlap tokens between chunks (for store uploads)")
 	uploadCmd.Flags().StringArrayVar(&uploadMetadata, "metadata", []string{}, "Custom metadata as key=value (repeatable, for store uploads)")
 	uploadCmd.Flags().IntVar(&uploadConcurrency, "concurrency", 5, "Number of parallel uploads")
+	uploadCmd.Flags().BoolVar(&uploadSplit, "split", false, "Split large text/Markdown files on headings and large PDFs by page ranges into separate documents")
+	uploadCmd.Flags().StringVar(&uploadSplitSize, "split-size", "20MB", "Maximum size of each part when splitting (e.g. 512KB, 20MB)")
+	uploadCmd.Flags().IntVar(&uploadSplitPages, "split-pages", 0, "Maximum pages per PDF part when splitting (0 derives it from --split-size)")
+	uploadCmd.Flags().BoolVar(&uploadNoConvert, "no-convert", false, "Upload files as-is, skipping HTML/archive/notebook conversion")
+	uploadCmd.Flags().StringVar(&uploadOnDuplicate, "on-duplicate", string(gemini.DuplicateSkip), "When the store has a document with the same name or content: skip (same content only; a changed file under an existing name fails), replace, keep or error")
+	uploadCmd.RegisterFlagCompletionFunc("on-duplicate", completeDuplicatePolicy)
+	uploadCmd.Flags().BoolVar(&uploadRecordSource, "record-source", false, "Record each file's absolute local path as source_path metadata, for store doctor --retry")
 	uploadCmd.RegisterFlagCompletionFunc("store", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+		return getCompleter().GetStoreNames(toComplete), cobra.ShellCompDirectiveNoFileComp
 	})
 	uploadCmd.RegisterFlagCompletionFunc("store-id", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+		return getCompleter().GetStoreIDs(toComplete), cobra.ShellCompDirectiveNoFileComp
 	})
 	fileCmd.AddCommand(uploadCmd)
 }
+
+// parseByteSize parses sizes such as "512KB", "20MB" or "1048576" into bytes.
+// Units are binary (1KB = 1024 bytes).
+func parseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			multiplier = unit.size
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("expected a positive size such as 20MB, got %q", s)
+	}
+	return n * multiplier, nil
+}
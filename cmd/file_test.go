@@ -79,3 +79,31 @@ func TestParseMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"1048576", 1 << 20, false},
+		{"20MB", 20 << 20, false},
+		{"512kb", 512 << 10, false},
+		{"1 GB", 1 << 30, false},
+		{"100B", 100, false},
+		{"", 0, true},
+		{"-5MB", 0, true},
+		{"lots", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseByteSize(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseByteSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseByteSize(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"os"
 
 	"github.com/mikesmitty/file-search/internal/gemini"
 	"github.com/mikesmitty/file-search/internal/mcp"
@@ -16,11 +18,16 @@ var mcpCmd = &cobra.Command{
 		ctx := context.Background()
 		// For MCP, we start the server even without API key configured.
 		// Tools will fail gracefully when invoked if auth is missing.
-		key, _ := getAPIKey()
+		opts, optsErr := getClientOptions(false)
 
 		var client mcp.GeminiClient
-		if key != "" {
-			c, err := gemini.NewClient(ctx, key, nil)
+		if optsErr == nil {
+			opts.OnUsage = logUsage(opts.OnUsage)
+			// The server outlives changes made by other clients, so names
+			// resolved from an old listing are listed again
+			opts.NameCacheTTL = viper.GetDuration("mcp_name_cache_ttl")
+			initCompleter(opts)
+			c, err := gemini.NewClientWithOptions(ctx, opts)
 			if err != nil {
 				return err
 			}
@@ -28,8 +35,36 @@ var mcpCmd = &cobra.Command{
 			client = c
 		}
 
+		registry, err := getConverterRegistry()
+		if err != nil {
+			return err
+		}
+
+		profiles, err := getStoreProfiles()
+		if err != nil {
+			return err
+		}
+
+		// The default store is resolved once, relative to the directory the
+		// server was started in
+		store, _, err := defaultStore()
+		if err != nil {
+			return err
+		}
+
+		queryDefaults, err := queryDefaults()
+		if err != nil {
+			return err
+		}
+
 		tools := getMCPTools()
-		return mcp.RunServer(ctx, client, tools)
+		return mcp.RunServer(ctx, client, tools, &mcp.ServerOptions{
+			Converters:    registry,
+			StoreProfiles: profiles,
+			DefaultModel:  viper.GetString("default_model"),
+			DefaultStore:  store,
+			QueryDefaults: queryDefaults,
+		})
 	},
 }
 
@@ -40,3 +75,15 @@ func init() {
 	viper.BindPFlag("mcp_tools", mcpCmd.Flags().Lookup("mcp-tools"))
 	viper.BindEnv("mcp_tools", "MCP_TOOLS")
 }
+
+// logUsage logs the token usage of each tool call to stderr, which MCP
+// clients keep as the server log, before passing it on to next
+func logUsage(next gemini.UsageHook) gemini.UsageHook {
+	return func(ctx context.Context, e gemini.UsageEvent) {
+		fmt.Fprintf(os.Stderr, "usage: tool=%s operation=%s model=%s store=%s tokens: %s\n",
+			gemini.UsageLabel(ctx), e.Operation, e.Model, e.Store, e.Usage)
+		if next != nil {
+			next(ctx, e)
+		}
+	}
+}
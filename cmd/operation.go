@@ -35,6 +35,12 @@ Examples:
   # Get operation status in JSON format
   file-search operation get "fileSearchStores/abc123/operations/op456" --format json`,
 		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return getCompleter().GetOperationNames(toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
 			client, err := getClient(ctx)
@@ -65,5 +71,6 @@ Examples:
 		},
 	}
 	operationGetCmd.Flags().StringVar(&operationType, "type", "", "Operation type: import or upload (auto-detect if not specified)")
+	operationGetCmd.RegisterFlagCompletionFunc("type", cobra.FixedCompletions([]string{"import", "upload"}, cobra.ShellCompDirectiveNoFileComp))
 	operationCmd.AddCommand(operationGetCmd)
 }
@@ -2,18 +2,59 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/mikesmitty/file-search/internal/auth"
+	"github.com/mikesmitty/file-search/internal/config"
 	"github.com/mikesmitty/file-search/internal/constants"
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"github.com/mikesmitty/file-search/internal/report"
+	"github.com/mikesmitty/file-search/internal/schema"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/genai"
 )
 
 var queryCmd = &cobra.Command{
 	Use:     "query [text...]",
 	Aliases: []string{"q"},
 	Short:   "Query Gemini File Search",
-	Args:    cobra.MinimumNArgs(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if queryQuestionsFile != "" {
+			if len(args) > 0 {
+				return fmt.Errorf("give either a question or --questions, not both")
+			}
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if queryQuestionsFile != "" && queryOutputFile == "" {
+			return fmt.Errorf("--questions requires --output")
+		}
+		if queryOutputFile != "" {
+			if err := report.CheckPath(queryOutputFile); err != nil {
+				return err
+			}
+		}
+		queryOpts, err := queryOptions(cmd)
+		if err != nil {
+			return err
+		}
+		var responseSchema *schema.Schema
+		if querySchemaFile != "" {
+			responseSchema, err = schema.Load(querySchemaFile)
+			if err != nil {
+				return err
+			}
+			queryOpts.ResponseSchema = responseSchema.Document()
+		}
+
 		ctx := context.Background()
 		client, err := getClient(ctx)
 		if err != nil {
@@ -22,26 +63,68 @@ var queryCmd = &cobra.Command{
 		defer client.Close()
 
 		// Resolve store name to ID if --store was used
-		storeID := queryStoreID
-		if queryStoreName != "" {
-			storeID, err = client.ResolveStoreName(ctx, queryStoreName)
+		storeName, storeID, err := withDefaultStore(queryStoreName, queryStoreID)
+		if err != nil {
+			return err
+		}
+		if storeName != "" {
+			storeID, err = client.ResolveStoreName(ctx, storeName)
 			if err != nil {
 				return err
 			}
 		}
 
-		if queryModel == "" {
-			queryModel = constants.DefaultModel
-		}
+		queryModel = queryModelName(cmd)
 
 		// Join all arguments to form the query string
 		queryString := strings.Join(args, " ")
 
-		resp, err := client.Query(ctx, queryString, storeID, queryModel, queryMetadataFilter)
+		if queryOutputFile != "" {
+			questions := []string{queryString}
+			if queryQuestionsFile != "" {
+				questions, err = report.ReadQuestions(queryQuestionsFile)
+				if err != nil {
+					return err
+				}
+			}
+			store := storeName
+			if store == "" {
+				store = storeID
+			}
+			return writeQueryReport(ctx, client, questions, store, storeID, queryOpts)
+		}
+
+		if querySourcesOnly {
+			passages, err := client.SearchPassages(ctx, queryString, storeID, queryModel, queryOpts)
+			if err != nil {
+				return err
+			}
+			return printOutput(passages, outputFormat)
+		}
+
+		resp, err := client.Query(ctx, queryString, storeID, queryModel, queryOpts)
 		if err != nil {
 			return err
 		}
-		return printOutput(resp, outputFormat)
+		if responseSchema != nil {
+			answer, err := responseSchema.Validate(resp.Text())
+			if err != nil {
+				return fmt.Errorf("%w\nAnswer:\n%s", err, resp.Text())
+			}
+			return printOutput(&gemini.StructuredAnswer{Answer: answer, Sources: gemini.Sources(resp), Usage: gemini.UsageOf(resp)}, outputFormat)
+		}
+		if err := printOutput(gemini.NewCitedAnswer(resp), outputFormat); err != nil {
+			return err
+		}
+
+		// Debug output: Print full grounding metadata as JSON
+		if debug && outputFormat != "json" && len(resp.Candidates) > 0 && resp.Candidates[0].GroundingMetadata != nil {
+			debugJSON, err := json.MarshalIndent(resp.Candidates[0].GroundingMetadata, "", "  ")
+			if err == nil {
+				fmt.Printf("\n[Grounding Metadata]\n%s\n", auth.Scrub(string(debugJSON)))
+			}
+		}
+		return nil
 	},
 }
 
@@ -50,22 +133,212 @@ var (
 	queryStoreID        string
 	queryModel          string
 	queryMetadataFilter string
+	querySchemaFile     string
+	querySourcesOnly    bool
+	queryOutputFile     string
+	queryQuestionsFile  string
+	// queryShowUnsupported highlights answer sentences without a citation
+	queryShowUnsupported bool
 )
 
 func init() {
 	rootCmd.AddCommand(queryCmd)
 
-	queryCmd.Flags().StringVar(&queryStoreName, "store", "", "Store display name (optional)")
-	queryCmd.Flags().StringVar(&queryStoreID, "store-id", "", "Store resource ID (optional, "+constants.StoreResourcePrefix+"xxx)")
-	queryCmd.Flags().StringVar(&queryModel, "model", constants.DefaultModel, "Model name")
-	queryCmd.Flags().StringVar(&queryMetadataFilter, "metadata-filter", "", "Metadata filter expression (optional)")
+	queryCmd.PersistentFlags().StringVar(&queryStoreName, "store", "", "Store display name (optional, defaults to the project store)")
+	queryCmd.PersistentFlags().StringVar(&queryStoreID, "store-id", "", "Store resource ID (optional, "+constants.StoreResourcePrefix+"xxx)")
+	queryCmd.PersistentFlags().StringVar(&queryModel, "model", constants.DefaultModel, "Model name")
+	queryCmd.PersistentFlags().StringVar(&queryMetadataFilter, "metadata-filter", "", "Metadata filter expression (optional)")
+	queryCmd.PersistentFlags().String("system", "", "System instruction for the answer (config: query.system_instruction)")
+	queryCmd.PersistentFlags().String("system-file", "", "Read the system instruction from a file (config: query.system_file)")
+	queryCmd.PersistentFlags().Float32("temperature", 0, "Sampling temperature, e.g. 0 for deterministic answers (config: query.temperature)")
+	queryCmd.PersistentFlags().Float32("top-p", 0, "Nucleus sampling probability (config: query.top_p)")
+	queryCmd.PersistentFlags().Int32("max-output-tokens", 0, "Maximum tokens in the answer (config: query.max_output_tokens)")
+	queryCmd.PersistentFlags().Int32("thinking-budget", 0, "Thinking token budget; 0 disables thinking where supported (config: query.thinking_budget)")
+	queryCmd.PersistentFlags().String("thinking-level", "", "Thinking level: minimal, low, medium or high (config: query.thinking_level)")
+	queryCmd.PersistentFlags().StringToString("safety", nil, "Safety thresholds as category=threshold, e.g. dangerous_content=block_only_high (config: query.safety)")
+	queryCmd.PersistentFlags().StringArray("stop", nil, "Stop sequence (repeatable, config: query.stop_sequences)")
+	queryCmd.PersistentFlags().Bool("no-cache", false, "Always generate a new answer, bypassing the response cache")
+	queryCmd.Flags().StringVar(&querySchemaFile, "schema", "", "JSON Schema file; the answer is returned as validated JSON with its sources")
+	queryCmd.Flags().BoolVar(&querySourcesOnly, "sources-only", false, "Return the retrieved passages instead of an answer")
+	queryCmd.Flags().BoolVar(&queryShowUnsupported, "show-unsupported", false, "Highlight answer sentences that no source supports")
+	queryCmd.MarkFlagsMutuallyExclusive("system", "system-file")
+	queryCmd.Flags().StringVarP(&queryOutputFile, "output", "o", "", "Write the answer to a Markdown (.md) or HTML (.html) report")
+	queryCmd.Flags().StringVar(&queryQuestionsFile, "questions", "", "File with one question per line to answer in a single report (requires --output)")
+	queryCmd.MarkFlagsMutuallyExclusive("sources-only", "schema")
+	queryCmd.MarkFlagsMutuallyExclusive("output", "sources-only")
+	queryCmd.MarkFlagsMutuallyExclusive("output", "schema")
+	queryCmd.RegisterFlagCompletionFunc("thinking-level", cobra.FixedCompletions([]string{"minimal", "low", "medium", "high"}, cobra.ShellCompDirectiveNoFileComp))
 	queryCmd.RegisterFlagCompletionFunc("store", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+		return getCompleter().GetStoreNames(toComplete), cobra.ShellCompDirectiveNoFileComp
 	})
 	queryCmd.RegisterFlagCompletionFunc("store-id", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+		return getCompleter().GetStoreIDs(toComplete), cobra.ShellCompDirectiveNoFileComp
 	})
 	queryCmd.RegisterFlagCompletionFunc("model", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return getCompleter().GetModelNames(), cobra.ShellCompDirectiveNoFileComp
 	})
 }
+
+// queryModelName returns the --model flag, or the configured default model
+// when the flag wasn't given
+func queryModelName(cmd *cobra.Command) string {
+	model := queryModel
+	if !cmd.Flags().Changed("model") {
+		if m := viper.GetString("default_model"); m != "" {
+			model = m
+		}
+	}
+	if model == "" {
+		model = constants.DefaultModel
+	}
+	return model
+}
+
+// writeQueryReport answers each question and writes the answers to the
+// --output report. Failed questions are recorded in the report.
+func writeQueryReport(ctx context.Context, client *gemini.Client, questions []string, store, storeID string, opts *gemini.QueryOptions) error {
+	r := &report.Report{Model: queryModel, Store: store, Created: time.Now()}
+	failed := 0
+	for i, question := range questions {
+		if !quiet && len(questions) > 1 {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", i+1, len(questions), question)
+		}
+		entry := report.Entry{Question: question}
+		resp, err := client.Query(ctx, question, storeID, queryModel, opts)
+		if err != nil {
+			entry.Err = err
+			failed++
+		} else {
+			entry.Answer = gemini.NewCitedAnswer(resp)
+		}
+		r.Entries = append(r.Entries, entry)
+	}
+
+	if err := report.Write(queryOutputFile, r); err != nil {
+		return err
+	}
+	if !quiet {
+		fmt.Printf("Wrote report to %s\n", queryOutputFile)
+	}
+	if failed == len(questions) {
+		return fmt.Errorf("all %d questions failed", failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d questions failed; see the report for details", failed, len(questions))
+	}
+	return nil
+}
+
+// queryDefaults returns the generation settings from the "query" config map
+// (including the active profile), e.g. query: {temperature: 0, max_output_tokens: 256}
+func queryDefaults() (*gemini.QueryOptions, error) {
+	opts := &gemini.QueryOptions{
+		SystemInstruction: viper.GetString("query.system_instruction"),
+		MaxOutputTokens:   viper.GetInt32("query.max_output_tokens"),
+		ThinkingLevel:     viper.GetString("query.thinking_level"),
+		SafetySettings:    viper.GetStringMapString("query.safety"),
+		StopSequences:     viper.GetStringSlice("query.stop_sequences"),
+	}
+	if path := viper.GetString("query.system_file"); path != "" && opts.SystemInstruction == "" {
+		data, err := os.ReadFile(configRelativePath("query.system_file", path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read query.system_file: %w", err)
+		}
+		opts.SystemInstruction = string(data)
+	}
+	if viper.IsSet("query.temperature") {
+		opts.Temperature = genai.Ptr(float32(viper.GetFloat64("query.temperature")))
+	}
+	if viper.IsSet("query.top_p") {
+		opts.TopP = genai.Ptr(float32(viper.GetFloat64("query.top_p")))
+	}
+	if viper.IsSet("query.thinking_budget") {
+		opts.ThinkingBudget = genai.Ptr(viper.GetInt32("query.thinking_budget"))
+	}
+	return opts, nil
+}
+
+// configRelativePath resolves a relative path from the config value key
+// against the directory of the config file that set it, so a project config
+// behaves the same from any subdirectory. The active profile's value wins, as
+// in applyProfile. Paths not set by a file are left relative to the current
+// directory.
+func configRelativePath(key, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	keys := [][]string{strings.Split(key, ".")}
+	if profile := activeProfile(); profile != "" {
+		keys = append([][]string{append([]string{"profiles", profile}, keys[0]...)}, keys...)
+	}
+	if file := config.SetIn(configFiles, keys...); file != "" {
+		return filepath.Join(filepath.Dir(file), path)
+	}
+	return path
+}
+
+// queryOptions returns the generation settings for the query command:
+// the config defaults overridden by any flags that were given
+func queryOptions(cmd *cobra.Command) (*gemini.QueryOptions, error) {
+	opts, err := queryDefaults()
+	if err != nil {
+		return nil, err
+	}
+	opts.MetadataFilter = queryMetadataFilter
+
+	flags := cmd.Flags()
+	if flags.Changed("system") {
+		opts.SystemInstruction, _ = flags.GetString("system")
+	}
+	if flags.Changed("system-file") {
+		path, _ := flags.GetString("system-file")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		opts.SystemInstruction = string(data)
+	}
+	if flags.Changed("temperature") {
+		v, _ := flags.GetFloat32("temperature")
+		opts.Temperature = &v
+	}
+	if flags.Changed("top-p") {
+		v, _ := flags.GetFloat32("top-p")
+		opts.TopP = &v
+	}
+	if flags.Changed("max-output-tokens") {
+		opts.MaxOutputTokens, _ = flags.GetInt32("max-output-tokens")
+	}
+	if flags.Changed("thinking-budget") {
+		v, _ := flags.GetInt32("thinking-budget")
+		opts.ThinkingBudget = &v
+	}
+	if flags.Changed("thinking-level") {
+		opts.ThinkingLevel, _ = flags.GetString("thinking-level")
+	}
+	if flags.Changed("safety") {
+		// Flag values override matching categories from the config
+		safety, _ := flags.GetStringToString("safety")
+		merged := make(map[string]string, len(opts.SafetySettings)+len(safety))
+		for k, v := range opts.SafetySettings {
+			merged[k] = v
+		}
+		for k, v := range safety {
+			merged[k] = v
+		}
+		opts.SafetySettings = merged
+	}
+	if flags.Changed("stop") {
+		opts.StopSequences, _ = flags.GetStringArray("stop")
+	}
+	opts.NoCache, _ = flags.GetBool("no-cache")
+
+	// Catch invalid names before sending the request
+	if _, err := gemini.ParseThinkingLevel(opts.ThinkingLevel); err != nil {
+		return nil, err
+	}
+	if _, err := gemini.ParseSafetySettings(opts.SafetySettings); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
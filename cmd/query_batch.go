@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"github.com/mikesmitty/file-search/internal/report"
+	"github.com/spf13/cobra"
+)
+
+var queryBatchCmd = &cobra.Command{
+	Use:   "batch <questions.txt|questions.jsonl>",
+	Short: "Run a set of questions against a store",
+	Long: `Run a set of questions concurrently and write one result per question.
+
+Questions are read from a text file (one per line, # for comments) or a JSONL
+file of objects with a "question" and optional "id", "model" and
+"metadata_filter" overrides:
+
+  {"id": "vin-max", "question": "What is the max input voltage?", "metadata_filter": "part = \"LM317\""}
+
+Results are written as JSONL, or CSV when --output ends in .csv, with the
+answer, sources, token usage and latency of each question. Failed questions
+are recorded with their error and the run continues.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		questions, err := readBatchQuestions(args[0])
+		if err != nil {
+			return err
+		}
+		write, err := batchResultWriter(batchOutputFile)
+		if err != nil {
+			return err
+		}
+		queryOpts, err := queryOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		client, err := getClient(ctx)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		storeName, storeID, err := withDefaultStore(queryStoreName, queryStoreID)
+		if err != nil {
+			return err
+		}
+		if storeName != "" {
+			storeID, err = client.ResolveStoreName(ctx, storeName)
+			if err != nil {
+				return err
+			}
+		}
+		model := queryModelName(cmd)
+
+		// processBatch works on string keys, so questions are keyed by position
+		keys := make([]string, len(questions))
+		results := make([]*BatchQueryResult, len(questions))
+		for i := range questions {
+			keys[i] = strconv.Itoa(i)
+		}
+		processor := func(ctx context.Context, key string) error {
+			i, _ := strconv.Atoi(key)
+			results[i] = runBatchQuestion(ctx, client, questions[i], storeID, model, queryOpts)
+			if results[i].Error != "" {
+				return fmt.Errorf("%s", results[i].Error)
+			}
+			return nil
+		}
+		onProgress := func(current, total int, key string, err error) {
+			i, _ := strconv.Atoi(key)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[%d/%d] ✗ Failed: %s (%v)\n", current, total, questions[i].ID, err)
+			} else {
+				fmt.Fprintf(os.Stderr, "[%d/%d] ✓ Finished: %s\n", current, total, questions[i].ID)
+			}
+		}
+
+		var interval time.Duration
+		if batchRPM > 0 {
+			interval = time.Minute / time.Duration(batchRPM)
+		}
+		batchResult := processBatch(ctx, keys, processor, &BatchOptions{
+			Concurrency: batchConcurrency,
+			Interval:    interval,
+			Quiet:       quiet,
+			OnProgress:  onProgress,
+		})
+
+		out := io.Writer(os.Stdout)
+		if batchOutputFile != "" {
+			f, err := os.Create(batchOutputFile)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		if err := write(out, results); err != nil {
+			return err
+		}
+
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "\nSummary:\n")
+			fmt.Fprintf(os.Stderr, "  ✓ Succeeded: %d\n", len(batchResult.Succeeded))
+			fmt.Fprintf(os.Stderr, "  ✗ Failed: %d\n", len(batchResult.Failed))
+			if batchOutputFile != "" {
+				fmt.Fprintf(os.Stderr, "Wrote results to %s\n", batchOutputFile)
+			}
+		}
+		if len(batchResult.Failed) > 0 {
+			return fmt.Errorf("%d of %d questions failed", len(batchResult.Failed), batchResult.Total)
+		}
+		return nil
+	},
+}
+
+var (
+	batchOutputFile  string
+	batchConcurrency int
+	batchRPM         int
+)
+
+func init() {
+	queryCmd.AddCommand(queryBatchCmd)
+
+	queryBatchCmd.Flags().StringVarP(&batchOutputFile, "output", "o", "", "Write results to a .jsonl or .csv file (default: JSONL on stdout)")
+	queryBatchCmd.Flags().IntVar(&batchConcurrency, "concurrency", 5, "Number of parallel queries")
+	queryBatchCmd.Flags().IntVar(&batchRPM, "rpm", 60, "Maximum queries started per minute (0 for no limit)")
+}
+
+// BatchQuestion is one question of a batch run. Model and MetadataFilter
+// override the command's flags when set.
+type BatchQuestion struct {
+	ID             string `json:"id"`
+	Question       string `json:"question"`
+	Model          string `json:"model,omitempty"`
+	MetadataFilter string `json:"metadata_filter,omitempty"`
+}
+
+// BatchQueryResult is the outcome of one batch question
+type BatchQueryResult struct {
+	ID             string          `json:"id"`
+	Question       string          `json:"question"`
+	Model          string          `json:"model"`
+	MetadataFilter string          `json:"metadata_filter,omitempty"`
+	Answer         string          `json:"answer,omitempty"`
+	Sources        []gemini.Source `json:"sources,omitempty"`
+	Usage          gemini.Usage    `json:"usage"`
+	LatencyMS      int64           `json:"latency_ms"`
+	Error          string          `json:"error,omitempty"`
+}
+
+// runBatchQuestion answers q, recording any failure in the result
+func runBatchQuestion(ctx context.Context, client *gemini.Client, q BatchQuestion, storeID, model string, opts *gemini.QueryOptions) *BatchQueryResult {
+	questionOpts := *opts
+	if q.MetadataFilter != "" {
+		questionOpts.MetadataFilter = q.MetadataFilter
+	}
+	if q.Model != "" {
+		model = q.Model
+	}
+	result := &BatchQueryResult{ID: q.ID, Question: q.Question, Model: model, MetadataFilter: questionOpts.MetadataFilter}
+
+	start := time.Now()
+	resp, err := client.Query(ctx, q.Question, storeID, model, &questionOpts)
+	result.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	compact := gemini.NewCitedAnswer(resp).Compact()
+	result.Answer = compact.Answer
+	result.Sources = compact.Sources
+	if u := gemini.UsageOf(resp); u != nil {
+		result.Usage = *u
+	}
+	return result
+}
+
+// readBatchQuestions reads questions from a .jsonl file, or from a text file
+// with one question per line. Questions without an ID are numbered from 1.
+func readBatchQuestions(path string) ([]BatchQuestion, error) {
+	var questions []BatchQuestion
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var q BatchQuestion
+			if err := json.Unmarshal([]byte(text), &q); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			if strings.TrimSpace(q.Question) == "" {
+				return nil, fmt.Errorf("%s:%d: question is required", path, line)
+			}
+			questions = append(questions, q)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		if len(questions) == 0 {
+			return nil, fmt.Errorf("no questions in %s", path)
+		}
+	} else {
+		lines, err := report.ReadQuestions(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			questions = append(questions, BatchQuestion{Question: line})
+		}
+	}
+
+	for i := range questions {
+		if questions[i].ID == "" {
+			questions[i].ID = strconv.Itoa(i + 1)
+		}
+	}
+	return questions, nil
+}
+
+// batchResultWriter returns the writer for the extension of path: CSV for
+// .csv, otherwise JSONL
+func batchResultWriter(path string) (func(io.Writer, []*BatchQueryResult) error, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return writeBatchCSV, nil
+	case "", ".jsonl", ".json":
+		return writeBatchJSONL, nil
+	}
+	return nil, fmt.Errorf("unsupported output format %q (use .jsonl or .csv)", filepath.Ext(path))
+}
+
+// writeBatchJSONL writes one JSON object per result
+func writeBatchJSONL(w io.Writer, results []*BatchQueryResult) error {
+	enc := json.NewEncoder(w)
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBatchCSV writes a header and one row per result. Sources are joined
+// into a single "[n] title (location)" column.
+func writeBatchCSV(w io.Writer, results []*BatchQueryResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "question", "model", "metadata_filter", "answer", "sources",
+		"prompt_tokens", "output_tokens", "thinking_tokens", "tool_tokens", "total_tokens", "latency_ms", "error"})
+	for _, r := range results {
+		sources := make([]string, 0, len(r.Sources))
+		for _, src := range r.Sources {
+			s := fmt.Sprintf("[%d] %s", src.Index, src.Title)
+			if loc := src.Location(); loc != "" {
+				s += " (" + loc + ")"
+			}
+			sources = append(sources, s)
+		}
+		cw.Write([]string{
+			r.ID, r.Question, r.Model, r.MetadataFilter, r.Answer, strings.Join(sources, "; "),
+			strconv.Itoa(int(r.Usage.PromptTokens)),
+			strconv.Itoa(int(r.Usage.OutputTokens)),
+			strconv.Itoa(int(r.Usage.ThinkingTokens)),
+			strconv.Itoa(int(r.Usage.ToolTokens)),
+			strconv.Itoa(int(r.Usage.TotalTokens)),
+			strconv.FormatInt(r.LatencyMS, 10),
+			r.Error,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
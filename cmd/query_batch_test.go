@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+)
+
+func TestReadBatchQuestions(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("jsonl", func(t *testing.T) {
+		path := filepath.Join(dir, "questions.jsonl")
+		data := `{"id": "vin", "question": "Max input voltage?", "metadata_filter": "part = \"LM317\""}
+
+{"question": "Dropout voltage?", "model": "gemini-2.5-pro"}
+`
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		questions, err := readBatchQuestions(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(questions) != 2 || questions[0].ID != "vin" || questions[0].MetadataFilter != `part = "LM317"` {
+			t.Errorf("unexpected questions: %+v", questions)
+		}
+		if questions[1].ID != "2" || questions[1].Model != "gemini-2.5-pro" {
+			t.Errorf("expected a numbered ID and model override, got %+v", questions[1])
+		}
+	})
+
+	t.Run("jsonl without question", func(t *testing.T) {
+		path := filepath.Join(dir, "bad.jsonl")
+		os.WriteFile(path, []byte(`{"id": "x"}`+"\n"), 0o644)
+		if _, err := readBatchQuestions(path); err == nil || !strings.Contains(err.Error(), "bad.jsonl:1") {
+			t.Errorf("expected an error with the line number, got %v", err)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		path := filepath.Join(dir, "questions.txt")
+		os.WriteFile(path, []byte("# datasheet review\nMax input voltage?\nDropout voltage?\n"), 0o644)
+		questions, err := readBatchQuestions(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(questions) != 2 || questions[1].ID != "2" || questions[1].Question != "Dropout voltage?" {
+			t.Errorf("unexpected questions: %+v", questions)
+		}
+	})
+}
+
+func TestWriteBatchCSV(t *testing.T) {
+	results := []*BatchQueryResult{
+		{
+			ID: "1", Question: "Max voltage?", Model: "m", Answer: "40 V.[1]",
+			Sources:   []gemini.Source{{Index: 1, Type: "document", Title: "lm317.pdf", FirstPage: 3, LastPage: 3}},
+			Usage:     gemini.Usage{PromptTokens: 10, OutputTokens: 5, TotalTokens: 15},
+			LatencyMS: 1200,
+		},
+		{ID: "2", Question: "Dropout?", Model: "m", Error: "quota exceeded"},
+	}
+	var b strings.Builder
+	if err := writeBatchCSV(&b, results); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][5] != "sources" {
+		t.Fatalf("unexpected rows: %q", rows)
+	}
+	if rows[1][5] != "[1] lm317.pdf (Page 3)" || rows[1][10] != "15" || rows[1][11] != "1200" {
+		t.Errorf("unexpected row: %q", rows[1])
+	}
+	if rows[2][12] != "quota exceeded" {
+		t.Errorf("expected the error column, got %q", rows[2])
+	}
+
+	if _, err := batchResultWriter("results.xlsx"); err == nil {
+		t.Error("expected an error for an unsupported output format")
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestQueryOptions(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader(`
+query:
+  system_instruction: Answer tersely.
+  temperature: 0
+  max_output_tokens: 200
+  safety:
+    harassment: block_none
+`)); err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := queryOptions(queryCmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.SystemInstruction != "Answer tersely." || opts.MaxOutputTokens != 200 {
+		t.Errorf("config defaults not applied: %+v", opts)
+	}
+	if opts.Temperature == nil || *opts.Temperature != 0 {
+		t.Errorf("expected temperature 0 from config, got %v", opts.Temperature)
+	}
+	if opts.TopP != nil || opts.ThinkingBudget != nil {
+		t.Errorf("expected unset values to stay nil: %+v", opts)
+	}
+
+	flags := queryCmd.Flags()
+	t.Cleanup(func() {
+		for _, name := range []string{"temperature", "safety", "thinking-level"} {
+			flags.Lookup(name).Changed = false
+		}
+	})
+	flags.Set("temperature", "0.5")
+	flags.Set("safety", "dangerous_content=block_only_high")
+	opts, err = queryOptions(queryCmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *opts.Temperature != 0.5 {
+		t.Errorf("flag did not override config temperature: %v", *opts.Temperature)
+	}
+	if len(opts.SafetySettings) != 2 {
+		t.Errorf("expected flag and config safety settings to merge, got %v", opts.SafetySettings)
+	}
+
+	flags.Set("thinking-level", "extreme")
+	if _, err := queryOptions(queryCmd); err == nil {
+		t.Error("expected an error for an invalid thinking level")
+	}
+}
+
+func TestQuerySystemFileRelativeToConfig(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, ".file-search.yaml")
+	os.WriteFile(cfg, []byte("query:\n  system_file: prompts/system.txt\n"), 0o600)
+	os.MkdirAll(filepath.Join(dir, "prompts"), 0o755)
+	os.WriteFile(filepath.Join(dir, "prompts", "system.txt"), []byte("Cite page numbers."), 0o600)
+
+	viper.SetConfigFile(cfg)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	saved := configFiles
+	configFiles = []string{cfg}
+	t.Cleanup(func() { configFiles = saved })
+
+	// The test runs in the package directory, not the config's
+	opts, err := queryDefaults()
+	if err != nil {
+		t.Fatalf("queryDefaults failed: %v", err)
+	}
+	if opts.SystemInstruction != "Cite page numbers." {
+		t.Errorf("expected the system file next to the config, got %q", opts.SystemInstruction)
+	}
+}
@@ -1,18 +1,29 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/mikesmitty/file-search/internal/auth"
+	"github.com/mikesmitty/file-search/internal/chunk"
 	"github.com/mikesmitty/file-search/internal/completion"
+	"github.com/mikesmitty/file-search/internal/config"
+	"github.com/mikesmitty/file-search/internal/constants"
+	"github.com/mikesmitty/file-search/internal/convert"
 	"github.com/mikesmitty/file-search/internal/gemini"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 	"google.golang.org/genai"
 )
 
@@ -39,31 +50,63 @@ var rootCmd = &cobra.Command{
 enables interaction with the Google Gemini File Search API.
 
 It allows you to manage file stores, upload documents, and perform semantic searches.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		commandPath = cmd.CommandPath()
+		return configErr
+	},
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.file-search.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: $HOME/.file-search.yaml or $XDG_CONFIG_HOME/file-search/config.yaml, plus the nearest project .file-search.yaml)")
 	rootCmd.PersistentFlags().StringVar(&apiKey, "api-key", "", "Gemini API Key")
 	rootCmd.PersistentFlags().StringVar(&apiKeyEnv, "api-key-env", "", "Environment variable to read API Key from")
 	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", "text", "Output format: text or json")
 	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Suppress progress indicators")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug output (JSON)")
+	rootCmd.PersistentFlags().String("profile", "", "Config profile to use (env: FILE_SEARCH_PROFILE)")
+	rootCmd.PersistentFlags().String("backend", "gemini", "API backend: gemini or vertex (env: FILE_SEARCH_BACKEND)")
+	rootCmd.PersistentFlags().String("project", "", "Google Cloud project for the vertex backend (env: GOOGLE_CLOUD_PROJECT)")
+	rootCmd.PersistentFlags().String("location", "", "Google Cloud location for the vertex backend (env: GOOGLE_CLOUD_LOCATION)")
 
 	viper.BindPFlag("api_key", rootCmd.PersistentFlags().Lookup("api-key"))
 	viper.BindPFlag("api_key_env", rootCmd.PersistentFlags().Lookup("api-key-env"))
+	viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+	viper.BindPFlag("backend", rootCmd.PersistentFlags().Lookup("backend"))
+	viper.BindPFlag("project", rootCmd.PersistentFlags().Lookup("project"))
+	viper.BindPFlag("location", rootCmd.PersistentFlags().Lookup("location"))
 }
 
-var globalCompleter *completion.Completer
+var (
+	completerOnce   sync.Once
+	globalCompleter *completion.Completer
+)
 
-// getCompleter returns or initializes the global completer instance
+// getCompleter returns or initializes the global completer instance. It is
+// safe for concurrent use, e.g. from the operation hook of batch uploads.
 func getCompleter() *completion.Completer {
-	if globalCompleter != nil {
-		return globalCompleter
-	}
+	completerOnce.Do(func() {
+		// Get client options, without prompting for a passphrase mid-completion
+		opts, err := getClientOptions(false)
+		globalCompleter = newCompleter(opts, err)
+	})
+	return globalCompleter
+}
 
+// initCompleter sets up the global completer with options a command already
+// resolved, e.g. after prompting for a passphrase, so operations it records
+// land in the same cache completion reads. It does nothing once set up.
+func initCompleter(opts gemini.ClientOptions) {
+	completerOnce.Do(func() {
+		globalCompleter = newCompleter(opts, nil)
+	})
+}
+
+// newCompleter creates a completer for opts, disabled if resolving them
+// failed
+func newCompleter(opts gemini.ClientOptions, optsErr error) *completion.Completer {
 	// Get configuration
 	enabled := viper.GetBool("completion_enabled")
 	cacheTTL := viper.GetDuration("completion_cache_ttl")
@@ -71,32 +114,46 @@ func getCompleter() *completion.Completer {
 		cacheTTL = 300 * time.Second // 5 minutes default
 	}
 
-	// Get API key
-	key, err := getAPIKey()
-	if err != nil || key == "" {
-		// If no API key, create disabled completer
-		globalCompleter = completion.NewCompleter("", false, cacheTTL)
-		return globalCompleter
+	if optsErr != nil {
+		// If no credentials, create disabled completer
+		return completion.NewCompleter("", false, cacheTTL)
 	}
 
-	// Create completer with configuration
-	globalCompleter = completion.NewCompleter(key, enabled, cacheTTL)
-	return globalCompleter
+	// Each completion runs in a new process, so the cache is kept on disk
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return completion.NewCompleterWithOptions(opts, enabled, cacheTTL)
+	}
+	cache := completion.NewFileCache(completion.CachePath(cacheDir, opts), cacheTTL)
+	return completion.NewCompleterWithCache(opts, enabled, cache)
+}
+
+// invalidateCompletions drops cached completions after the CLI changes the
+// stores, files or documents they list. The cache files are found by path,
+// so this works without credentials, e.g. for a passphrase-protected key.
+func invalidateCompletions(prefixes ...string) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		// Without a cache dir the completer only caches in memory
+		getCompleter().Invalidate(prefixes...)
+		return
+	}
+	completion.InvalidateFiles(cacheDir, prefixes...)
 }
 
 // getMCPTools returns the list of enabled MCP tools
 // Supports comma-separated string from flag/env/config
 // Default: ["query"]
 func getMCPTools() []string {
-	// Check if set via flag/env/config
-	toolsStr := viper.GetString("mcp_tools")
-	if toolsStr == "" {
-		// Default to query only
-		return []string{"query"}
+	// Check if set via flag/env/config. Config files and profiles may use a YAML list.
+	var tools []string
+	switch viper.Get("mcp_tools").(type) {
+	case []any, []string:
+		tools = viper.GetStringSlice("mcp_tools")
+	default:
+		tools = strings.Split(viper.GetString("mcp_tools"), ",")
 	}
 
-	// Parse comma-separated list
-	tools := strings.Split(toolsStr, ",")
 	result := make([]string, 0, len(tools))
 	for _, tool := range tools {
 		trimmed := strings.TrimSpace(tool)
@@ -111,60 +168,387 @@ func getMCPTools() []string {
 	return result
 }
 
+// getConverterRegistry returns the upload converter registry with any
+// per-extension overrides from the "converters" config map applied.
+// Example config: converters: {".html": "html", ".zip": "none"}
+func getConverterRegistry() (*convert.Registry, error) {
+	registry := convert.NewRegistry()
+	for ext, name := range viper.GetStringMapString("converters") {
+		if err := registry.SetExtension(ext, name); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// getStoreProfiles returns the per-store upload defaults from the "stores" config map.
+// Example config: stores: {Datasheets: {chunk_size: 512, metadata: {team: hw}}}
+func getStoreProfiles() (config.StoreProfiles, error) {
+	var profiles config.StoreProfiles
+	if err := viper.UnmarshalKey("stores", &profiles); err != nil {
+		return nil, fmt.Errorf("invalid stores config: %w", err)
+	}
+	return profiles, nil
+}
+
+// lookupStoreProfile finds the profile for a store by display name or
+// resource ID, fetching the display name if only the ID is known.
+// It returns the matching config key and a nil profile if none is configured.
+func lookupStoreProfile(ctx context.Context, client *gemini.Client, storeID, storeName string) (string, *config.StoreProfile, error) {
+	profiles, err := getStoreProfiles()
+	if err != nil || len(profiles) == 0 {
+		return "", nil, err
+	}
+	if key, profile := profiles.Lookup(storeName, storeID); profile != nil {
+		return key, profile, nil
+	}
+	if storeName == "" && storeID != "" {
+		store, err := client.GetStore(ctx, storeID)
+		if err != nil {
+			return "", nil, err
+		}
+		key, profile := profiles.Lookup(store.DisplayName)
+		return key, profile, nil
+	}
+	return "", nil, nil
+}
+
+// duplicatePolicy returns the --on-duplicate policy, falling back to the
+// store's configured on_duplicate when the flag wasn't given
+func duplicatePolicy(cmd *cobra.Command, flag string, profile *config.StoreProfile) (gemini.DuplicatePolicy, error) {
+	if !cmd.Flags().Changed("on-duplicate") && profile != nil && profile.OnDuplicate != "" {
+		flag = profile.OnDuplicate
+	}
+	policy, err := gemini.ParseDuplicatePolicy(flag)
+	if err != nil {
+		return "", fmt.Errorf("invalid --on-duplicate: %w", err)
+	}
+	return policy, nil
+}
+
+// completeDuplicatePolicy completes --on-duplicate
+func completeDuplicatePolicy(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return gemini.DuplicatePolicies, cobra.ShellCompDirectiveNoFileComp
+}
+
+// defaultStore returns the default store for the working directory and where
+// it was configured: FILE_SEARCH_STORE, then the nearest .file-search-store
+// marker, then default_store in the config (including the active profile).
+func defaultStore() (string, string, error) {
+	if store := os.Getenv("FILE_SEARCH_STORE"); store != "" {
+		return store, "FILE_SEARCH_STORE", nil
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		store, path, err := config.FindStoreMarker(cwd)
+		if err != nil || store != "" {
+			return store, path, err
+		}
+	}
+	if store := viper.GetString("default_store"); store != "" {
+		source := "config"
+		if profile := activeProfile(); profile != "" {
+			source = "config, profile " + profile
+		}
+		return store, source, nil
+	}
+	return "", "", nil
+}
+
+// withDefaultStore returns the store name and ID a command should use,
+// falling back to the default store when neither --store nor --store-id was
+// given. The fallback is reported on stderr so it is never silent.
+func withDefaultStore(storeName, storeID string) (string, string, error) {
+	if storeName != "" || storeID != "" {
+		return storeName, storeID, nil
+	}
+	store, source, err := defaultStore()
+	if err != nil || store == "" {
+		return "", "", err
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Using default store %q (from %s)\n", store, source)
+	}
+	if strings.HasPrefix(store, constants.StoreResourcePrefix) {
+		return "", store, nil
+	}
+	return store, "", nil
+}
+
+// commandPath is the running command, e.g. "file-search query", used to
+// attribute usage
+var commandPath string
+
+// configFiles lists the config files that were loaded, lowest precedence first
+var configFiles []string
+
+// configErr records a problem applying the config (e.g. an unknown profile).
+// It is reported when a command runs, since initConfig cannot return errors.
+var configErr error
+
 func initConfig() {
+	viper.SetConfigType("yaml")
+	configFiles = nil
+	configErr = nil
+
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
+		if err := viper.ReadInConfig(); err == nil {
+			configFiles = []string{cfgFile}
+		}
 	} else {
 		home, err := os.UserHomeDir()
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
-
-		viper.AddConfigPath(home)
-		viper.AddConfigPath(".")
-		viper.SetConfigType("yaml")
-		viper.SetConfigName(".file-search")
+		cwd, _ := os.Getwd()
+
+		// Merge the user config with the nearest project config, which wins
+		for _, path := range config.SearchPaths(cwd, home) {
+			viper.SetConfigFile(path)
+			if err := viper.MergeInConfig(); err != nil {
+				configErr = fmt.Errorf("failed to read config %s: %w", path, err)
+				continue
+			}
+			configFiles = append(configFiles, path)
+		}
 	}
 
 	// Set defaults
 	viper.SetDefault("completion_enabled", true)
 	viper.SetDefault("completion_cache_ttl", "300s")
 	viper.SetDefault("mcp_tools", "all")
+	viper.SetDefault("mcp_name_cache_ttl", "5m")
+	viper.SetDefault("usage.enabled", true)
+	viper.SetDefault("cache.enabled", false)
+	viper.SetDefault("cache.ttl", "24h")
 
 	// Bind environment variables
 	viper.BindEnv("api_key", "GOOGLE_API_KEY", "GEMINI_API_KEY")
 	viper.BindEnv("mcp_tools", "MCP_TOOLS")
 	viper.BindEnv("completion_enabled", "COMPLETION_ENABLED")
 	viper.BindEnv("completion_cache_ttl", "COMPLETION_CACHE_TTL")
+	viper.BindEnv("cache.enabled", "FILE_SEARCH_CACHE")
+	viper.BindEnv("profile", "FILE_SEARCH_PROFILE")
+	viper.BindEnv("backend", "FILE_SEARCH_BACKEND")
+	viper.BindEnv("project", "GOOGLE_CLOUD_PROJECT")
+	viper.BindEnv("location", "GOOGLE_CLOUD_LOCATION")
+
+	if err := applyProfile(); err != nil {
+		configErr = err
+	}
+}
 
-	if err := viper.ReadInConfig(); err == nil {
-		// fmt.Println("Using config file:", viper.ConfigFileUsed())
+// activeProfile returns the selected profile: --profile, then
+// FILE_SEARCH_PROFILE, then current_profile from the config file.
+func activeProfile() string {
+	if p := viper.GetString("profile"); p != "" {
+		return p
 	}
+	return viper.GetString("current_profile")
+}
+
+// applyProfile merges the active profile block over the top-level config.
+// Profile values sit in the config layer, so flags and env vars still win.
+func applyProfile() error {
+	name := activeProfile()
+	if name == "" {
+		return nil
+	}
+	settings, ok := profileSettings(name)
+	if !ok {
+		return fmt.Errorf("profile %q not found in config", name)
+	}
+	return viper.MergeConfigMap(settings)
+}
+
+// profileSettings returns the config block of a profile. The name is looked
+// up in the profiles map rather than as a dotted key, so names containing
+// dots (e.g. prod.eu) work.
+func profileSettings(name string) (map[string]any, bool) {
+	settings, ok := viper.GetStringMap("profiles")[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	m, _ := settings.(map[string]any)
+	return m, true
 }
 
 func getAPIKey() (string, error) {
+	key, _, err := resolveAPIKey(term.IsTerminal(int(os.Stdin.Fd())))
+	return key, err
+}
+
+// resolveAPIKey returns the API key and where it came from, checking in order:
+// the --api-key-env variable, --api-key/GOOGLE_API_KEY/GEMINI_API_KEY/config,
+// the OS keyring, and the encrypted key file. Decrypting the key file needs
+// FILE_SEARCH_PASSPHRASE, or a terminal prompt when interactive is set.
+func resolveAPIKey(interactive bool) (string, string, error) {
+	key, source, err := lookupAPIKey(interactive)
+	auth.RegisterSecret(key)
+	return key, source, err
+}
+
+func lookupAPIKey(interactive bool) (string, string, error) {
 	// 1. Check if a custom env var is specified
 	if envVar := viper.GetString("api_key_env"); envVar != "" {
 		if key := os.Getenv(envVar); key != "" {
-			return key, nil
+			return key, "$" + envVar, nil
+		}
+	}
+
+	// 2. Check standard flag/env/config
+	if key := viper.GetString("api_key"); key != "" {
+		return key, apiKeySource(), nil
+	}
+
+	// 3. Check the OS keyring. An unavailable keyring is the same as no key.
+	account := auth.Account(activeProfile())
+	if key, err := auth.KeyringGet(account); err == nil && key != "" {
+		return key, "keyring", nil
+	}
+
+	// 4. Check the encrypted key file
+	home, err := os.UserHomeDir()
+	if err == nil {
+		path := auth.FilePath(config.Dir(home), account)
+		if _, err := os.Stat(path); err == nil {
+			passphrase := os.Getenv("FILE_SEARCH_PASSPHRASE")
+			if passphrase == "" && interactive {
+				passphrase, err = readSecret("Passphrase for " + path + ": ")
+				if err != nil {
+					return "", "", err
+				}
+			}
+			if passphrase == "" {
+				return "", "", fmt.Errorf("API key in %s is encrypted: set FILE_SEARCH_PASSPHRASE or run interactively", path)
+			}
+			key, err := auth.LoadFile(path, passphrase)
+			if err != nil {
+				return "", "", err
+			}
+			return key, "encrypted file " + path, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("API key not set. Use `file-search auth login`, --api-key, --api-key-env, config file, or GOOGLE_API_KEY/GEMINI_API_KEY")
+}
+
+// apiKeySource describes where viper found api_key
+func apiKeySource() string {
+	if rootCmd.PersistentFlags().Changed("api-key") {
+		return "--api-key flag"
+	}
+	for _, env := range []string{"GOOGLE_API_KEY", "GEMINI_API_KEY"} {
+		if os.Getenv(env) != "" {
+			return "$" + env
 		}
 	}
+	return "config"
+}
 
-	// 2. Check standard config/env
-	key := viper.GetString("api_key")
-	if key == "" {
-		return "", fmt.Errorf("API key not set. Use --api-key, --api-key-env, config file, or GOOGLE_API_KEY/GEMINI_API_KEY")
+// getClientOptions returns the backend and credentials from flags and config.
+// The Gemini API needs an API key; Vertex AI uses project, location and
+// application-default credentials.
+func getClientOptions(interactive bool) (gemini.ClientOptions, error) {
+	opts := gemini.ClientOptions{
+		Backend:  viper.GetString("backend"),
+		Project:  viper.GetString("project"),
+		Location: viper.GetString("location"),
+		OnUsage:  usageHook(),
+		// Recent operations are offered when completing operation get
+		OnOperation: func(name string, opType gemini.OperationType, store string) {
+			getCompleter().AddOperation(name, opType, store)
+		},
+	}
+	if c := responseCache(); c != nil {
+		opts.Cache = c
 	}
-	return key, nil
+	if strings.EqualFold(opts.Backend, gemini.BackendVertex) {
+		return opts, nil
+	}
+	key, _, err := resolveAPIKey(interactive)
+	if err != nil {
+		return opts, err
+	}
+	opts.APIKey = key
+	return opts, nil
 }
 
 func getClient(ctx context.Context) (*gemini.Client, error) {
-	key, err := getAPIKey()
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	opts, err := getClientOptions(interactive)
 	if err != nil {
 		return nil, err
 	}
-	return gemini.NewClient(ctx, key, nil)
+	initCompleter(opts)
+	if interactive && term.IsTerminal(int(os.Stderr.Fd())) && outputFormat != "json" {
+		opts.Pick = pickCandidate
+	}
+	return gemini.NewClientWithOptions(ctx, opts)
+}
+
+var (
+	// promptMu serializes terminal prompts, since batch commands resolve
+	// names from concurrent workers
+	promptMu    sync.Mutex
+	stdinReader *bufio.Reader
+)
+
+// stdin returns a reader shared by every prompt, so input buffered while
+// reading one answer isn't lost to the next. Callers must hold promptMu.
+func stdin() *bufio.Reader {
+	if stdinReader == nil {
+		stdinReader = bufio.NewReader(os.Stdin)
+	}
+	return stdinReader
+}
+
+// pickCandidate asks on the terminal which of several stores, files or
+// documents was meant. Concurrent calls take turns.
+func pickCandidate(kind, ref string, candidates []gemini.Candidate) (gemini.Candidate, bool) {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	return promptCandidate(stdin(), os.Stderr, kind, ref, candidates)
+}
+
+// confirmStdin asks a yes/no question on the terminal
+func confirmStdin(question string) bool {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+	return confirm(stdin(), os.Stderr, question)
+}
+
+func promptCandidate(in io.Reader, out io.Writer, kind, ref string, candidates []gemini.Candidate) (gemini.Candidate, bool) {
+	if strings.EqualFold(candidates[0].DisplayName, ref) {
+		fmt.Fprintf(out, "Several %ss are named %q:\n", kind, ref)
+	} else {
+		fmt.Fprintf(out, "No %s is named %q. Did you mean:\n", kind, ref)
+	}
+	for i, c := range candidates {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, c)
+	}
+	fmt.Fprintf(out, "Choose 1-%d (Enter to cancel): ", len(candidates))
+
+	// A *bufio.Reader is used as-is, keeping anything it has buffered
+	line, _ := bufio.NewReader(in).ReadString('\n')
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 1 || n > len(candidates) {
+		return gemini.Candidate{}, false
+	}
+	return candidates[n-1], true
+}
+
+// confirm asks a yes/no question on out and reports whether the answer read
+// from in was yes
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N]: ", question)
+	line, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
 }
 
 // printOutput handles formatting and printing of results
@@ -221,106 +605,35 @@ func printOutput(data interface{}, format string) error {
 				fmt.Printf("  %s: %s\n", meta.Key, meta.StringValue)
 			}
 		}
-	case *genai.GenerateContentResponse:
-		for _, cand := range v.Candidates {
-			for _, part := range cand.Content.Parts {
-				fmt.Printf("%v\n", part.Text)
-			}
-			if cand.GroundingMetadata != nil {
-				fmt.Printf("\n[Grounding Metadata]\n")
-
-				// Debug output: Print full metadata as JSON if --debug is set
-				if debug {
-					debugJSON, err := json.MarshalIndent(cand.GroundingMetadata, "", "  ")
-					if err == nil {
-						fmt.Println(string(debugJSON))
-					}
-				}
-
-				if len(cand.GroundingMetadata.GroundingChunks) > 0 {
-					fmt.Println("\nSources:")
-					for i, chunk := range cand.GroundingMetadata.GroundingChunks {
-						if chunk.Web != nil {
-							fmt.Printf("  %d. [Web] %s (%s)\n", i+1, chunk.Web.Title, chunk.Web.URI)
-						} else if chunk.RetrievedContext != nil {
-							title := chunk.RetrievedContext.Title
-							if title == "" {
-								title = "Unknown Document"
-							}
-
-							// Build location string (URI and/or Page)
-							var locParts []string
-							if chunk.RetrievedContext.URI != "" {
-								locParts = append(locParts, fmt.Sprintf("URI: %s", chunk.RetrievedContext.URI))
-							}
-
-							// Check for RAGChunk page numbers
-							if chunk.RetrievedContext.RAGChunk != nil && chunk.RetrievedContext.RAGChunk.PageSpan != nil {
-								span := chunk.RetrievedContext.RAGChunk.PageSpan
-								if span.FirstPage > 0 {
-									if span.FirstPage == span.LastPage || span.LastPage == 0 {
-										locParts = append(locParts, fmt.Sprintf("Page %d", span.FirstPage))
-									} else {
-										locParts = append(locParts, fmt.Sprintf("Pages %d-%d", span.FirstPage, span.LastPage))
-									}
-								}
-							}
-
-							// Fallback: Extract page number from text using regex
-							// Look for pattern like "--- PAGE 17 ---"
-							if chunk.RetrievedContext.Text != "" {
-								re := regexp.MustCompile(`--- PAGE (\d+) ---`)
-								matches := re.FindStringSubmatch(chunk.RetrievedContext.Text)
-								if len(matches) > 1 {
-									// Only add if we haven't already added a page number from RAGChunk
-									alreadyHasPage := false
-									for _, part := range locParts {
-										if strings.Contains(part, "Page") {
-											alreadyHasPage = true
-											break
-										}
-									}
-									if !alreadyHasPage {
-										locParts = append(locParts, fmt.Sprintf("Page %s", matches[1]))
-									}
-								}
-							}
-
-							locStr := ""
-							if len(locParts) > 0 {
-								locStr = fmt.Sprintf(" (%s)", strings.Join(locParts, ", "))
-							}
-
-							fmt.Printf("  %d. [Doc] %s%s\n", i+1, title, locStr)
-
-							if chunk.RetrievedContext.Text != "" {
-								text := chunk.RetrievedContext.Text
-
-								if verbose {
-									// Verbose mode: Print full text but collapse excessive newlines
-									// Replace 3+ newlines with 2
-									re := regexp.MustCompile(`\n{3,}`)
-									text = re.ReplaceAllString(text, "\n\n")
-									fmt.Printf("     Full Text:\n%s\n", text)
-								} else {
-									// Default mode: Clean up snippet (single line)
-									text = strings.ReplaceAll(text, "\n", " ")
-									text = strings.ReplaceAll(text, "\r", " ")
-									text = strings.Join(strings.Fields(text), " ") // Collapse multiple spaces
-
-									// Truncate text if too long
-									if len(text) > 200 {
-										text = text[:197] + "..."
-									}
-									// Indent the snippet
-									fmt.Printf("     Snippet: %s\n", text)
-								}
-							}
-						}
-					}
-				}
+	case []gemini.Source:
+		if len(v) == 0 {
+			fmt.Println("No passages found")
+		}
+		for _, src := range v {
+			if loc := src.Location(); loc != "" {
+				fmt.Printf("%d. %s (%s)\n", src.Index, src.Title, loc)
+			} else {
+				fmt.Printf("%d. %s\n", src.Index, src.Title)
 			}
+			text := regexp.MustCompile(`\n{3,}`).ReplaceAllString(src.Text, "\n\n")
+			fmt.Printf("%s\n\n", text)
+		}
+	case *gemini.StructuredAnswer:
+		out, err := json.MarshalIndent(v.Answer, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		printSources(v.Sources)
+		printUsage(v.Usage)
+	case *gemini.CitedAnswer:
+		var mark func(string) string
+		if queryShowUnsupported {
+			mark = markUnsupported
 		}
+		fmt.Println(strings.TrimRight(v.Render(mark), "\n"))
+		printSources(v.Sources)
+		printUsage(v.Usage)
 	case *gemini.OperationStatus:
 		fmt.Printf("Operation: %s\n", v.Name)
 		fmt.Printf("Type: %s\n", v.Type)
@@ -346,6 +659,53 @@ func printOutput(data interface{}, format string) error {
 				fmt.Printf("  %s: %v\n", k, val)
 			}
 		}
+	case []*chunk.Preview:
+		for i, p := range v {
+			if i > 0 {
+				fmt.Println()
+			}
+			printChunkPreview(p)
+		}
+	case *chunk.Preview:
+		printChunkPreview(v)
+	case *config.EffectiveStoreConfig:
+		fmt.Printf("Store: %s (%s)\n", v.DisplayName, v.Store)
+		if v.ProfileKey == "" {
+			fmt.Println("Profile: none (API defaults apply)")
+			return nil
+		}
+		fmt.Printf("Profile: stores.%s\n", v.ProfileKey)
+		printSetting := func(name string, value int) {
+			if value > 0 {
+				fmt.Printf("%s: %d\n", name, value)
+			} else {
+				fmt.Printf("%s: (API default)\n", name)
+			}
+		}
+		printSetting("Chunk Size", v.Settings.ChunkSize)
+		printSetting("Chunk Overlap", v.Settings.ChunkOverlap)
+		printStringMap := func(name string, m map[string]string) {
+			if len(m) == 0 {
+				return
+			}
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			fmt.Printf("%s:\n", name)
+			for _, k := range keys {
+				fmt.Printf("  %s: %s\n", k, m[k])
+			}
+		}
+		printStringMap("Metadata", v.Settings.Metadata)
+		printStringMap("MIME Types", v.Settings.MIMETypes)
+		if len(v.Settings.Include) > 0 {
+			fmt.Printf("Include: %s\n", strings.Join(v.Settings.Include, ", "))
+		}
+		if len(v.Settings.Exclude) > 0 {
+			fmt.Printf("Exclude: %s\n", strings.Join(v.Settings.Exclude, ", "))
+		}
 	default:
 		// Fallback for simple strings or unknown types
 		fmt.Printf("%v\n", v)
@@ -353,7 +713,92 @@ func printOutput(data interface{}, format string) error {
 	return nil
 }
 
+func printChunkPreview(p *chunk.Preview) {
+	fmt.Printf("File: %s\n", p.Name)
+	fmt.Printf("Chunk Size: %d tokens (overlap %d)\n", p.MaxTokens, p.Overlap)
+	if p.Calibrated {
+		fmt.Printf("Tokens: ~%d (%d words, %.2f tokens/word)\n", p.TotalTokens, p.TotalWords, p.TokensPerWord)
+	} else {
+		fmt.Printf("Tokens: %d (whitespace tokens, uncalibrated)\n", p.TotalWords)
+	}
+	fmt.Printf("Chunks: %d\n", p.ChunkCount)
+	if p.ChunkCount == 0 {
+		return
+	}
+	fmt.Printf("Chunk Sizes: min %d, median %d, mean %.1f, p90 %d, max %d\n",
+		p.Stats.Min, p.Stats.Median, p.Stats.Mean, p.Stats.P90, p.Stats.Max)
+
+	for _, c := range p.Chunks {
+		fmt.Printf("\n  #%d  lines %d-%d, %d tokens\n", c.Index, c.StartLine, c.EndLine, c.Tokens)
+		fmt.Printf("     Start: %s\n", c.Head)
+		fmt.Printf("     End:   %s\n", c.Tail)
+	}
+	if hidden := p.ChunkCount - len(p.Chunks); hidden > 0 {
+		fmt.Printf("\n  ... %d more chunks (use --show to display more)\n", hidden)
+	}
+}
+
+// printUsage prints the token counts of a query in verbose mode
+func printUsage(u *gemini.Usage) {
+	if u == nil || !verbose {
+		return
+	}
+	fmt.Printf("\nTokens: %s\n", u)
+}
+
+// printSources prints a numbered list of grounding sources with a snippet
+// of each chunk, or the full text in verbose mode
+func printSources(sources []gemini.Source) {
+	if len(sources) == 0 {
+		return
+	}
+	fmt.Println("\nSources:")
+	for _, src := range sources {
+		kind := "Doc"
+		if src.Type == "web" {
+			kind = "Web"
+		}
+		if loc := src.Location(); loc != "" {
+			fmt.Printf("  %d. [%s] %s (%s)\n", src.Index, kind, src.Title, loc)
+		} else {
+			fmt.Printf("  %d. [%s] %s\n", src.Index, kind, src.Title)
+		}
+
+		if src.Text == "" {
+			continue
+		}
+		text := src.Text
+		if verbose {
+			// Verbose mode: Print full text but collapse excessive newlines
+			// Replace 3+ newlines with 2
+			re := regexp.MustCompile(`\n{3,}`)
+			text = re.ReplaceAllString(text, "\n\n")
+			fmt.Printf("     Full Text:\n%s\n", text)
+		} else {
+			// Default mode: Clean up snippet (single line)
+			text = strings.Join(strings.Fields(text), " ") // Collapse whitespace and newlines
+
+			// Truncate text if too long
+			if len(text) > 200 {
+				text = text[:197] + "..."
+			}
+			// Indent the snippet
+			fmt.Printf("     Snippet: %s\n", text)
+		}
+	}
+}
+
+// markUnsupported highlights an answer sentence that no source supports
+func markUnsupported(sentence string) string {
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		return "\x1b[33m" + sentence + "\x1b[0m [unsupported]"
+	}
+	return sentence + " [unsupported]"
+}
+
 // Execute runs the root command
 func Execute(ctx context.Context) error {
-	return rootCmd.ExecuteContext(ctx)
+	addCompletionRefresh()
+	// Errors can echo request details, so never let key material through
+	return auth.ScrubError(rootCmd.ExecuteContext(ctx))
 }
@@ -1,9 +1,18 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/mikesmitty/file-search/internal/gemini"
 	"github.com/spf13/viper"
 )
 
@@ -187,4 +196,274 @@ func TestGetCompleterConfiguration(t *testing.T) {
 			t.Error("Expected completion_enabled to be false from config")
 		}
 	})
+
+	t.Run("records operations from concurrent uploads in one completer", func(t *testing.T) {
+		viper.Reset()
+		defer viper.Reset()
+		t.Setenv("HOME", t.TempDir())
+		t.Setenv("XDG_CACHE_HOME", t.TempDir())
+		viper.Set("api_key", "test-key")
+		viper.Set("completion_enabled", true)
+		completerOnce, globalCompleter = sync.Once{}, nil
+		defer func() { completerOnce, globalCompleter = sync.Once{}, nil }()
+
+		opts, err := getClientOptions(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		for i := range 10 {
+			wg.Go(func() {
+				opts.OnOperation(fmt.Sprintf("operations/op%d", i), gemini.OperationTypeUpload, "fileSearchStores/s")
+			})
+		}
+		wg.Wait()
+		if got := getCompleter().GetOperationNames(""); len(got) != 10 {
+			t.Errorf("recorded %d operations, want 10: %v", len(got), got)
+		}
+	})
+}
+
+func TestGetStoreProfiles(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigType("yaml")
+	err := viper.ReadConfig(strings.NewReader(`
+stores:
+  Datasheets:
+    chunk_size: 512
+    chunk_overlap: 64
+    metadata:
+      team: hw
+    mime_types:
+      .log: text/plain
+    exclude:
+      - "*.tmp"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := getStoreProfiles()
+	if err != nil {
+		t.Fatalf("getStoreProfiles failed: %v", err)
+	}
+
+	key, profile := profiles.Lookup("Datasheets")
+	if profile == nil {
+		t.Fatalf("expected profile for Datasheets, got keys %v", profiles)
+	}
+	if key != "datasheets" {
+		t.Errorf("expected lowercased config key, got %q", key)
+	}
+	if profile.ChunkSize != 512 || profile.ChunkOverlap != 64 {
+		t.Errorf("unexpected chunking: %+v", profile)
+	}
+	if profile.Metadata["team"] != "hw" || profile.MIMETypes[".log"] != "text/plain" {
+		t.Errorf("unexpected maps: %+v", profile)
+	}
+	if profile.Allows("scratch.tmp") {
+		t.Error("expected exclude pattern to apply")
+	}
+
+	// Keys from the file keep their dots; only their case is lost
+	viper.Reset()
+	viper.SetConfigType("yaml")
+	err = viper.ReadConfig(strings.NewReader(`
+stores:
+  MyDocs:
+    chunk_size: 256
+profiles:
+  work:
+    stores:
+      Datasheets v1.2:
+        chunk_size: 128
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("profile", "work")
+	if err := applyProfile(); err != nil {
+		t.Fatal(err)
+	}
+	profiles, err = getStoreProfiles()
+	if err != nil {
+		t.Fatalf("getStoreProfiles failed: %v", err)
+	}
+	for name, want := range map[string]int{"MyDocs": 256, "MYDOCS": 256, "Datasheets v1.2": 128} {
+		if _, profile := profiles.Lookup(name); profile == nil || profile.ChunkSize != want {
+			t.Errorf("expected %s to match its profile, got %+v from keys %v", name, profile, profiles)
+		}
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	readConfig := func(t *testing.T, yaml string) {
+		t.Helper()
+		viper.Reset()
+		viper.SetConfigType("yaml")
+		if err := viper.ReadConfig(strings.NewReader(yaml)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const cfg = `
+default_model: gemini-2.5-flash
+current_profile: personal
+profiles:
+  personal:
+    default_model: gemini-2.5-flash-lite
+  work:
+    api_key_env: WORK_KEY
+    default_model: gemini-2.5-pro
+    mcp_tools: [query_knowledge_base, list_stores]
+`
+
+	t.Run("current_profile from config", func(t *testing.T) {
+		readConfig(t, cfg)
+		if err := applyProfile(); err != nil {
+			t.Fatal(err)
+		}
+		if got := viper.GetString("default_model"); got != "gemini-2.5-flash-lite" {
+			t.Errorf("expected personal profile model, got %s", got)
+		}
+	})
+
+	t.Run("explicit profile overrides current_profile", func(t *testing.T) {
+		readConfig(t, cfg)
+		viper.Set("profile", "work")
+		if err := applyProfile(); err != nil {
+			t.Fatal(err)
+		}
+		if got := viper.GetString("default_model"); got != "gemini-2.5-pro" {
+			t.Errorf("expected work profile model, got %s", got)
+		}
+		if got := viper.GetString("api_key_env"); got != "WORK_KEY" {
+			t.Errorf("expected work profile api_key_env, got %s", got)
+		}
+		if got := getMCPTools(); len(got) != 2 || got[1] != "list_stores" {
+			t.Errorf("expected profile MCP tools list, got %v", got)
+		}
+	})
+
+	t.Run("profile name with dots", func(t *testing.T) {
+		readConfig(t, cfg+`
+  Prod.EU:
+    default_model: gemini-2.5-pro-eu
+`)
+		viper.Set("profile", "Prod.EU")
+		if err := applyProfile(); err != nil {
+			t.Fatal(err)
+		}
+		if got := viper.GetString("default_model"); got != "gemini-2.5-pro-eu" {
+			t.Errorf("expected prod.eu profile model, got %s", got)
+		}
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		readConfig(t, cfg)
+		viper.Set("profile", "missing")
+		if err := applyProfile(); err == nil {
+			t.Error("expected error for unknown profile")
+		}
+	})
+
+	viper.Reset()
+}
+
+func TestWithDefaultStore(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	quiet = true
+	defer func() { quiet = false }()
+
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "docs", "api")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".file-search-store"), []byte("# project store\nTeam Docs\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(sub)
+	t.Setenv("FILE_SEARCH_STORE", "")
+	viper.Set("default_store", "Config Store")
+
+	t.Run("flags win", func(t *testing.T) {
+		t.Setenv("FILE_SEARCH_STORE", "Env Store")
+		name, id, err := withDefaultStore("", "fileSearchStores/abc")
+		if err != nil || name != "" || id != "fileSearchStores/abc" {
+			t.Errorf("got (%q, %q, %v)", name, id, err)
+		}
+	})
+
+	t.Run("env before marker", func(t *testing.T) {
+		t.Setenv("FILE_SEARCH_STORE", "fileSearchStores/env")
+		name, id, err := withDefaultStore("", "")
+		if err != nil || name != "" || id != "fileSearchStores/env" {
+			t.Errorf("got (%q, %q, %v)", name, id, err)
+		}
+	})
+
+	t.Run("marker before config", func(t *testing.T) {
+		name, id, err := withDefaultStore("", "")
+		if err != nil || name != "Team Docs" || id != "" {
+			t.Errorf("got (%q, %q, %v)", name, id, err)
+		}
+	})
+
+	t.Run("config", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		name, _, err := withDefaultStore("", "")
+		if err != nil || name != "Config Store" {
+			t.Errorf("got (%q, %v)", name, err)
+		}
+	})
+}
+
+func TestPromptCandidate(t *testing.T) {
+	candidates := []gemini.Candidate{
+		{Name: "fileSearchStores/s/documents/a", DisplayName: "datasheet.pdf"},
+		{Name: "fileSearchStores/s/documents/b", DisplayName: "datasheet.pdf"},
+	}
+
+	var out bytes.Buffer
+	got, ok := promptCandidate(strings.NewReader("2\n"), &out, "document", "datasheet.pdf", candidates)
+	if !ok || got.Name != "fileSearchStores/s/documents/b" {
+		t.Errorf("promptCandidate() = %+v, %v", got, ok)
+	}
+	if !strings.Contains(out.String(), `Several documents are named "datasheet.pdf"`) || !strings.Contains(out.String(), "2) datasheet.pdf (fileSearchStores/s/documents/b)") {
+		t.Errorf("unexpected prompt:\n%s", out.String())
+	}
+
+	for _, input := range []string{"\n", "3\n", "x\n", ""} {
+		if _, ok := promptCandidate(strings.NewReader(input), io.Discard, "document", "datasheet.pdf", candidates); ok {
+			t.Errorf("expected input %q to cancel", input)
+		}
+	}
+
+	out.Reset()
+	promptCandidate(strings.NewReader("\n"), &out, "store", "docz", []gemini.Candidate{{Name: "fileSearchStores/d", DisplayName: "Docs"}})
+	if !strings.Contains(out.String(), `No store is named "docz". Did you mean:`) {
+		t.Errorf("unexpected prompt:\n%s", out.String())
+	}
+
+	// Prompts sharing a buffered reader each get their own answer
+	in := bufio.NewReader(strings.NewReader("2\n1\n"))
+	first, _ := promptCandidate(in, io.Discard, "document", "datasheet.pdf", candidates)
+	second, _ := promptCandidate(in, io.Discard, "document", "datasheet.pdf", candidates)
+	if first.Name != candidates[1].Name || second.Name != candidates[0].Name {
+		t.Errorf("shared reader answers = %s, %s", first.Name, second.Name)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for input, want := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		var out bytes.Buffer
+		if got := confirm(strings.NewReader(input), &out, "Delete 2 documents?"); got != want {
+			t.Errorf("confirm(%q) = %v, want %v", input, got, want)
+		}
+		if out.String() != "Delete 2 documents? [y/N]: " {
+			t.Errorf("unexpected prompt %q", out.String())
+		}
+	}
 }
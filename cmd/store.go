@@ -2,8 +2,12 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/mikesmitty/file-search/internal/completion"
+	"github.com/mikesmitty/file-search/internal/config"
 	"github.com/mikesmitty/file-search/internal/constants"
 	"github.com/mikesmitty/file-search/internal/gemini"
 	"github.com/spf13/cobra"
@@ -43,7 +47,7 @@ func init() {
 		Short: "Get details of a File Search Store",
 		Args:  cobra.ExactArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-			return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+			return getCompleter().GetStoreNames(toComplete), cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
@@ -75,7 +79,7 @@ func init() {
 		Short:   "Delete a File Search Store",
 		Args:    cobra.ExactArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-			return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+			return getCompleter().GetStoreNames(toComplete), cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
@@ -95,6 +99,7 @@ func init() {
 			if err != nil {
 				return err
 			}
+			invalidateCompletions(completion.KeyStores, completion.KeyDocuments)
 			if outputFormat == "json" {
 				return printOutput(map[string]string{"status": "deleted", "name": args[0]}, "json")
 			}
@@ -122,6 +127,7 @@ func init() {
 			if err != nil {
 				return err
 			}
+			invalidateCompletions(completion.KeyStores)
 			if outputFormat == "json" {
 				return printOutput(store, "json")
 			}
@@ -134,13 +140,21 @@ func init() {
 	var importFileStore string
 	var importFileStoreID string
 	var importConcurrency int
+	var importChunkSize int
+	var importChunkOverlap int
+	var importMetadata []string
+	var importOnDuplicate string
 	importFileCmd := &cobra.Command{
 		Use:   "import-file [file-name-or-id]...",
 		Short: "Import files from Files API into a Store",
 		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if importFileStore == "" && importFileStoreID == "" {
-				return fmt.Errorf("either --store or --store-id is required")
+			storeName, storeID, err := withDefaultStore(importFileStore, importFileStoreID)
+			if err != nil {
+				return err
+			}
+			if storeName == "" && storeID == "" {
+				return fmt.Errorf("either --store or --store-id is required (or set a default store)")
 			}
 			ctx := context.Background()
 			client, err := getClient(ctx)
@@ -150,14 +164,67 @@ func init() {
 			defer client.Close()
 
 			// Resolve store name to ID if --store was used
-			storeID := importFileStoreID
-			if importFileStore != "" {
-				storeID, err = client.ResolveStoreName(ctx, importFileStore)
+			if storeName != "" {
+				storeID, err = client.ResolveStoreName(ctx, storeName)
 				if err != nil {
 					return err
 				}
 			}
 
+			// Parse metadata from key=value strings
+			metadataMap := make(map[string]string)
+			for _, meta := range importMetadata {
+				parts := strings.SplitN(meta, "=", 2)
+				if len(parts) == 2 {
+					metadataMap[parts[0]] = parts[1]
+				}
+			}
+
+			// Apply the store's configured defaults under any explicit flags
+			chunkSize, chunkOverlap := importChunkSize, importChunkOverlap
+			_, profile, err := lookupStoreProfile(ctx, client, storeID, storeName)
+			if err != nil {
+				return err
+			}
+			if profile != nil {
+				if !cmd.Flags().Changed("chunk-size") {
+					chunkSize = profile.ChunkSize
+				}
+				if !cmd.Flags().Changed("chunk-overlap") {
+					chunkOverlap = profile.ChunkOverlap
+				}
+				metadataMap = profile.MergeMetadata(metadataMap)
+
+				if len(profile.Include) > 0 || len(profile.Exclude) > 0 {
+					var allowed []string
+					for _, arg := range args {
+						// Patterns match the file's display name
+						name := arg
+						if strings.HasPrefix(arg, constants.FileResourcePrefix) {
+							f, err := client.GetFile(ctx, arg)
+							if err != nil {
+								return err
+							}
+							name = f.DisplayName
+						}
+						if profile.Allows(name) {
+							allowed = append(allowed, arg)
+						} else if !quiet {
+							fmt.Printf("[-] Skipping %s (excluded by store config)\n", arg)
+						}
+					}
+					if len(allowed) == 0 {
+						return fmt.Errorf("all files were excluded by the include/exclude patterns configured for this store")
+					}
+					args = allowed
+				}
+			}
+
+			onDuplicate, err := duplicatePolicy(cmd, importOnDuplicate, profile)
+			if err != nil {
+				return err
+			}
+
 			// Define the processor function for a single file ID/name
 			processor := func(ctx context.Context, fileIDOrName string) error {
 				// Resolve file name to ID
@@ -171,14 +238,23 @@ func init() {
 				}
 
 				err = client.ImportFile(ctx, fileID, storeID, &gemini.ImportFileOptions{
-					Quiet: true, // Force quiet for inner operation
+					MaxChunkTokens: chunkSize,
+					ChunkOverlap:   chunkOverlap,
+					Metadata:       metadataMap,
+					Quiet:          true, // Force quiet for inner operation
+					OnDuplicate:    onDuplicate,
 				})
+				if errors.Is(err, gemini.ErrDuplicateSkipped) {
+					return skipped(err)
+				}
 				return err
 			}
 
 			// Define the progress callback
 			onProgress := func(current, total int, file string, err error) {
-				if err != nil {
+				if errors.Is(err, errSkipped) {
+					fmt.Printf("[%d/%d] - Skipped: %s (%v)\n", current, total, file, err)
+				} else if err != nil {
 					fmt.Printf("[%d/%d] ✗ Failed: %s (%v)\n", current, total, file, err)
 				} else {
 					fmt.Printf("[%d/%d] ✓ Finished: %s\n", current, total, file)
@@ -191,6 +267,9 @@ func init() {
 				Quiet:       quiet,
 				OnProgress:  onProgress,
 			})
+			if len(batchResult.Succeeded) > 0 {
+				invalidateCompletions(completion.KeyStores, completion.KeyDocuments)
+			}
 
 			// Print summary
 			if !quiet {
@@ -198,6 +277,9 @@ func init() {
 					fmt.Printf("\n\nSummary:\n")
 					fmt.Printf("  ✓ Succeeded: %d\n", len(batchResult.Succeeded))
 					fmt.Printf("  ✗ Failed: %d\n", len(batchResult.Failed))
+					if len(batchResult.Skipped) > 0 {
+						fmt.Printf("  - Skipped: %d\n", len(batchResult.Skipped))
+					}
 				}
 			}
 
@@ -207,11 +289,15 @@ func init() {
 				jsonResult["total"] = batchResult.Total
 				jsonResult["succeeded"] = len(batchResult.Succeeded)
 				jsonResult["failed"] = len(batchResult.Failed)
+				jsonResult["skipped"] = len(batchResult.Skipped)
 
 				filesSummary := make([]map[string]interface{}, 0, batchResult.Total)
 				for _, f := range batchResult.Succeeded {
 					filesSummary = append(filesSummary, map[string]interface{}{"file": f, "status": "success", "store": storeID})
 				}
+				for f, err := range batchResult.Skipped {
+					filesSummary = append(filesSummary, map[string]interface{}{"file": f, "status": "skipped", "reason": err.Error(), "store": storeID})
+				}
 				for f, err := range batchResult.Failed {
 					filesSummary = append(filesSummary, map[string]interface{}{"file": f, "status": "failed", "error": err.Error(), "store": storeID})
 				}
@@ -239,11 +325,64 @@ func init() {
 	importFileCmd.Flags().StringVar(&importFileStore, "store", "", "Store display name")
 	importFileCmd.Flags().StringVar(&importFileStoreID, "store-id", "", "Store resource ID ("+constants.StoreResourcePrefix+"xxx)")
 	importFileCmd.Flags().IntVar(&importConcurrency, "concurrency", 5, "Number of parallel imports")
+	importFileCmd.Flags().IntVar(&importChunkSize, "chunk-size", 0, "Max tokens per chunk")
+	importFileCmd.Flags().IntVar(&importChunkOverlap, "chunk-overlap", 0, "Overlap tokens between chunks")
+	importFileCmd.Flags().StringArrayVar(&importMetadata, "metadata", []string{}, "Custom metadata as key=value (repeatable)")
+	importFileCmd.Flags().StringVar(&importOnDuplicate, "on-duplicate", string(gemini.DuplicateSkip), "When the store has a document with the same name or content: skip (same content only; a changed file under an existing name fails), replace, keep or error")
+	importFileCmd.RegisterFlagCompletionFunc("on-duplicate", completeDuplicatePolicy)
 	importFileCmd.RegisterFlagCompletionFunc("store", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+		return getCompleter().GetStoreNames(toComplete), cobra.ShellCompDirectiveNoFileComp
 	})
 	importFileCmd.RegisterFlagCompletionFunc("store-id", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return getCompleter().GetStoreNames(), cobra.ShellCompDirectiveNoFileComp
+		return getCompleter().GetStoreIDs(toComplete), cobra.ShellCompDirectiveNoFileComp
 	})
 	storeCmd.AddCommand(importFileCmd)
+
+	// Store config
+	storeConfigCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect per-store upload defaults",
+	}
+	storeConfigShowCmd := &cobra.Command{
+		Use:   "show [name]",
+		Short: "Show the effective upload settings for a store",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return getCompleter().GetStoreNames(toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			client, err := getClient(ctx)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			storeID, err := client.ResolveStoreName(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			store, err := client.GetStore(ctx, storeID)
+			if err != nil {
+				return err
+			}
+
+			profiles, err := getStoreProfiles()
+			if err != nil {
+				return err
+			}
+			key, profile := profiles.Lookup(store.DisplayName, store.Name)
+			if profile == nil {
+				profile = &config.StoreProfile{}
+			}
+			return printOutput(&config.EffectiveStoreConfig{
+				Store:       store.Name,
+				DisplayName: store.DisplayName,
+				ProfileKey:  key,
+				Settings:    profile,
+			}, outputFormat)
+		},
+	}
+	storeConfigCmd.AddCommand(storeConfigShowCmd)
+	storeCmd.AddCommand(storeConfigCmd)
 }
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/completion"
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	dedupeKeep   string
+	dedupeDryRun bool
+	dedupeYes    bool
+)
+
+var storeDedupeCmd = &cobra.Command{
+	Use:   "dedupe <store>",
+	Short: "Find and delete duplicate documents in a store",
+	Long: `Find documents with the same display name, size, MIME type and content
+hash (the content_sha256 metadata set on upload), show each group, and delete
+all but the newest (or, with --keep oldest, the oldest) of each. An active
+document is always kept over failed or pending copies.
+
+Deleting asks for confirmation unless --yes is given. Use --dry-run to only
+show the groups.`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getCompleter().GetStoreNames(toComplete), cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		client, err := getClient(ctx)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		storeID, err := client.ResolveStoreName(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		docs, err := client.ListDocuments(ctx, storeID)
+		if err != nil {
+			return err
+		}
+		groups, err := gemini.GroupDuplicates(docs, dedupeKeep)
+		if err != nil {
+			return fmt.Errorf("invalid --keep: %w", err)
+		}
+
+		var toDelete []gemini.Candidate
+		for _, g := range groups {
+			toDelete = append(toDelete, g.Delete...)
+		}
+		if outputFormat != "json" {
+			printDuplicateGroups(args[0], groups, len(toDelete))
+		}
+
+		var deleted []string
+		failed := make(map[string]string)
+		if len(toDelete) > 0 && !dedupeDryRun {
+			if !dedupeYes {
+				if !term.IsTerminal(int(os.Stdin.Fd())) {
+					return fmt.Errorf("refusing to delete %d documents without confirmation; pass --yes or --dry-run", len(toDelete))
+				}
+				if !confirmStdin(fmt.Sprintf("Delete %d duplicate documents from %s?", len(toDelete), args[0])) {
+					return fmt.Errorf("cancelled")
+				}
+			}
+			for _, doc := range toDelete {
+				if err := client.DeleteDocument(ctx, doc.Name, true); err != nil {
+					failed[doc.Name] = err.Error()
+					if outputFormat != "json" {
+						fmt.Printf("✗ Failed to delete %s: %v\n", doc.Name, err)
+					}
+					continue
+				}
+				deleted = append(deleted, doc.Name)
+			}
+			if len(deleted) > 0 {
+				invalidateCompletions(completion.KeyStores, completion.KeyDocuments)
+			}
+		}
+
+		if outputFormat == "json" {
+			if err := printOutput(map[string]interface{}{
+				"store":   storeID,
+				"keep":    dedupeKeep,
+				"dry_run": dedupeDryRun,
+				"groups":  groups,
+				"deleted": deleted,
+				"failed":  failed,
+			}, "json"); err != nil {
+				return err
+			}
+		} else if dedupeDryRun && len(toDelete) > 0 {
+			fmt.Println("Dry run: no documents were deleted.")
+		} else if len(deleted) > 0 {
+			fmt.Printf("Deleted %d duplicate documents.\n", len(deleted))
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to delete %d of %d documents", len(failed), len(toDelete))
+		}
+		return nil
+	},
+}
+
+func init() {
+	storeCmd.AddCommand(storeDedupeCmd)
+
+	storeDedupeCmd.Flags().StringVar(&dedupeKeep, "keep", gemini.KeepNewest, "Which document of each group to keep: newest or oldest")
+	storeDedupeCmd.Flags().BoolVar(&dedupeDryRun, "dry-run", false, "Show the duplicate groups without deleting anything")
+	storeDedupeCmd.Flags().BoolVarP(&dedupeYes, "yes", "y", false, "Delete without asking for confirmation")
+	storeDedupeCmd.RegisterFlagCompletionFunc("keep", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{gemini.KeepNewest, gemini.KeepOldest}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// printDuplicateGroups lists each group with the document kept and the
+// documents to delete
+func printDuplicateGroups(store string, groups []gemini.DuplicateGroup, toDelete int) {
+	if len(groups) == 0 {
+		fmt.Printf("No duplicate documents in %s.\n", store)
+		return
+	}
+	fmt.Printf("Found %d duplicate groups in %s (%d documents to delete):\n", len(groups), store, toDelete)
+	for _, g := range groups {
+		fmt.Printf("\n%s (%d bytes, %s", g.DisplayName, g.SizeBytes, g.MIMEType)
+		if g.ContentHash != "" {
+			fmt.Printf(", sha256 %.12s", g.ContentHash)
+		}
+		fmt.Println(")")
+		fmt.Printf("  keep    %s\n", dedupeLine(g.Keep))
+		for _, c := range g.Delete {
+			fmt.Printf("  delete  %s\n", dedupeLine(c))
+		}
+	}
+	fmt.Println()
+}
+
+func dedupeLine(c gemini.Candidate) string {
+	if c.CreateTime.IsZero() {
+		return c.Name
+	}
+	return fmt.Sprintf("%s (created %s)", c.Name, c.CreateTime.Local().Format(time.DateTime))
+}
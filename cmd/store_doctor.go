@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/completion"
+	"github.com/mikesmitty/file-search/internal/convert"
+	"github.com/mikesmitty/file-search/internal/doctor"
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"github.com/mikesmitty/file-search/internal/split"
+	"github.com/mikesmitty/file-search/internal/upload"
+	"github.com/spf13/cobra"
+	"google.golang.org/genai"
+)
+
+var (
+	doctorPendingAfter time.Duration
+	doctorExpiryWindow time.Duration
+	doctorExpect       []string
+	doctorRetry        bool
+)
+
+var storeDoctorCmd = &cobra.Command{
+	Use:   "doctor <store>",
+	Short: "Report failed, stuck and duplicate documents in a store",
+	Long: `Audit a store and list what needs attention:
+
+  - documents that failed to index, with their metadata
+  - documents pending for longer than --pending-after
+  - duplicate documents (see store dedupe)
+  - documents missing the metadata keys set in the store's config or --expect-metadata
+  - empty documents and documents far larger than the median
+  - files in the Files API expiring within --expiry-window that are not in the store
+
+With --retry, failed documents are uploaded again from the source_path
+recorded in their metadata, and the failed copy is deleted once the new one
+is active. If the new upload fails, the failed copy is kept. Uploads only record source_path with file upload --record-source
+or record_source in the store's config. Use --format json for monitoring; "healthy" is false when any
+issue is found.`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return getCompleter().GetStoreNames(toComplete), cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		client, err := getClient(ctx)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		storeID, err := client.ResolveStoreName(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		store, err := client.GetStore(ctx, storeID)
+		if err != nil {
+			return err
+		}
+		docs, err := client.ListDocuments(ctx, storeID)
+		if err != nil {
+			return err
+		}
+		files, err := client.ListFiles(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Every upload gets the profile's metadata, so documents without
+		// it predate the profile or bypassed it
+		_, profile, err := lookupStoreProfile(ctx, client, storeID, store.DisplayName)
+		if err != nil {
+			return err
+		}
+		expected := slices.Clone(doctorExpect)
+		if profile != nil {
+			for _, key := range slices.Sorted(maps.Keys(profile.Metadata)) {
+				if !slices.Contains(expected, key) {
+					expected = append(expected, key)
+				}
+			}
+		}
+
+		report := doctor.Check(store, docs, files, doctor.Options{
+			PendingAfter:     doctorPendingAfter,
+			ExpiryWindow:     doctorExpiryWindow,
+			ExpectedMetadata: expected,
+		})
+
+		var retries []retryResult
+		if doctorRetry && len(report.FailedDocuments) > 0 {
+			var chunkSize, chunkOverlap int
+			if profile != nil {
+				chunkSize, chunkOverlap = profile.ChunkSize, profile.ChunkOverlap
+			}
+			registry, err := getConverterRegistry()
+			if err != nil {
+				return err
+			}
+			for _, doc := range docs {
+				if doc.State != genai.DocumentStateFailed {
+					continue
+				}
+				if outputFormat != "json" && !quiet {
+					fmt.Printf("[+] Retrying %s\n", doc.DisplayName)
+				}
+				result := retryDocument(ctx, client, storeID, doc, &upload.Options{Converters: registry}, chunkSize, chunkOverlap)
+				retries = append(retries, result)
+			}
+			invalidateCompletions(completion.KeyStores, completion.KeyDocuments)
+		}
+
+		var retryFailures int
+		for _, r := range retries {
+			if r.Error != "" {
+				retryFailures++
+			}
+		}
+
+		if outputFormat == "json" {
+			if err := printOutput(struct {
+				*doctor.Report
+				Retried []retryResult `json:"retried,omitempty"`
+			}{report, retries}, "json"); err != nil {
+				return err
+			}
+		} else {
+			printDoctorReport(report, doctorPendingAfter, doctorExpiryWindow)
+			for _, r := range retries {
+				if r.Error != "" {
+					fmt.Printf("✗ Retry of %s failed: %s\n", r.DisplayName, r.Error)
+				} else {
+					fmt.Printf("✓ Re-uploaded %s from %s\n", r.DisplayName, r.Source)
+				}
+			}
+		}
+		if retryFailures > 0 {
+			return fmt.Errorf("%d of %d retries failed", retryFailures, len(retries))
+		}
+		return nil
+	},
+}
+
+func init() {
+	storeCmd.AddCommand(storeDoctorCmd)
+
+	storeDoctorCmd.Flags().DurationVar(&doctorPendingAfter, "pending-after", doctor.DefaultPendingAfter, "Flag documents pending for longer than this")
+	storeDoctorCmd.Flags().DurationVar(&doctorExpiryWindow, "expiry-window", doctor.DefaultExpiryWindow, "Flag unimported files that expire within this")
+	storeDoctorCmd.Flags().StringSliceVar(&doctorExpect, "expect-metadata", nil, "Metadata keys every document should have, in addition to the store config's (repeatable)")
+	storeDoctorCmd.Flags().BoolVar(&doctorRetry, "retry", false, "Upload failed documents again from their recorded source path")
+}
+
+// retryResult records the outcome of re-uploading a failed document
+type retryResult struct {
+	Document    string `json:"document"`
+	DisplayName string `json:"display_name"`
+	Source      string `json:"source,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// retryDocument uploads a failed document again from its recorded source
+// path with its original metadata, then deletes the failed copy. UploadFile
+// only succeeds once the new document is active, so a failed re-upload keeps
+// the original.
+func retryDocument(ctx context.Context, client *gemini.Client, storeID string, doc *genai.Document, prepareOpts *upload.Options, chunkSize, chunkOverlap int) retryResult {
+	metadata := doctor.Metadata(doc)
+	result := retryResult{Document: doc.Name, DisplayName: doc.DisplayName, Source: metadata[upload.MetaSourcePath]}
+	fail := func(format string, args ...any) retryResult {
+		result.Error = fmt.Sprintf(format, args...)
+		return result
+	}
+	if result.Source == "" {
+		return fail("no source path recorded (upload with --record-source to allow retries); upload it again with file upload --on-duplicate replace")
+	}
+	if _, ok := metadata[split.MetaPart]; ok {
+		return fail("parts of split files can't be retried alone; upload %s again with --split --on-duplicate replace", result.Source)
+	}
+
+	// Converted documents are named after their source, e.g. an archive
+	sourceName := metadata[convert.MetaSourceName]
+	if sourceName == "" {
+		sourceName = doc.DisplayName
+	}
+	prepared, err := upload.Prepare(ctx, result.Source, sourceName, prepareOpts)
+	if err != nil {
+		return fail("%v", err)
+	}
+	defer prepared.Cleanup()
+
+	var item *upload.Item
+	for _, it := range prepared.Items {
+		if it.DisplayName == doc.DisplayName {
+			item = it
+			break
+		}
+	}
+	if item == nil {
+		return fail("%s no longer produces a document named %q", result.Source, doc.DisplayName)
+	}
+
+	// The hash is recomputed, in case the source changed since
+	delete(metadata, gemini.ContentHashKey)
+	mimeType := doc.MIMEType
+	if mimeType == "" {
+		mimeType = item.MIMEType
+	}
+	if _, err := client.UploadFile(ctx, item.Path, &gemini.UploadFileOptions{
+		StoreName:      storeID,
+		DisplayName:    doc.DisplayName,
+		MIMEType:       mimeType,
+		MaxChunkTokens: chunkSize,
+		ChunkOverlap:   chunkOverlap,
+		Metadata:       convert.WithProvenance(metadata, item.Metadata),
+		Quiet:          true,
+		OnDuplicate:    gemini.DuplicateKeep,
+	}); err != nil {
+		return fail("re-upload failed, keeping the failed document: %v", err)
+	}
+	if err := client.DeleteDocument(ctx, doc.Name, true); err != nil {
+		return fail("re-uploaded, but failed to delete the failed document: %v", err)
+	}
+	return result
+}
+
+// printDoctorReport prints each check that found something
+func printDoctorReport(r *doctor.Report, pendingAfter, expiryWindow time.Duration) {
+	fmt.Printf("Store: %s (%s)\n", r.DisplayName, r.Store)
+	fmt.Printf("Documents: %d (%d active, %d pending, %d failed)\n", r.Documents, r.Active, r.Pending, r.Failed)
+
+	printIssues := func(title string, issues []doctor.DocumentIssue) {
+		if len(issues) == 0 {
+			return
+		}
+		fmt.Printf("\n%s (%d):\n", title, len(issues))
+		for _, d := range issues {
+			fmt.Printf("  %s (%s, created %s)", d.DisplayName, d.Name, d.CreateTime.Local().Format(time.DateTime))
+			if d.Detail != "" {
+				fmt.Printf(": %s", d.Detail)
+			}
+			fmt.Println()
+			if len(d.Metadata) > 0 {
+				fmt.Printf("    %s\n", doctor.FormatMetadata(d.Metadata))
+			}
+		}
+	}
+	printIssues("Failed documents", r.FailedDocuments)
+	printIssues(fmt.Sprintf("Pending for over %s", pendingAfter), r.StalePending)
+
+	if len(r.Duplicates) > 0 {
+		fmt.Printf("\nDuplicate groups (%d, clean up with store dedupe):\n", len(r.Duplicates))
+		for _, g := range r.Duplicates {
+			fmt.Printf("  %s: %d copies\n", g.DisplayName, len(g.Delete)+1)
+		}
+	}
+
+	printIssues("Missing metadata", r.MissingMetadata)
+	printIssues("Size outliers", r.SizeOutliers)
+
+	if len(r.ExpiringFiles) > 0 {
+		fmt.Printf("\nFiles expiring within %s that are not in this store (%d):\n", expiryWindow, len(r.ExpiringFiles))
+		for _, f := range r.ExpiringFiles {
+			fmt.Printf("  %s (%s): expires in %s\n", f.DisplayName, f.Name, f.ExpiresIn)
+		}
+	}
+
+	if r.Healthy {
+		fmt.Println("\nNo problems found.")
+	} else {
+		fmt.Printf("\n%d issues found.\n", r.Issues)
+	}
+}
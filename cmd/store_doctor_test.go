@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"github.com/mikesmitty/file-search/internal/upload"
+	"google.golang.org/genai"
+)
+
+// retryTransport accepts an upload whose document ends in state, and
+// records deletions
+type retryTransport struct {
+	mu      sync.Mutex
+	state   genai.DocumentState
+	deleted []string
+}
+
+func (r *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	body := `{}`
+	path := strings.TrimPrefix(req.URL.Path, "/v1beta/")
+	switch {
+	case req.Header.Get("X-Goog-Upload-Command") == "start":
+		header.Set("X-Goog-Upload-Url", "https://generativelanguage.googleapis.com/upload/session")
+	case strings.Contains(req.Header.Get("X-Goog-Upload-Command"), "finalize"):
+		header.Set("X-Goog-Upload-Status", "final")
+		body = `{"name":"op","done":true,"response":{"documentName":"fileSearchStores/s/documents/new"}}`
+	case req.Method == http.MethodDelete:
+		r.deleted = append(r.deleted, path)
+	case path == "fileSearchStores/s/documents/new":
+		body = fmt.Sprintf(`{"name":"%s","state":"%s"}`, path, r.state)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestRetryDocument(t *testing.T) {
+	ctx := context.Background()
+	source := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(source, []byte("notes"), 0o600)
+	failed := &genai.Document{
+		Name:           "fileSearchStores/s/documents/old",
+		DisplayName:    "notes.txt",
+		MIMEType:       "text/plain",
+		State:          genai.DocumentStateFailed,
+		CustomMetadata: []*genai.CustomMetadata{{Key: upload.MetaSourcePath, StringValue: source}},
+	}
+
+	for _, state := range []genai.DocumentState{genai.DocumentStateFailed, genai.DocumentStateActive} {
+		t.Run(string(state), func(t *testing.T) {
+			transport := &retryTransport{state: state}
+			client, err := gemini.NewClientWithOptions(ctx, gemini.ClientOptions{APIKey: "k", HTTPClient: &http.Client{Transport: transport}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := retryDocument(ctx, client, "fileSearchStores/s", failed, nil, 0, 0)
+			if state != genai.DocumentStateActive {
+				if result.Error == "" || len(transport.deleted) != 0 {
+					t.Errorf("expected an error and the failed copy kept, got %+v and deletions %v", result, transport.deleted)
+				}
+				return
+			}
+			if result.Error != "" || len(transport.deleted) != 1 || transport.deleted[0] != failed.Name {
+				t.Errorf("expected the failed copy replaced, got %+v and deletions %v", result, transport.deleted)
+			}
+		})
+	}
+}
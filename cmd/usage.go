@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"github.com/mikesmitty/file-search/internal/usage"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report token usage and estimated cost",
+}
+
+var usageReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Summarize recorded token usage",
+	Long: `Summarize the token usage recorded in the local usage ledger, grouped by
+store, model or command, with an estimated cost.
+
+Every query and upload is recorded unless usage.enabled is false. Costs are
+estimates from a rate table in USD per million tokens, which can be
+overridden in the config:
+
+  usage:
+    indexing_rate: 0.15
+    rates:
+      gemini-2.5-flash: {input: 0.30, output: 2.50}`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := usage.ParseSince(usageSince, time.Now())
+		if err != nil {
+			return err
+		}
+		rates, err := usageRates()
+		if err != nil {
+			return err
+		}
+		path, err := usageLedgerPath()
+		if err != nil {
+			return err
+		}
+		records, err := usage.Read(path, since)
+		if err != nil {
+			return err
+		}
+		rows, err := usage.Report(records, usageBy, rates)
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			return printOutput(rows, outputFormat)
+		}
+		if len(rows) == 0 {
+			fmt.Printf("No usage recorded since %s\n", since.Format(time.DateTime))
+			return nil
+		}
+		return usage.WriteReport(os.Stdout, usageBy, rows)
+	},
+}
+
+var (
+	usageSince string
+	usageBy    string
+)
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+	usageCmd.AddCommand(usageReportCmd)
+
+	usageReportCmd.Flags().StringVar(&usageSince, "since", "7d", "Start of the report period (e.g. 7d, 2w, 12h or 2025-06-01)")
+	usageReportCmd.Flags().StringVar(&usageBy, "by", usage.ByStore, "Group by store, model or command")
+	usageReportCmd.RegisterFlagCompletionFunc("by", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{usage.ByStore, usage.ByModel, usage.ByCommand}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// usageLedgerPath returns the path of the per-user usage ledger
+func usageLedgerPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return usage.Path(home), nil
+}
+
+// usageRates returns the default rate table with usage.rates and
+// usage.indexing_rate from the config applied over it
+func usageRates() (usage.Rates, error) {
+	rates := usage.DefaultRates()
+	var models map[string]usage.Rate
+	if err := viper.UnmarshalKey("usage.rates", &models); err != nil {
+		return rates, fmt.Errorf("invalid usage.rates config: %w", err)
+	}
+	for model, rate := range models {
+		rates.Models[strings.TrimPrefix(model, "models/")] = rate
+	}
+	if viper.IsSet("usage.indexing_rate") {
+		rates.Indexing = viper.GetFloat64("usage.indexing_rate")
+	}
+	return rates, nil
+}
+
+// usageHook returns a hook that records usage in the ledger under the
+// running command, or nil if usage recording is disabled. Requests labelled
+// with gemini.WithUsageLabel (e.g. MCP tool calls) are recorded as
+// "<command> <label>".
+func usageHook() gemini.UsageHook {
+	if !viper.GetBool("usage.enabled") {
+		return nil
+	}
+	path, err := usageLedgerPath()
+	if err != nil {
+		return nil
+	}
+	ledger := usage.NewLedger(path)
+	command := commandPath
+	return func(ctx context.Context, e gemini.UsageEvent) {
+		rec := usage.Record{
+			Time:      time.Now(),
+			Command:   command,
+			Operation: e.Operation,
+			Model:     e.Model,
+			Store:     e.Store,
+			Usage:     e.Usage,
+		}
+		if label := gemini.UsageLabel(ctx); label != "" {
+			rec.Command = strings.TrimSpace(command + " " + label)
+		}
+		if err := ledger.Append(rec); err != nil && debug {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record usage: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"github.com/mikesmitty/file-search/internal/usage"
+	"github.com/spf13/viper"
+)
+
+func TestUsageRates(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader(`
+usage:
+  indexing_rate: 0.2
+  rates:
+    models/gemini-2.5-flash: {input: 1, output: 2}
+    my-tuned-model: {input: 3, output: 4}
+`)); err != nil {
+		t.Fatal(err)
+	}
+
+	rates, err := usageRates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates.Indexing != 0.2 {
+		t.Errorf("indexing rate = %v, want 0.2", rates.Indexing)
+	}
+	if r := rates.Models["gemini-2.5-flash"]; r.Input != 1 || r.Output != 2 {
+		t.Errorf("gemini-2.5-flash rate = %+v, want override", r)
+	}
+	if r := rates.Models["my-tuned-model"]; r.Input != 3 {
+		t.Errorf("my-tuned-model rate = %+v, want added", r)
+	}
+	if _, ok := rates.Models["gemini-2.5-pro"]; !ok {
+		t.Error("expected default rates to be kept")
+	}
+}
+
+func TestUsageHook(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	oldPath := commandPath
+	defer func() { commandPath = oldPath }()
+	commandPath = "file-search mcp"
+
+	if usageHook() != nil {
+		t.Fatal("expected no hook when usage.enabled is unset")
+	}
+	viper.Set("usage.enabled", true)
+	hook := usageHook()
+	if hook == nil {
+		t.Fatal("expected a hook")
+	}
+	ctx := gemini.WithUsageLabel(context.Background(), "query_knowledge_base")
+	hook(ctx, gemini.UsageEvent{Operation: gemini.UsageQuery, Model: "gemini-2.5-flash", Store: "fileSearchStores/abc", Usage: gemini.Usage{PromptTokens: 7}})
+
+	path, err := usageLedgerPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := usage.Read(path, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Command != "file-search mcp query_knowledge_base" || records[0].PromptTokens != 7 {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
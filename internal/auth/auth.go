@@ -0,0 +1,148 @@
+// Package auth stores Gemini API keys outside the plaintext config file: in the
+// OS keyring (Secret Service on Linux, Keychain on macOS, Credential Manager on
+// Windows) or, where no keyring is available, in a passphrase-encrypted age file.
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/zalando/go-keyring"
+)
+
+// Service is the keyring service name keys are stored under
+const Service = "file-search"
+
+// DefaultAccount is the keyring account used when no profile is active
+const DefaultAccount = "default"
+
+// ErrNotFound is returned when no key is stored for an account
+var ErrNotFound = errors.New("no stored API key")
+
+// ErrBadPassphrase is returned when an encrypted key file cannot be decrypted
+var ErrBadPassphrase = errors.New("incorrect passphrase for encrypted API key")
+
+// Account returns the keyring account for a config profile, so each profile
+// can have its own key
+func Account(profile string) string {
+	if profile == "" {
+		return DefaultAccount
+	}
+	return profile
+}
+
+// KeyringGet returns the key stored in the OS keyring for account
+func KeyringGet(account string) (string, error) {
+	key, err := keyring.Get(Service, account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	return key, err
+}
+
+// KeyringSet stores key in the OS keyring for account
+func KeyringSet(account, key string) error {
+	return keyring.Set(Service, account, key)
+}
+
+// KeyringDelete removes the key for account from the OS keyring.
+// It returns ErrNotFound if there was none.
+func KeyringDelete(account string) error {
+	err := keyring.Delete(Service, account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// FilePath returns the encrypted key file for account within the config dir
+func FilePath(dir, account string) string {
+	return filepath.Join(dir, "credentials", account+".age")
+}
+
+// SaveFile encrypts key with passphrase and writes it to path (mode 0600)
+func SaveFile(path, key, passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("a passphrase is required to encrypt the API key")
+	}
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	armored := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armored, recipient)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, key); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := armored.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+// LoadFile decrypts the key stored at path. It returns ErrNotFound if the
+// file does not exist and ErrBadPassphrase if the passphrase is wrong.
+func LoadFile(path, passphrase string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	r, err := age.Decrypt(armor.NewReader(bytes.NewReader(data)), identity)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return "", ErrBadPassphrase
+		}
+		return "", fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+	key, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(key)), nil
+}
+
+// DeleteFile removes the encrypted key file at path.
+// It returns ErrNotFound if there was none.
+func DeleteFile(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Mask shortens a key for display, keeping only enough to tell keys apart
+func Mask(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + "..." + key[len(key)-4:]
+}
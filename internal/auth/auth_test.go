@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestKeyring(t *testing.T) {
+	keyring.MockInit()
+
+	if _, err := KeyringGet("work"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := KeyringSet("work", "secret-key"); err != nil {
+		t.Fatal(err)
+	}
+	if key, err := KeyringGet("work"); err != nil || key != "secret-key" {
+		t.Errorf("KeyringGet() = (%q, %v)", key, err)
+	}
+	if err := KeyringDelete("work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := KeyringDelete("work"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound on second delete, got %v", err)
+	}
+}
+
+func TestEncryptedFile(t *testing.T) {
+	path := FilePath(t.TempDir(), DefaultAccount)
+
+	if _, err := LoadFile(path, "pass"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := SaveFile(path, "secret-key", ""); err == nil {
+		t.Error("expected an error for an empty passphrase")
+	}
+	if err := SaveFile(path, "secret-key", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-key") {
+		t.Error("key stored in plaintext")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if key, err := LoadFile(path, "correct horse"); err != nil || key != "secret-key" {
+		t.Errorf("LoadFile() = (%q, %v)", key, err)
+	}
+	if _, err := LoadFile(path, "wrong"); !errors.Is(err, ErrBadPassphrase) {
+		t.Errorf("expected ErrBadPassphrase, got %v", err)
+	}
+
+	if err := DeleteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteFile(filepath.Clean(path)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound on second delete, got %v", err)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	googleKey := "AIza" + strings.Repeat("x", 35)
+	RegisterSecret("custom-secret-value")
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"error calling API with " + googleKey, "error calling API with [REDACTED]"},
+		{"GET https://example.com/v1/models?alt=json&key=abc123 failed", "GET https://example.com/v1/models?alt=json&key=[REDACTED] failed"},
+		{"token custom-secret-value rejected", "token [REDACTED] rejected"},
+		{"nothing to hide", "nothing to hide"},
+	}
+	for _, tt := range tests {
+		if got := Scrub(tt.in); got != tt.want {
+			t.Errorf("Scrub(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	base := errors.New("boom")
+	err := ScrubError(fmt.Errorf("request with %s: %w", googleKey, base))
+	if strings.Contains(err.Error(), googleKey) {
+		t.Errorf("key not scrubbed from error: %v", err)
+	}
+	if !errors.Is(err, base) {
+		t.Error("scrubbed error does not unwrap to the original")
+	}
+}
+
+func TestMask(t *testing.T) {
+	if got := Mask("AIzaSyExampleKey1234"); got != "AIza...1234" {
+		t.Errorf("Mask() = %q", got)
+	}
+	if got := Mask("short"); got != "*****" {
+		t.Errorf("Mask() = %q", got)
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redacted replaces key material in scrubbed output
+const redacted = "[REDACTED]"
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+
+	// Google API keys, and keys passed as URL query parameters
+	keyPattern   = regexp.MustCompile(`AIza[0-9A-Za-z_\-]{35}`)
+	paramPattern = regexp.MustCompile(`([?&](?:key|api_key)=)[^&\s"']+`)
+)
+
+// RegisterSecret records a key so Scrub removes it from output even if it
+// does not look like a Google API key
+func RegisterSecret(secret string) {
+	if len(secret) < 8 {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		if s == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+}
+
+// Scrub removes registered secrets and anything that looks like an API key from s
+func Scrub(s string) string {
+	secretsMu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	secretsMu.RUnlock()
+	s = keyPattern.ReplaceAllString(s, redacted)
+	return paramPattern.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// ScrubError returns err with key material removed from its message.
+// The original error is still available to errors.Is and errors.As.
+func ScrubError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &scrubbedError{err: err}
+}
+
+type scrubbedError struct {
+	err error
+}
+
+func (e *scrubbedError) Error() string { return Scrub(e.err.Error()) }
+func (e *scrubbedError) Unwrap() error { return e.err }
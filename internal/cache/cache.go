@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cache is an on-disk cache of query responses with one JSON file per
+// entry. Entries expire after a TTL, and are discarded early when the
+// fingerprint of the stores they were answered from changes.
+type Cache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// entry is the file stored for each key
+type entry struct {
+	Created     time.Time       `json:"created"`
+	Fingerprint string          `json:"fingerprint"`
+	Hits        int             `json:"hits"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// Dir returns the per-user directory for cached responses,
+// $XDG_CACHE_HOME/file-search/responses on Linux
+func Dir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "file-search", "responses"), nil
+}
+
+// New returns a cache storing entries in dir for ttl
+func New(dir string, ttl time.Duration) *Cache {
+	return &Cache{dir: dir, ttl: ttl, now: time.Now}
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// Get returns the data stored for key if it has not expired and was stored
+// with the same fingerprint. Stale entries are removed.
+func (c *Cache) Get(key, fingerprint string) ([]byte, bool) {
+	path := c.path(key)
+	e, err := readEntry(path)
+	if err != nil {
+		return nil, false
+	}
+	if e.Fingerprint != fingerprint || c.expired(e) {
+		os.Remove(path)
+		return nil, false
+	}
+	e.Hits++
+	c.write(path, e)
+	return e.Data, true
+}
+
+// Put stores data for key. data must be valid JSON.
+func (c *Cache) Put(key, fingerprint string, data []byte) error {
+	return c.write(c.path(key), &entry{Created: c.now(), Fingerprint: fingerprint, Data: data})
+}
+
+func (c *Cache) expired(e *entry) bool {
+	return c.ttl > 0 && c.now().Sub(e.Created) > c.ttl
+}
+
+// write replaces the file at path, via a temporary file so concurrent
+// readers never see a partial entry
+func (c *Cache) write(path string, e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func readEntry(path string) (*entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Stats describes the contents of a cache
+type Stats struct {
+	Dir     string `json:"dir"`
+	Entries int    `json:"entries"`
+	// Expired entries are removed on their next lookup or by Clear
+	Expired int   `json:"expired"`
+	Bytes   int64 `json:"bytes"`
+	// Hits is the number of lookups answered by the current entries
+	Hits   int       `json:"hits"`
+	Oldest time.Time `json:"oldest,omitzero"`
+	Newest time.Time `json:"newest,omitzero"`
+}
+
+// Stats reads every entry in the cache. A missing cache is empty.
+func (c *Cache) Stats() (*Stats, error) {
+	s := &Stats{Dir: c.dir}
+	err := c.walk(func(path string, info os.FileInfo) error {
+		e, err := readEntry(path)
+		if err != nil {
+			return nil
+		}
+		s.Entries++
+		s.Bytes += info.Size()
+		s.Hits += e.Hits
+		if c.expired(e) {
+			s.Expired++
+		}
+		if s.Oldest.IsZero() || e.Created.Before(s.Oldest) {
+			s.Oldest = e.Created
+		}
+		if e.Created.After(s.Newest) {
+			s.Newest = e.Created
+		}
+		return nil
+	})
+	return s, err
+}
+
+// Clear removes every entry, or only expired and unreadable entries when
+// expiredOnly is set, and returns the number removed
+func (c *Cache) Clear(expiredOnly bool) (int, error) {
+	removed := 0
+	err := c.walk(func(path string, info os.FileInfo) error {
+		if expiredOnly {
+			if e, err := readEntry(path); err == nil && !c.expired(e) {
+				return nil
+			}
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// walk calls fn for each entry file in the cache
+func (c *Cache) walk(fn func(path string, info os.FileInfo) error) error {
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, de := range entries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".json") {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		if err := fn(filepath.Join(c.dir, de.Name()), info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
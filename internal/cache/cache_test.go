@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache_GetPut(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "responses")
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := New(dir, time.Hour)
+	c.now = func() time.Time { return now }
+
+	if _, ok := c.Get("k", "fp"); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+	if err := c.Put("k", "fp", []byte(`{"text":"hi"}`)); err != nil {
+		t.Fatal(err)
+	}
+	data, ok := c.Get("k", "fp")
+	if !ok || string(data) != `{"text":"hi"}` {
+		t.Fatalf("Get() = %s, %v", data, ok)
+	}
+
+	// A changed fingerprint misses and drops the entry
+	if _, ok := c.Get("k", "fp2"); ok {
+		t.Error("expected miss for changed fingerprint")
+	}
+	if _, err := os.Stat(c.path("k")); !os.IsNotExist(err) {
+		t.Errorf("expected stale entry to be removed, got %v", err)
+	}
+
+	c.Put("k", "fp", []byte(`{}`))
+	now = now.Add(2 * time.Hour)
+	if _, ok := c.Get("k", "fp"); ok {
+		t.Error("expected miss for expired entry")
+	}
+}
+
+func TestCache_StatsClear(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := New(dir, time.Hour)
+	c.now = func() time.Time { return now }
+
+	c.Put("old", "", []byte(`{}`))
+	now = now.Add(90 * time.Minute)
+	c.Put("new", "", []byte(`{}`))
+	c.Get("new", "")
+	c.Get("new", "")
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o600)
+
+	s, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Entries != 2 || s.Expired != 1 || s.Hits != 2 || s.Bytes == 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if !s.Newest.Equal(now) || !s.Oldest.Equal(now.Add(-90*time.Minute)) {
+		t.Errorf("unexpected oldest/newest %v/%v", s.Oldest, s.Newest)
+	}
+
+	removed, err := c.Clear(true)
+	if err != nil || removed != 2 {
+		t.Errorf("Clear(expired) = %d, %v; want 2 (expired and broken)", removed, err)
+	}
+	if _, ok := c.Get("new", ""); !ok {
+		t.Error("expected live entry to survive Clear(expired)")
+	}
+	removed, err = c.Clear(false)
+	if err != nil || removed != 1 {
+		t.Errorf("Clear() = %d, %v; want 1", removed, err)
+	}
+
+	empty := New(filepath.Join(dir, "missing"), time.Hour)
+	if s, err := empty.Stats(); err != nil || s.Entries != 0 {
+		t.Errorf("missing cache: %+v, %v", s, err)
+	}
+}
@@ -0,0 +1,222 @@
+package chunk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Token is a whitespace-delimited token, identified by its byte offsets in the source text
+type Token struct {
+	Start int
+	End   int
+}
+
+// Tokenize splits text on whitespace, mirroring the tokens used by white-space chunking
+func Tokenize(text string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				tokens = append(tokens, Token{Start: start, End: i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, Token{Start: start, End: len(text)})
+	}
+	return tokens
+}
+
+// Options mirrors the WhiteSpaceConfig used for store uploads
+type Options struct {
+	// MaxTokens is the maximum number of tokens per chunk
+	MaxTokens int
+	// Overlap is the number of tokens shared between consecutive chunks
+	Overlap int
+	// TokensPerWord is the ratio of model tokens to whitespace tokens, as
+	// measured by Calibrate. 0 counts each whitespace token as one token.
+	TokensPerWord float64
+}
+
+// Chunk describes a single chunk of the previewed text
+type Chunk struct {
+	Index     int    `json:"index"`
+	Tokens    int    `json:"tokens"`
+	Words     int    `json:"words"`
+	StartByte int    `json:"startByte"`
+	EndByte   int    `json:"endByte"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Head      string `json:"head"`
+	Tail      string `json:"tail"`
+}
+
+// Stats summarizes the distribution of chunk sizes in tokens
+type Stats struct {
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median int     `json:"median"`
+	P90    int     `json:"p90"`
+}
+
+// Preview is the local approximation of how a document will be chunked
+type Preview struct {
+	Name          string   `json:"name"`
+	MaxTokens     int      `json:"maxTokens"`
+	Overlap       int      `json:"overlap"`
+	TokensPerWord float64  `json:"tokensPerWord"`
+	Calibrated    bool     `json:"calibrated"`
+	TotalWords    int      `json:"totalWords"`
+	TotalTokens   int      `json:"totalTokens"`
+	ChunkCount    int      `json:"chunkCount"`
+	Stats         Stats    `json:"stats"`
+	Chunks        []*Chunk `json:"chunks"`
+}
+
+// excerptLen is the number of characters shown at each chunk boundary
+const excerptLen = 60
+
+// NewPreview chunks text the way white-space chunking would, scaling the
+// token budget by opts.TokensPerWord so that sizes approximate model tokens.
+func NewPreview(name, text string, opts *Options) (*Preview, error) {
+	if opts == nil || opts.MaxTokens <= 0 {
+		return nil, fmt.Errorf("chunk size must be greater than zero")
+	}
+	if opts.Overlap < 0 || opts.Overlap >= opts.MaxTokens {
+		return nil, fmt.Errorf("chunk overlap must be between 0 and the chunk size (%d)", opts.MaxTokens)
+	}
+	ratio := opts.TokensPerWord
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	// Convert the token budget into whitespace tokens
+	wordsPerChunk := max(1, int(float64(opts.MaxTokens)/ratio))
+	overlapWords := int(math.Round(float64(opts.Overlap) / ratio))
+	step := max(1, wordsPerChunk-overlapWords)
+
+	words := Tokenize(text)
+	lines := lineStarts(text)
+	p := &Preview{
+		Name:          name,
+		MaxTokens:     opts.MaxTokens,
+		Overlap:       opts.Overlap,
+		TokensPerWord: ratio,
+		Calibrated:    opts.TokensPerWord > 0,
+		TotalWords:    len(words),
+		TotalTokens:   int(math.Round(float64(len(words)) * ratio)),
+	}
+
+	for start := 0; start < len(words); start += step {
+		end := min(start+wordsPerChunk, len(words))
+		first, last := words[start], words[end-1]
+		c := &Chunk{
+			Index:     len(p.Chunks) + 1,
+			Words:     end - start,
+			Tokens:    int(math.Round(float64(end-start) * ratio)),
+			StartByte: first.Start,
+			EndByte:   last.End,
+			StartLine: lineOf(lines, first.Start),
+			EndLine:   lineOf(lines, last.End-1),
+		}
+		body := text[c.StartByte:c.EndByte]
+		c.Head = excerpt(body, false)
+		c.Tail = excerpt(body, true)
+		p.Chunks = append(p.Chunks, c)
+
+		if end == len(words) {
+			break
+		}
+	}
+
+	p.ChunkCount = len(p.Chunks)
+	p.Stats = stats(p.Chunks)
+	return p, nil
+}
+
+// calibrationSampleBytes caps how much text is sent to the token counter
+const calibrationSampleBytes = 64 << 10
+
+// Calibrate measures the ratio of model tokens to whitespace tokens using a
+// sample from the start of text. count returns the model's token count.
+func Calibrate(ctx context.Context, text string, count func(context.Context, string) (int, error)) (float64, error) {
+	sample := text
+	if len(sample) > calibrationSampleBytes {
+		sample = sample[:calibrationSampleBytes]
+		// Cut at the last whitespace so the final word is not split
+		if i := strings.LastIndexFunc(sample, unicode.IsSpace); i > 0 {
+			sample = sample[:i]
+		}
+	}
+
+	words := len(Tokenize(sample))
+	if words == 0 {
+		return 0, fmt.Errorf("no text to calibrate against")
+	}
+	tokens, err := count(ctx, sample)
+	if err != nil {
+		return 0, err
+	}
+	if tokens <= 0 {
+		return 0, fmt.Errorf("token counter returned %d tokens", tokens)
+	}
+	return float64(tokens) / float64(words), nil
+}
+
+func stats(chunks []*Chunk) Stats {
+	if len(chunks) == 0 {
+		return Stats{}
+	}
+	sizes := make([]int, len(chunks))
+	total := 0
+	for i, c := range chunks {
+		sizes[i] = c.Tokens
+		total += c.Tokens
+	}
+	sort.Ints(sizes)
+	return Stats{
+		Min:    sizes[0],
+		Max:    sizes[len(sizes)-1],
+		Mean:   float64(total) / float64(len(sizes)),
+		Median: sizes[len(sizes)/2],
+		P90:    sizes[(len(sizes)*9)/10],
+	}
+}
+
+// lineStarts returns the byte offset at which each line begins
+func lineStarts(text string) []int {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// lineOf returns the 1-based line number containing the byte offset
+func lineOf(starts []int, offset int) int {
+	return sort.Search(len(starts), func(i int) bool { return starts[i] > offset })
+}
+
+// excerpt returns the first (or last) excerptLen characters of s on one line
+func excerpt(s string, tail bool) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= excerptLen {
+		return s
+	}
+	if tail {
+		return "..." + string(r[len(r)-excerptLen:])
+	}
+	return string(r[:excerptLen]) + "..."
+}
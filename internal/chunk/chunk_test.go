@@ -0,0 +1,110 @@
+package chunk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	text := "  hello\tworld\n\nfoo "
+	tokens := Tokenize(text)
+	if len(tokens) != 3 {
+		t.Fatalf("expected 3 tokens, got %d", len(tokens))
+	}
+	for i, want := range []string{"hello", "world", "foo"} {
+		if got := text[tokens[i].Start:tokens[i].End]; got != want {
+			t.Errorf("token %d = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestNewPreview(t *testing.T) {
+	text := strings.Repeat("word ", 25)
+
+	tests := []struct {
+		name       string
+		opts       *Options
+		wantChunks int
+		wantMax    int
+		wantLast   int
+	}{
+		{"no overlap", &Options{MaxTokens: 10}, 3, 10, 5},
+		{"with overlap", &Options{MaxTokens: 10, Overlap: 5}, 4, 10, 10},
+		{"calibrated", &Options{MaxTokens: 10, TokensPerWord: 2}, 5, 10, 10},
+		{"single chunk", &Options{MaxTokens: 100}, 1, 25, 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPreview("doc.txt", text, tt.opts)
+			if err != nil {
+				t.Fatalf("NewPreview failed: %v", err)
+			}
+			if p.ChunkCount != tt.wantChunks {
+				t.Errorf("expected %d chunks, got %d", tt.wantChunks, p.ChunkCount)
+			}
+			if p.Stats.Max != tt.wantMax {
+				t.Errorf("expected max chunk size %d, got %d", tt.wantMax, p.Stats.Max)
+			}
+			if last := p.Chunks[len(p.Chunks)-1]; last.Tokens != tt.wantLast {
+				t.Errorf("expected last chunk size %d, got %d", tt.wantLast, last.Tokens)
+			}
+		})
+	}
+}
+
+func TestNewPreviewBoundaries(t *testing.T) {
+	text := "alpha beta\ngamma delta\nepsilon"
+
+	p, err := NewPreview("doc.txt", text, &Options{MaxTokens: 2})
+	if err != nil {
+		t.Fatalf("NewPreview failed: %v", err)
+	}
+	if p.ChunkCount != 3 {
+		t.Fatalf("expected 3 chunks, got %d", p.ChunkCount)
+	}
+
+	c := p.Chunks[1]
+	if text[c.StartByte:c.EndByte] != "gamma delta" {
+		t.Errorf("unexpected chunk text %q", text[c.StartByte:c.EndByte])
+	}
+	if c.StartLine != 2 || c.EndLine != 2 {
+		t.Errorf("expected chunk on line 2, got lines %d-%d", c.StartLine, c.EndLine)
+	}
+	if p.Chunks[2].StartLine != 3 {
+		t.Errorf("expected last chunk on line 3, got %d", p.Chunks[2].StartLine)
+	}
+}
+
+func TestNewPreviewInvalidOptions(t *testing.T) {
+	for _, opts := range []*Options{nil, {MaxTokens: 0}, {MaxTokens: 10, Overlap: 10}, {MaxTokens: 10, Overlap: -1}} {
+		if _, err := NewPreview("doc.txt", "text", opts); err == nil {
+			t.Errorf("expected error for options %+v", opts)
+		}
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	count := func(ctx context.Context, s string) (int, error) {
+		return len(Tokenize(s)) * 3 / 2, nil
+	}
+	ratio, err := Calibrate(context.Background(), "one two three four", count)
+	if err != nil {
+		t.Fatalf("Calibrate failed: %v", err)
+	}
+	if ratio != 1.5 {
+		t.Errorf("expected ratio 1.5, got %v", ratio)
+	}
+
+	failing := func(ctx context.Context, s string) (int, error) {
+		return 0, errors.New("quota exceeded")
+	}
+	if _, err := Calibrate(context.Background(), "text", failing); err == nil {
+		t.Error("expected counter error to be returned")
+	}
+	if _, err := Calibrate(context.Background(), "   ", count); err == nil {
+		t.Error("expected error for empty text")
+	}
+}
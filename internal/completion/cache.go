@@ -1,21 +1,29 @@
 package completion
 
 import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
 
 // CacheEntry represents a cached list of completion values with expiration
 type CacheEntry struct {
-	Values    []string
-	ExpiresAt time.Time
+	Values    []string  `json:"values"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// Cache provides thread-safe TTL-based caching for completion values
+// Cache provides thread-safe TTL-based caching for completion values.
+// A cache created with NewFileCache is also saved to a file, so it is
+// shared by the short-lived processes the shell starts for each completion.
 type Cache struct {
 	mu      sync.RWMutex
 	entries map[string]*CacheEntry
 	ttl     time.Duration
+	path    string
 }
 
 // NewCache creates a new Cache with the specified TTL.
@@ -30,6 +38,65 @@ func NewCache(ttl time.Duration) *Cache {
 	}
 }
 
+// NewFileCache creates a Cache with the specified TTL that is loaded from
+// and saved to path. A missing or unreadable file starts an empty cache.
+func NewFileCache(path string, ttl time.Duration) *Cache {
+	c := NewCache(ttl)
+	c.path = path
+	c.entries = c.load()
+	return c
+}
+
+// load reads the unexpired entries saved at the cache's path
+func (c *Cache) load() map[string]*CacheEntry {
+	entries := make(map[string]*CacheEntry)
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return entries
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return make(map[string]*CacheEntry)
+	}
+	now := time.Now()
+	for key, entry := range entries {
+		if entry == nil || now.After(entry.ExpiresAt) {
+			delete(entries, key)
+		}
+	}
+	return entries
+}
+
+// save writes the entries to the cache's path, via a temporary file so
+// concurrent completions never read a partial cache. The caller must hold
+// the write lock.
+func (c *Cache) save() error {
+	if c.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".completion-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
 // Get retrieves cached values for the given key.
 // Returns (values, true) if found and not expired, (nil, false) otherwise.
 func (c *Cache) Get(key string) ([]string, bool) {
@@ -46,13 +113,42 @@ func (c *Cache) Get(key string) ([]string, bool) {
 
 // Set stores values in the cache with the configured TTL
 func (c *Cache) Set(key string, values []string) {
+	c.SetWithTTL(key, values, c.ttl)
+}
+
+// SetWithTTL stores values in the cache for ttl. File caches are reloaded
+// first so entries saved by other processes are kept.
+func (c *Cache) SetWithTTL(key string, values []string, ttl time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if c.path != "" {
+		c.entries = c.load()
+	}
 	c.entries[key] = &CacheEntry{
 		Values:    values,
-		ExpiresAt: time.Now().Add(c.ttl),
+		ExpiresAt: time.Now().Add(ttl),
 	}
+	c.save() // Completion works without a saved cache, just slower
+}
+
+// Delete removes the entries whose keys start with any of the prefixes
+func (c *Cache) Delete(prefixes ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.path != "" {
+		c.entries = c.load()
+	}
+	for key := range c.entries {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				delete(c.entries, key)
+				break
+			}
+		}
+	}
+	c.save()
 }
 
 // Clear removes all entries from the cache
@@ -61,4 +157,9 @@ func (c *Cache) Clear() {
 	defer c.mu.Unlock()
 
 	c.entries = make(map[string]*CacheEntry)
+	if c.path != "" {
+		if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			c.save()
+		}
+	}
 }
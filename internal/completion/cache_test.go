@@ -1,6 +1,8 @@
 package completion
 
 import (
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -375,3 +377,68 @@ func TestCacheExpiration(t *testing.T) {
 		}
 	})
 }
+
+func TestFileCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file-search", "completion.json")
+
+	t.Run("shared across instances", func(t *testing.T) {
+		first := NewFileCache(path, time.Minute)
+		first.Set(KeyStores, []string{"Docs"})
+
+		second := NewFileCache(path, time.Minute)
+		second.Set(KeyFiles, []string{"files/abc"})
+		if values, ok := second.Get(KeyStores); !ok || len(values) != 1 || values[0] != "Docs" {
+			t.Errorf("expected stores saved by another instance, got %v, %v", values, ok)
+		}
+
+		// Set reloads, so the first instance doesn't drop the second's entry
+		first.Set(KeyDocuments+"Docs", []string{"a.pdf"})
+		third := NewFileCache(path, time.Minute)
+		if _, ok := third.Get(KeyFiles); !ok {
+			t.Error("expected files entry to survive a write from another instance")
+		}
+	})
+
+	t.Run("per-key TTL", func(t *testing.T) {
+		c := NewFileCache(path, time.Minute)
+		c.SetWithTTL("short", []string{"x"}, -time.Second)
+		if _, ok := NewFileCache(path, time.Minute).Get("short"); ok {
+			t.Error("expected expired entry to be dropped on load")
+		}
+	})
+
+	t.Run("delete by prefix", func(t *testing.T) {
+		c := NewFileCache(path, time.Minute)
+		c.Set(KeyDocuments+"Other", []string{"b.pdf"})
+		c.Delete(KeyDocuments)
+		reloaded := NewFileCache(path, time.Minute)
+		if _, ok := reloaded.Get(KeyDocuments + "Docs"); ok {
+			t.Error("expected document entries to be deleted")
+		}
+		if _, ok := reloaded.Get(KeyStores); !ok {
+			t.Error("expected stores entry to be kept")
+		}
+	})
+
+	t.Run("clear removes file", func(t *testing.T) {
+		c := NewFileCache(path, time.Minute)
+		c.Clear()
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected cache file to be removed, got %v", err)
+		}
+	})
+
+	t.Run("corrupt file", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		c := NewFileCache(path, time.Minute)
+		if _, ok := c.Get(KeyStores); ok {
+			t.Error("expected empty cache from corrupt file")
+		}
+		c.Set(KeyStores, []string{"Docs"})
+		if _, ok := NewFileCache(path, time.Minute).Get(KeyStores); !ok {
+			t.Error("expected corrupt file to be replaced")
+		}
+	})
+}
@@ -2,28 +2,89 @@ package completion
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/mikesmitty/file-search/internal/constants"
 
 	"github.com/mikesmitty/file-search/internal/gemini"
+	"google.golang.org/genai"
 )
 
+// Cache keys for each kind of completion. Document keys are followed by the
+// store reference they were listed for.
+const (
+	KeyStores = "stores"
+	// KeyStoreIDs starts with KeyStores, so invalidating stores drops both
+	KeyStoreIDs   = "stores:ids"
+	KeyFiles      = "files"
+	KeyDocuments  = "docs:"
+	KeyOperations = "operations"
+)
+
+// Recent operations are kept longer than listings, since they can't be
+// listed again from the API
+const (
+	maxOperations = 20
+	operationTTL  = 7 * 24 * time.Hour
+)
+
+// CachePath returns the completion cache file in cacheDir for the account
+// and backend in opts, so switching keys or projects never completes
+// another account's names
+func CachePath(cacheDir string, opts gemini.ClientOptions) string {
+	h := sha256.Sum256([]byte(strings.Join([]string{strings.ToLower(opts.Backend), opts.APIKey, opts.Project, opts.Location}, "\x00")))
+	return filepath.Join(cacheDir, "file-search", "completion-"+hex.EncodeToString(h[:6])+".json")
+}
+
+// InvalidateFiles drops the entries whose keys start with any of the
+// prefixes from every account's cache file in cacheDir. Finding one
+// account's file needs its API key, which may be behind a passphrase.
+func InvalidateFiles(cacheDir string, prefixes ...string) error {
+	paths, err := filepath.Glob(filepath.Join(cacheDir, "file-search", "completion-*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		NewFileCache(path, 0).Delete(prefixes...)
+	}
+	return nil
+}
+
 // Completer provides completion suggestions for CLI arguments
 type Completer struct {
 	cache      *Cache
 	apiKey     string
+	clientOpts gemini.ClientOptions
 	enabled    bool
 	client     *gemini.Client
 	clientInit bool
+	opsMu      sync.Mutex
 }
 
 // NewCompleter creates a new Completer with the specified configuration
 func NewCompleter(apiKey string, enabled bool, cacheTTL time.Duration) *Completer {
+	return NewCompleterWithOptions(gemini.ClientOptions{APIKey: apiKey}, enabled, cacheTTL)
+}
+
+// NewCompleterWithOptions creates a Completer whose client uses the given backend options
+func NewCompleterWithOptions(opts gemini.ClientOptions, enabled bool, cacheTTL time.Duration) *Completer {
+	return NewCompleterWithCache(opts, enabled, NewCache(cacheTTL))
+}
+
+// NewCompleterWithCache creates a Completer that uses the given cache, e.g.
+// a file cache shared across shell invocations
+func NewCompleterWithCache(opts gemini.ClientOptions, enabled bool, cache *Cache) *Completer {
 	return &Completer{
-		cache:   NewCache(cacheTTL),
-		apiKey:  apiKey,
-		enabled: enabled,
+		cache:      cache,
+		apiKey:     opts.APIKey,
+		clientOpts: opts,
+		enabled:    enabled,
 	}
 }
 
@@ -33,7 +94,7 @@ func (c *Completer) ensureClient(ctx context.Context) (*gemini.Client, error) {
 		return c.client, nil
 	}
 
-	client, err := gemini.NewClient(ctx, c.apiKey, nil)
+	client, err := gemini.NewClientWithOptions(ctx, c.clientOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -50,50 +111,86 @@ func (c *Completer) Close() {
 	}
 }
 
-// GetStoreNames returns a list of store names for completion.
+// GetStoreNames returns the display names of stores that start with
+// toComplete, described by their document count and ID.
 // Returns empty slice if disabled or on error (graceful degradation).
-func (c *Completer) GetStoreNames() []string {
-	if !c.enabled {
-		return []string{}
-	}
+func (c *Completer) GetStoreNames(toComplete string) []string {
+	return c.complete(KeyStores, toComplete, c.fillStores)
+}
 
-	// Check cache first
-	if cached, ok := c.cache.Get("stores"); ok {
-		return cached
-	}
+// GetStoreIDs returns the resource IDs of stores that start with
+// toComplete, described by their display name and document count.
+// Returns empty slice if disabled or on error (graceful degradation).
+func (c *Completer) GetStoreIDs(toComplete string) []string {
+	return c.complete(KeyStoreIDs, toComplete, c.fillStores)
+}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
+// GetFileNames returns the display names of files that start with
+// toComplete, described by their ID.
+// Returns empty slice if disabled or on error (graceful degradation).
+func (c *Completer) GetFileNames(toComplete string) []string {
+	return c.complete(KeyFiles, toComplete, c.fillFiles)
+}
 
-	// Ensure client is initialized
-	client, err := c.ensureClient(ctx)
-	if err != nil {
-		return []string{} // Silent failure
+// GetDocumentNames returns the display names of documents in a store that
+// start with toComplete, described by their state.
+// Returns empty slice if disabled or on error (graceful degradation).
+func (c *Completer) GetDocumentNames(storeRef, toComplete string) []string {
+	if storeRef == "" {
+		return []string{}
 	}
+	return c.complete(KeyDocuments+storeRef, toComplete, func(ctx context.Context, client *gemini.Client) error {
+		storeID, err := client.ResolveStoreName(ctx, storeRef)
+		if err != nil {
+			return err
+		}
+		return c.fillDocuments(ctx, client, storeID, storeRef)
+	})
+}
 
-	// Get store names from API
-	names, err := client.GetStoreNames(ctx)
-	if err != nil {
-		return []string{} // Silent failure
+// GetOperationNames returns the operations recently started by the CLI that
+// start with toComplete, most recent first
+func (c *Completer) GetOperationNames(toComplete string) []string {
+	if !c.enabled {
+		return []string{}
 	}
+	values, _ := c.cache.Get(KeyOperations)
+	return filterPrefix(values, toComplete)
+}
 
-	// Cache the results
-	c.cache.Set("stores", names)
+// AddOperation records an operation for completion, keeping the most recent
+// maxOperations for operationTTL
+func (c *Completer) AddOperation(name string, opType gemini.OperationType, store string) {
+	c.opsMu.Lock()
+	defer c.opsMu.Unlock()
 
-	return names
+	desc := fmt.Sprintf("%s, %s", opType, time.Now().Format(time.DateTime))
+	if store != "" {
+		desc = fmt.Sprintf("%s into %s, %s", opType, store, time.Now().Format(time.DateTime))
+	}
+	values := []string{describe(name, desc)}
+	previous, _ := c.cache.Get(KeyOperations)
+	for _, v := range previous {
+		if len(values) == maxOperations {
+			break
+		}
+		if value, _, _ := strings.Cut(v, "\t"); value != name {
+			values = append(values, v)
+		}
+	}
+	c.cache.SetWithTTL(KeyOperations, values, operationTTL)
 }
 
-// GetFileNames returns a list of file names for completion.
-// Returns empty slice if disabled or on error (graceful degradation).
-func (c *Completer) GetFileNames() []string {
+// complete returns the cached values for key that match toComplete, calling
+// fill to list and cache them on a miss
+func (c *Completer) complete(key, toComplete string, fill func(context.Context, *gemini.Client) error) []string {
 	if !c.enabled {
 		return []string{}
 	}
 
 	// Check cache first
-	if cached, ok := c.cache.Get("files"); ok {
-		return cached
+	if cached, ok := c.cache.Get(key); ok {
+		return filterPrefix(cached, toComplete)
 	}
 
 	// Create context with timeout
@@ -105,60 +202,136 @@ func (c *Completer) GetFileNames() []string {
 	if err != nil {
 		return []string{} // Silent failure
 	}
+	if err := fill(ctx, client); err != nil {
+		return []string{} // Silent failure
+	}
+	cached, _ := c.cache.Get(key)
+	return filterPrefix(cached, toComplete)
+}
 
-	// Get file names from API
-	names, err := client.GetFileNames(ctx)
+// fillStores caches the store names and IDs
+func (c *Completer) fillStores(ctx context.Context, client *gemini.Client) error {
+	_, err := c.listStores(ctx, client)
+	return err
+}
+
+// listStores lists stores and caches their names and IDs
+func (c *Completer) listStores(ctx context.Context, client *gemini.Client) ([]*genai.FileSearchStore, error) {
+	stores, err := client.ListStores(ctx)
 	if err != nil {
-		return []string{} // Silent failure
+		return nil, err
+	}
+	names := make([]string, 0, len(stores))
+	ids := make([]string, 0, len(stores))
+	for _, s := range stores {
+		docs := documentCount(s.ActiveDocumentsCount + s.PendingDocumentsCount + s.FailedDocumentsCount)
+		if s.DisplayName != "" {
+			names = append(names, describe(s.DisplayName, docs+", "+s.Name))
+			ids = append(ids, describe(s.Name, s.DisplayName+" ("+docs+")"))
+		} else {
+			ids = append(ids, describe(s.Name, docs))
+		}
 	}
+	c.cache.Set(KeyStores, names)
+	c.cache.Set(KeyStoreIDs, ids)
+	return stores, nil
+}
 
-	// Cache the results
-	c.cache.Set("files", names)
+// fillFiles caches the file names
+func (c *Completer) fillFiles(ctx context.Context, client *gemini.Client) error {
+	files, err := client.ListFiles(ctx)
+	if err != nil {
+		return err
+	}
+	values := make([]string, 0, len(files))
+	for _, f := range files {
+		values = append(values, describe(f.DisplayName, f.Name))
+	}
+	c.cache.Set(KeyFiles, values)
+	return nil
+}
 
-	return names
+// fillDocuments caches the documents of a store under each reference
+func (c *Completer) fillDocuments(ctx context.Context, client *gemini.Client, storeID string, refs ...string) error {
+	docs, err := client.ListDocuments(ctx, storeID)
+	if err != nil {
+		return err
+	}
+	values := make([]string, 0, len(docs))
+	for _, d := range docs {
+		values = append(values, describe(d.DisplayName, strings.ToLower(strings.TrimPrefix(string(d.State), "STATE_"))))
+	}
+	for _, ref := range refs {
+		if ref != "" {
+			c.cache.Set(KeyDocuments+ref, values)
+		}
+	}
+	return nil
 }
 
-// GetDocumentNames returns a list of document names for completion within a store.
-// Returns empty slice if disabled or on error (graceful degradation).
-func (c *Completer) GetDocumentNames(storeRef string) []string {
-	if !c.enabled || storeRef == "" {
-		return []string{}
+func documentCount(n int64) string {
+	if n == 1 {
+		return "1 doc"
 	}
+	return fmt.Sprintf("%d docs", n)
+}
 
-	// Cache key includes store reference
-	cacheKey := "docs:" + storeRef
+// describe returns a Cobra completion with a description. Tabs and line
+// breaks in either part would corrupt the completion protocol.
+func describe(value, description string) string {
+	value = strings.NewReplacer("\t", " ", "\n", " ").Replace(value)
+	if description == "" {
+		return value
+	}
+	return value + "\t" + strings.NewReplacer("\t", " ", "\n", " ").Replace(description)
+}
 
-	// Check cache first
-	if cached, ok := c.cache.Get(cacheKey); ok {
-		return cached
+// filterPrefix returns the completions whose value starts with toComplete,
+// ignoring case. Names with spaces are returned unquoted; the shell scripts
+// escape them. toComplete may still carry an opening quote or backslash
+// escapes when the user started typing a quoted name.
+func filterPrefix(values []string, toComplete string) []string {
+	prefix := strings.TrimLeft(toComplete, `"'`)
+	prefix = strings.ToLower(strings.ReplaceAll(prefix, `\ `, " "))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		value, _, _ := strings.Cut(v, "\t")
+		if strings.HasPrefix(strings.ToLower(value), prefix) {
+			result = append(result, v)
+		}
 	}
+	return result
+}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
+// Invalidate drops cached completions whose keys start with any of the
+// prefixes, e.g. KeyStores after a store is created
+func (c *Completer) Invalidate(prefixes ...string) {
+	c.cache.Delete(prefixes...)
+}
 
-	// Ensure client is initialized
+// Refresh lists stores, files and the documents of every store and caches
+// them, replacing any cached values. Unlike completion lookups it is not
+// limited to a short timeout.
+func (c *Completer) Refresh(ctx context.Context) error {
 	client, err := c.ensureClient(ctx)
 	if err != nil {
-		return []string{} // Silent failure
+		return err
 	}
 
-	// Resolve store name to ID
-	storeID, err := client.ResolveStoreName(ctx, storeRef)
+	// Drop documents of stores that no longer exist
+	c.cache.Delete(KeyDocuments)
+	stores, err := c.listStores(ctx, client)
 	if err != nil {
-		return []string{} // Silent failure
+		return err
 	}
-
-	// Get document names from API
-	names, err := client.GetDocumentNames(ctx, storeID)
-	if err != nil {
-		return []string{} // Silent failure
+	for _, store := range stores {
+		// Completion looks documents up by whichever store reference was typed
+		if err := c.fillDocuments(ctx, client, store.Name, store.Name, store.DisplayName); err != nil {
+			return err
+		}
 	}
 
-	// Cache the results
-	c.cache.Set(cacheKey, names)
-
-	return names
+	return c.fillFiles(ctx, client)
 }
 
 // GetModelNames returns a list of available model names
@@ -1,8 +1,15 @@
 package completion
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
 )
 
 func TestNewCompleter(t *testing.T) {
@@ -155,19 +162,19 @@ func TestCompleterDisabledBehavior(t *testing.T) {
 		{
 			name: "GetStoreNames",
 			method: func(c *Completer) []string {
-				return c.GetStoreNames()
+				return c.GetStoreNames("")
 			},
 		},
 		{
 			name: "GetFileNames",
 			method: func(c *Completer) []string {
-				return c.GetFileNames()
+				return c.GetFileNames("")
 			},
 		},
 		{
 			name: "GetDocumentNames",
 			method: func(c *Completer) []string {
-				return c.GetDocumentNames("test-store")
+				return c.GetDocumentNames("test-store", "")
 			},
 		},
 	}
@@ -192,7 +199,7 @@ func TestCompleterDisabledBehavior(t *testing.T) {
 func TestCompleterGetDocumentNamesEmptyStore(t *testing.T) {
 	t.Run("returns empty when storeRef is empty", func(t *testing.T) {
 		completer := NewCompleter("test-key", true, 5*time.Minute)
-		result := completer.GetDocumentNames("")
+		result := completer.GetDocumentNames("", "")
 
 		if len(result) != 0 {
 			t.Errorf("Expected empty slice for empty storeRef, got %v", result)
@@ -201,7 +208,7 @@ func TestCompleterGetDocumentNamesEmptyStore(t *testing.T) {
 
 	t.Run("returns empty when disabled and empty storeRef", func(t *testing.T) {
 		completer := NewCompleter("test-key", false, 5*time.Minute)
-		result := completer.GetDocumentNames("")
+		result := completer.GetDocumentNames("", "")
 
 		if len(result) != 0 {
 			t.Errorf("Expected empty slice when disabled with empty storeRef, got %v", result)
@@ -326,3 +333,126 @@ func TestCompleterCacheBehavior(t *testing.T) {
 // - Cache interaction
 // - Static methods (GetModelNames)
 // - Error conditions we can trigger
+
+func TestCachePath(t *testing.T) {
+	a := CachePath("/cache", gemini.ClientOptions{APIKey: "key-a"})
+	b := CachePath("/cache", gemini.ClientOptions{APIKey: "key-b"})
+	if a == b {
+		t.Error("expected different cache files for different keys")
+	}
+	if strings.Contains(a, "key-a") {
+		t.Errorf("cache path must not contain the API key: %s", a)
+	}
+	if filepath.Dir(a) != filepath.Join("/cache", "file-search") {
+		t.Errorf("unexpected cache dir %s", a)
+	}
+}
+
+func TestInvalidateFiles(t *testing.T) {
+	dir := t.TempDir()
+	var caches []*Cache
+	for _, key := range []string{"key-a", "key-b"} {
+		c := NewFileCache(CachePath(dir, gemini.ClientOptions{APIKey: key}), time.Hour)
+		c.Set(KeyStores, []string{"Docs"})
+		c.Set(KeyFiles, []string{"a.pdf"})
+		caches = append(caches, c)
+	}
+
+	// No credentials are needed to find the files
+	if err := InvalidateFiles(dir, KeyStores); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range caches {
+		reloaded := NewFileCache(c.path, time.Hour)
+		if _, ok := reloaded.Get(KeyStores); ok {
+			t.Errorf("cache %d: stores not invalidated", i)
+		}
+		if _, ok := reloaded.Get(KeyFiles); !ok {
+			t.Errorf("cache %d: files invalidated too", i)
+		}
+	}
+}
+
+func TestFilterPrefix(t *testing.T) {
+	values := []string{"My Docs\t3 docs, fileSearchStores/a", "my-notes\t1 doc, fileSearchStores/b", "Other"}
+	tests := []struct {
+		toComplete string
+		want       int
+	}{
+		{"", 3},
+		{"my", 2},
+		{"My D", 1},
+		{`"My D`, 1},
+		{`My\ D`, 1},
+		{"x", 0},
+	}
+	for _, tt := range tests {
+		if got := filterPrefix(values, tt.toComplete); len(got) != tt.want {
+			t.Errorf("filterPrefix(%q) = %v, want %d values", tt.toComplete, got, tt.want)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	if got := describe("a\tb", "line\nbreak"); got != "a b\tline break" {
+		t.Errorf("describe() = %q", got)
+	}
+	if got := describe("name", ""); got != "name" {
+		t.Errorf("describe() without description = %q", got)
+	}
+}
+
+func TestCompleterStores(t *testing.T) {
+	transport := &listTransport{body: `{"fileSearchStores":[
+		{"name":"fileSearchStores/abc","displayName":"My Docs","activeDocumentsCount":"2","pendingDocumentsCount":"1"},
+		{"name":"fileSearchStores/xyz","displayName":"Specs","activeDocumentsCount":"1"}]}`}
+	completer := NewCompleterWithOptions(gemini.ClientOptions{APIKey: "k", HTTPClient: &http.Client{Transport: transport}}, true, time.Minute)
+
+	names := completer.GetStoreNames("my")
+	if len(names) != 1 || names[0] != "My Docs\t3 docs, fileSearchStores/abc" {
+		t.Errorf("GetStoreNames() = %q", names)
+	}
+	ids := completer.GetStoreIDs("fileSearchStores/x")
+	if len(ids) != 1 || ids[0] != "fileSearchStores/xyz\tSpecs (1 doc)" {
+		t.Errorf("GetStoreIDs() = %q", ids)
+	}
+	if transport.requests != 1 {
+		t.Errorf("expected names and IDs from one listing, got %d requests", transport.requests)
+	}
+}
+
+func TestCompleterOperations(t *testing.T) {
+	completer := NewCompleter("test-key", true, time.Minute)
+	for i := range maxOperations + 5 {
+		completer.AddOperation(fmt.Sprintf("fileSearchStores/s/operations/op%d", i), gemini.OperationTypeImport, "")
+	}
+	completer.AddOperation("fileSearchStores/s/operations/op7", gemini.OperationTypeUpload, "fileSearchStores/s")
+
+	ops := completer.GetOperationNames("")
+	if len(ops) != maxOperations {
+		t.Fatalf("expected %d operations, got %d", maxOperations, len(ops))
+	}
+	if !strings.HasPrefix(ops[0], "fileSearchStores/s/operations/op7\tupload into fileSearchStores/s") {
+		t.Errorf("expected most recent operation first, got %q", ops[0])
+	}
+	// op2 itself was dropped as one of the oldest
+	if got := completer.GetOperationNames("fileSearchStores/s/operations/op2"); len(got) != 5 {
+		t.Errorf("expected op20-op24, got %q", got)
+	}
+}
+
+// listTransport answers every request with body and counts requests
+type listTransport struct {
+	body     string
+	requests int
+}
+
+func (l *listTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l.requests++
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(l.body)),
+		Request:    req,
+	}, nil
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// FileName is the name of the per-user (legacy) and per-project config file
+const FileName = ".file-search.yaml"
+
+// GlobalConfigPath returns the user-level config file. An existing
+// $HOME/.file-search.yaml takes precedence for compatibility; otherwise the
+// XDG location ($XDG_CONFIG_HOME/file-search/config.yaml) is used.
+func GlobalConfigPath(home string) string {
+	legacy := filepath.Join(home, FileName)
+	if fileExists(legacy) {
+		return legacy
+	}
+
+	return filepath.Join(Dir(home), "config.yaml")
+}
+
+// Dir returns the per-user directory for config and credential files,
+// $XDG_CONFIG_HOME/file-search (default ~/.config/file-search).
+func Dir(home string) string {
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" {
+		xdg = filepath.Join(home, ".config")
+	}
+	return filepath.Join(xdg, "file-search")
+}
+
+// FindProjectConfig searches dir and its parents for a .file-search.yaml,
+// stopping before home so the user-level file is not treated as a project file.
+// Returns an empty string if none is found.
+func FindProjectConfig(dir, home string) string {
+	dir = filepath.Clean(dir)
+	home = filepath.Clean(home)
+	for {
+		if dir == home {
+			return ""
+		}
+		candidate := filepath.Join(dir, FileName)
+		if fileExists(candidate) {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// SearchPaths returns the existing config files to load, lowest precedence
+// first: the user-level file, then the nearest project file.
+func SearchPaths(cwd, home string) []string {
+	var paths []string
+	if global := GlobalConfigPath(home); fileExists(global) {
+		paths = append(paths, global)
+	}
+	if project := FindProjectConfig(cwd, home); project != "" {
+		paths = append(paths, project)
+	}
+	return paths
+}
+
+// StoreMarkerFile names a file whose first non-empty line is the default
+// store (display name or resource ID) for the directory tree it is in
+const StoreMarkerFile = ".file-search-store"
+
+// FindStoreMarker searches dir and its parents for a .file-search-store marker.
+// It returns the store named in the nearest marker and the marker's path, or
+// empty strings if there is none.
+func FindStoreMarker(dir string) (string, string, error) {
+	dir = filepath.Clean(dir)
+	for {
+		path := filepath.Join(dir, StoreMarkerFile)
+		if fileExists(path) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", "", err
+			}
+			for _, line := range strings.Split(string(data), "\n") {
+				line = strings.TrimSpace(line)
+				if line != "" && !strings.HasPrefix(line, "#") {
+					return line, path, nil
+				}
+			}
+			return "", "", fmt.Errorf("%s does not name a store", path)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", nil
+		}
+		dir = parent
+	}
+}
+
+// SetValue sets a dotted key (e.g. "profiles.work.default_model") in a YAML
+// config file, creating the file and any intermediate maps as needed.
+// Comments and key order in the existing file are preserved.
+func SetValue(path, key, raw string) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	var value yaml.Node
+	if err := yaml.Unmarshal([]byte(raw), &value); err != nil || len(value.Content) == 0 {
+		// Not valid YAML on its own (or empty): store as a plain string
+		value = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: raw}
+	} else {
+		value = *value.Content[0]
+	}
+
+	node := doc.Content[0]
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("cannot set %s: %s is not a map", key, strings.Join(parts[:i], "."))
+		}
+		child := mappingValue(node, part)
+		if i == len(parts)-1 {
+			if child != nil {
+				*child = value
+			} else {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: part}, &value)
+			}
+			break
+		}
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: part}, child)
+		}
+		node = child
+	}
+
+	out, err := MarshalYAML(&doc)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0o600)
+}
+
+// SetIn returns the highest-precedence file of paths (lowest precedence
+// first, as from SearchPaths) that sets one of the keys, trying keys in
+// order. Each key is a path of map keys, e.g. {"profiles", "prod.eu",
+// "default_model"}, so keys may contain dots. It returns an empty string if
+// no file sets any of them.
+func SetIn(paths []string, keys ...[]string) string {
+	for _, key := range keys {
+		for i := len(paths) - 1; i >= 0; i-- {
+			if fileSets(paths[i], key) {
+				return paths[i]
+			}
+		}
+	}
+	return ""
+}
+
+func fileSets(path string, key []string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return false
+	}
+	node := doc.Content[0]
+	for _, part := range key {
+		if node.Kind != yaml.MappingNode {
+			return false
+		}
+		if node = mappingValue(node, part); node == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalYAML encodes v as YAML with the two-space indentation used in config files
+func MarshalYAML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SecretKey is the config key whose values are never shown, at any depth
+// (e.g. profiles.work.api_key)
+const SecretKey = "api_key"
+
+// IsSecret reports whether a dotted config key path names a secret
+func IsSecret(key string) bool {
+	parts := strings.Split(key, ".")
+	return strings.EqualFold(parts[len(parts)-1], SecretKey)
+}
+
+// Redact returns a copy of v with every non-empty secret in its maps
+// replaced by "(set)". v itself, which may be viper's own map, is unchanged.
+func Redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			if s, ok := val.(string); ok && s != "" && strings.EqualFold(k, SecretKey) {
+				out[k] = "(set)"
+			} else {
+				out[k] = Redact(val)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = Redact(val)
+		}
+		return out
+	}
+	return v
+}
+
+// mappingValue returns the value node for key in a mapping node, matching
+// keys case-insensitively as viper does
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindProjectConfig(t *testing.T) {
+	home := t.TempDir()
+	project := filepath.Join(home, "work", "project")
+	nested := filepath.Join(project, "docs", "specs")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(home, FileName), []byte("{}"), 0o600)
+
+	if got := FindProjectConfig(nested, home); got != "" {
+		t.Errorf("expected user config in home not to count as a project config, got %s", got)
+	}
+
+	want := filepath.Join(project, FileName)
+	os.WriteFile(want, []byte("{}"), 0o600)
+	if got := FindProjectConfig(nested, home); got != want {
+		t.Errorf("FindProjectConfig() = %q, want %q", got, want)
+	}
+}
+
+func TestFindStoreMarker(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if store, _, err := FindStoreMarker(nested); err != nil || store != "" {
+		t.Errorf("expected no marker, got %q (%v)", store, err)
+	}
+
+	marker := filepath.Join(root, "a", StoreMarkerFile)
+	os.WriteFile(marker, []byte("# docs for this repo\n\n  Team Docs  \nignored\n"), 0o600)
+	store, path, err := FindStoreMarker(nested)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store != "Team Docs" || path != marker {
+		t.Errorf("FindStoreMarker() = (%q, %q), want (%q, %q)", store, path, "Team Docs", marker)
+	}
+
+	os.WriteFile(marker, []byte("# nothing here\n"), 0o600)
+	if _, _, err := FindStoreMarker(nested); err == nil {
+		t.Error("expected an error for a marker without a store")
+	}
+}
+
+func TestGlobalConfigPath(t *testing.T) {
+	home := t.TempDir()
+	xdg := filepath.Join(home, "xdg")
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	if got, want := GlobalConfigPath(home), filepath.Join(xdg, "file-search", "config.yaml"); got != want {
+		t.Errorf("expected XDG path %q, got %q", want, got)
+	}
+
+	legacy := filepath.Join(home, FileName)
+	os.WriteFile(legacy, []byte("{}"), 0o600)
+	if got := GlobalConfigPath(home); got != legacy {
+		t.Errorf("expected existing legacy config %q to take precedence, got %q", legacy, got)
+	}
+}
+
+func TestSearchPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", "")
+	project := filepath.Join(home, "project")
+	os.MkdirAll(project, 0o755)
+	os.WriteFile(filepath.Join(home, FileName), []byte("{}"), 0o600)
+	os.WriteFile(filepath.Join(project, FileName), []byte("{}"), 0o600)
+
+	paths := SearchPaths(project, home)
+	if len(paths) != 2 || paths[0] != filepath.Join(home, FileName) || paths[1] != filepath.Join(project, FileName) {
+		t.Errorf("expected user config then project config, got %v", paths)
+	}
+}
+
+func TestSetIn(t *testing.T) {
+	dir := t.TempDir()
+	user := filepath.Join(dir, "user.yaml")
+	project := filepath.Join(dir, "project.yaml")
+	os.WriteFile(user, []byte("query:\n  system_file: a.txt\nprofiles:\n  prod.eu:\n    query:\n      system_file: b.txt\n"), 0o600)
+	os.WriteFile(project, []byte("Query:\n  System_File: c.txt\n"), 0o600)
+	paths := []string{user, project}
+
+	if got := SetIn(paths, []string{"query", "system_file"}); got != project {
+		t.Errorf("expected the project file to win, got %q", got)
+	}
+	if got := SetIn(paths, []string{"profiles", "prod.eu", "query", "system_file"}, []string{"query", "system_file"}); got != user {
+		t.Errorf("expected the earlier key to win, got %q", got)
+	}
+	if got := SetIn(paths, []string{"query", "temperature"}); got != "" {
+		t.Errorf("expected no file for an unset key, got %q", got)
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "config.yaml")
+
+	// Creates the file and intermediate maps
+	if err := SetValue(path, "profiles.work.default_model", "gemini-2.5-pro"); err != nil {
+		t.Fatalf("SetValue failed: %v", err)
+	}
+	if err := SetValue(path, "profiles.work.mcp_tools", "[query_knowledge_base, list_stores]"); err != nil {
+		t.Fatalf("SetValue failed: %v", err)
+	}
+	if err := SetValue(path, "completion_enabled", "false"); err != nil {
+		t.Fatalf("SetValue failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	got := string(data)
+	for _, want := range []string{
+		"profiles:\n  work:\n    default_model: gemini-2.5-pro\n",
+		"mcp_tools: [query_knowledge_base, list_stores]",
+		"completion_enabled: false",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected config to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestSetValuePreservesComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("# API settings\nDefault_Model: old # inline\nother: 1\n"), 0o600)
+
+	if err := SetValue(path, "default_model", "new"); err != nil {
+		t.Fatalf("SetValue failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	got := string(data)
+	if !strings.Contains(got, "# API settings") || !strings.Contains(got, "Default_Model: new") || !strings.Contains(got, "other: 1") {
+		t.Errorf("expected existing key updated in place with comments kept, got:\n%s", got)
+	}
+}
+
+func TestSetValueNotAMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("default_model: x\n"), 0o600)
+
+	if err := SetValue(path, "default_model.nested", "y"); err == nil {
+		t.Error("expected error when setting a key below a scalar")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	settings := map[string]any{
+		"api_key":       "top-secret",
+		"default_model": "gemini-2.5-flash",
+		"profiles": map[string]any{
+			"work": map[string]any{"api_key": "work-secret", "api_key_env": "WORK_KEY"},
+			"home": map[string]any{"API_KEY": ""},
+		},
+	}
+	got := Redact(settings).(map[string]any)
+	if got["api_key"] != "(set)" || got["default_model"] != "gemini-2.5-flash" {
+		t.Errorf("top level: %v", got)
+	}
+	work := got["profiles"].(map[string]any)["work"].(map[string]any)
+	if work["api_key"] != "(set)" || work["api_key_env"] != "WORK_KEY" {
+		t.Errorf("nested profile: %v", work)
+	}
+	if home := got["profiles"].(map[string]any)["home"].(map[string]any); home["API_KEY"] != "" {
+		t.Errorf("empty key should stay empty: %v", home)
+	}
+	if settings["profiles"].(map[string]any)["work"].(map[string]any)["api_key"] != "work-secret" {
+		t.Error("Redact modified its input")
+	}
+
+	for key, want := range map[string]bool{"api_key": true, "profiles.work.api_key": true, "Profiles.Work.API_KEY": true, "api_key_env": false, "profiles.work": false} {
+		if got := IsSecret(key); got != want {
+			t.Errorf("IsSecret(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mikesmitty/file-search/internal/constants"
+)
+
+// StoreProfile holds default upload settings for a store, configured under
+// the "stores" key of .file-search.yaml. Explicit flags and tool arguments
+// take precedence over these defaults.
+type StoreProfile struct {
+	// ChunkSize is the default max tokens per chunk (0 uses the API default)
+	ChunkSize int `mapstructure:"chunk_size" json:"chunkSize,omitempty"`
+	// ChunkOverlap is the default overlap tokens between chunks
+	ChunkOverlap int `mapstructure:"chunk_overlap" json:"chunkOverlap,omitempty"`
+	// Metadata is merged into every upload; explicit keys win
+	Metadata map[string]string `mapstructure:"metadata" json:"metadata,omitempty"`
+	// MIMETypes maps file extensions (e.g. ".log") to the MIME type to upload with
+	MIMETypes map[string]string `mapstructure:"mime_types" json:"mimeTypes,omitempty"`
+	// Include limits uploads to files matching at least one glob pattern
+	Include []string `mapstructure:"include" json:"include,omitempty"`
+	// Exclude skips files matching any glob pattern
+	Exclude []string `mapstructure:"exclude" json:"exclude,omitempty"`
+	// OnDuplicate is the default duplicate policy: skip, replace, keep or error
+	OnDuplicate string `mapstructure:"on_duplicate" json:"onDuplicate,omitempty"`
+	// RecordSource records each upload's absolute local path as source_path
+	// metadata, so store doctor --retry can upload failed documents again
+	RecordSource bool `mapstructure:"record_source" json:"recordSource,omitempty"`
+}
+
+// StoreProfiles maps a store display name or resource ID to its profile.
+// Names containing dots must be written into the config file directly (or
+// replaced by the resource ID), since config set splits keys on dots.
+type StoreProfiles map[string]*StoreProfile
+
+// Lookup returns the first profile matching one of keys (store display names
+// or resource IDs) and the config key it was found under. Matching is
+// case-insensitive because config keys are lowercased when read, and IDs
+// match with or without the "fileSearchStores/" prefix.
+func (s StoreProfiles) Lookup(keys ...string) (string, *StoreProfile) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		candidates := []string{key}
+		if id, ok := strings.CutPrefix(key, constants.StoreResourcePrefix); ok {
+			candidates = append(candidates, id)
+		} else {
+			candidates = append(candidates, constants.StoreResourcePrefix+key)
+		}
+		for name, profile := range s {
+			for _, c := range candidates {
+				if strings.EqualFold(name, c) && profile != nil {
+					return name, profile
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
+// Allows reports whether a local file passes the include/exclude patterns.
+// Patterns containing a slash match the whole path, others match the base name.
+func (p *StoreProfile) Allows(filePath string) bool {
+	if p == nil {
+		return true
+	}
+	if len(p.Include) > 0 && !matchAny(p.Include, filePath) {
+		return false
+	}
+	return !matchAny(p.Exclude, filePath)
+}
+
+// MIMETypeFor returns the configured MIME type for the file's extension, if any
+func (p *StoreProfile) MIMETypeFor(filePath string) string {
+	if p == nil {
+		return ""
+	}
+	ext := strings.ToLower(filepath.Ext(filePath))
+	for k, v := range p.MIMETypes {
+		if strings.EqualFold(k, ext) || strings.EqualFold("."+k, ext) {
+			return v
+		}
+	}
+	return ""
+}
+
+// MergeMetadata returns the profile metadata overlaid with explicit values
+func (p *StoreProfile) MergeMetadata(explicit map[string]string) map[string]string {
+	if p == nil || len(p.Metadata) == 0 {
+		return explicit
+	}
+	merged := make(map[string]string, len(p.Metadata)+len(explicit))
+	for k, v := range p.Metadata {
+		merged[k] = v
+	}
+	for k, v := range explicit {
+		merged[k] = v
+	}
+	return merged
+}
+
+func matchAny(patterns []string, filePath string) bool {
+	slashPath := filepath.ToSlash(filePath)
+	base := path.Base(slashPath)
+	for _, pattern := range patterns {
+		target := base
+		if strings.Contains(pattern, "/") {
+			target = slashPath
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveStoreConfig describes the settings applied to uploads into a store
+type EffectiveStoreConfig struct {
+	Store       string        `json:"store"`
+	DisplayName string        `json:"displayName"`
+	ProfileKey  string        `json:"profileKey,omitempty"`
+	Settings    *StoreProfile `json:"settings"`
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStoreProfilesLookup(t *testing.T) {
+	datasheets := &StoreProfile{ChunkSize: 512}
+	archive := &StoreProfile{ChunkSize: 256}
+	profiles := StoreProfiles{
+		"datasheets":  datasheets, // keys are lowercased by viper
+		"archive-123": archive,    // ID without prefix
+		"empty":       nil,
+	}
+
+	tests := []struct {
+		name    string
+		keys    []string
+		wantKey string
+		want    *StoreProfile
+	}{
+		{"display name, case-insensitive", []string{"Datasheets"}, "datasheets", datasheets},
+		{"ID with prefix", []string{"", "fileSearchStores/archive-123"}, "archive-123", archive},
+		{"first matching key wins", []string{"Datasheets", "fileSearchStores/archive-123"}, "datasheets", datasheets},
+		{"nil profile ignored", []string{"empty"}, "", nil},
+		{"no match", []string{"Other", "fileSearchStores/xyz"}, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, got := profiles.Lookup(tt.keys...)
+			if key != tt.wantKey || got != tt.want {
+				t.Errorf("Lookup(%v) = %q, %v; want %q, %v", tt.keys, key, got, tt.wantKey, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreProfileAllows(t *testing.T) {
+	p := &StoreProfile{
+		Include: []string{"*.pdf", "*.md"},
+		Exclude: []string{"draft-*", "archive/*"},
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"specs/board.pdf", true},
+		{"README.md", true},
+		{"notes.txt", false},
+		{"specs/draft-board.pdf", false},
+		{"archive/old.pdf", false},
+	}
+
+	for _, tt := range tests {
+		if got := p.Allows(tt.path); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	var none *StoreProfile
+	if !none.Allows("anything") {
+		t.Error("nil profile should allow all files")
+	}
+}
+
+func TestStoreProfileMIMETypeFor(t *testing.T) {
+	p := &StoreProfile{MIMETypes: map[string]string{".log": "text/plain", "cfg": "text/plain"}}
+
+	if got := p.MIMETypeFor("/var/app.LOG"); got != "text/plain" {
+		t.Errorf("expected extension match to be case-insensitive, got %q", got)
+	}
+	if got := p.MIMETypeFor("app.cfg"); got != "text/plain" {
+		t.Errorf("expected extension without dot to match, got %q", got)
+	}
+	if got := p.MIMETypeFor("app.pdf"); got != "" {
+		t.Errorf("expected no override, got %q", got)
+	}
+}
+
+func TestStoreProfileMergeMetadata(t *testing.T) {
+	p := &StoreProfile{Metadata: map[string]string{"team": "hw", "kind": "datasheet"}}
+
+	got := p.MergeMetadata(map[string]string{"team": "fw"})
+	want := map[string]string{"team": "fw", "kind": "datasheet"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeMetadata() = %v, want %v", got, want)
+	}
+
+	var none *StoreProfile
+	explicit := map[string]string{"a": "b"}
+	if got := none.MergeMetadata(explicit); !reflect.DeepEqual(got, explicit) {
+		t.Errorf("nil profile should return explicit metadata, got %v", got)
+	}
+}
@@ -9,6 +9,10 @@ const (
 	FileResourcePrefix      = "files/"
 	DocumentResourcePrefix  = "/documents/"
 	OperationResourcePrefix = "/operations/"
+
+	// Upload size limits
+	MaxStoreUploadBytes = 100 << 20 // per-document limit for File Search stores
+	MaxFileUploadBytes  = 2 << 30   // per-file limit for the Files API
 )
 
 // GetModelList returns the list of models known to support file search
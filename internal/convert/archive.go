@@ -0,0 +1,157 @@
+package convert
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxArchiveMemberSize guards against decompression bombs (100 MB per member)
+const maxArchiveMemberSize = 100 << 20
+
+// ArchiveConverter unpacks .zip, .tar and .tar.gz archives so that each
+// member is uploaded as its own document.
+type ArchiveConverter struct{}
+
+func (a *ArchiveConverter) Name() string { return "archive" }
+
+func (a *ArchiveConverter) Convert(ctx context.Context, src *Artifact, outDir string) ([]*Artifact, error) {
+	lower := strings.ToLower(src.Path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return a.convertZip(ctx, src, outDir)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		f, err := os.Open(src.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return a.convertTar(ctx, src, tar.NewReader(gz), outDir)
+	case strings.HasSuffix(lower, ".tar"):
+		f, err := os.Open(src.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return a.convertTar(ctx, src, tar.NewReader(f), outDir)
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %s", filepath.Base(src.Path))
+	}
+}
+
+func (a *ArchiveConverter) convertZip(ctx context.Context, src *Artifact, outDir string) ([]*Artifact, error) {
+	zr, err := zip.OpenReader(src.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var artifacts []*Artifact
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if f.FileInfo().IsDir() || skipMember(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		artifact, err := extractMember(src, f.Name, rc, outDir)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, nil
+}
+
+func (a *ArchiveConverter) convertTar(ctx context.Context, src *Artifact, tr *tar.Reader, outDir string) ([]*Artifact, error) {
+	var artifacts []*Artifact
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || skipMember(hdr.Name) {
+			continue
+		}
+		artifact, err := extractMember(src, hdr.Name, tr, outDir)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, nil
+}
+
+// skipMember filters out OS metadata and hidden files commonly found in archives
+func skipMember(name string) bool {
+	name = path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+// extractMember writes an archive member below outDir, rejecting paths that
+// would escape it.
+func extractMember(src *Artifact, name string, r io.Reader, outDir string) (*Artifact, error) {
+	clean := path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))[1:]
+	if clean == "" || strings.HasPrefix(clean, "../") {
+		return nil, fmt.Errorf("invalid archive member path: %s", name)
+	}
+
+	dest := filepath.Join(outDir, filepath.FromSlash(clean))
+	if !strings.HasPrefix(dest, filepath.Clean(outDir)+string(os.PathSeparator)) {
+		return nil, fmt.Errorf("invalid archive member path: %s", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return nil, err
+	}
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	n, err := io.Copy(out, io.LimitReader(r, maxArchiveMemberSize+1))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n > maxArchiveMemberSize {
+		return nil, fmt.Errorf("archive member %s exceeds %d bytes", name, maxArchiveMemberSize)
+	}
+
+	return &Artifact{
+		Path:        dest,
+		DisplayName: src.DisplayName + "/" + clean,
+		Provenance: map[string]string{
+			MetaSourceMember: clean,
+		},
+	}, nil
+}
@@ -0,0 +1,249 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Provenance metadata keys attached to converted artifacts
+const (
+	MetaSourceName   = "source_name"
+	MetaSourceMember = "source_member"
+	MetaConverter    = "converter"
+)
+
+// NoneConverter is the converter name used in config to disable conversion for an extension
+const NoneConverter = "none"
+
+// maxDepth limits recursive conversion (e.g. an archive inside an archive)
+const maxDepth = 3
+
+// Artifact is a local file produced by a converter, ready to be uploaded.
+type Artifact struct {
+	Path        string            `json:"path"`
+	DisplayName string            `json:"displayName"`
+	MIMEType    string            `json:"mimeType,omitempty"`
+	Provenance  map[string]string `json:"provenance,omitempty"`
+}
+
+// Converter turns a single local file into one or more upload artifacts.
+// Artifacts must be written below outDir, which is removed by the caller.
+type Converter interface {
+	Name() string
+	Convert(ctx context.Context, src *Artifact, outDir string) ([]*Artifact, error)
+}
+
+// Result holds the artifacts produced for a single input path.
+// Cleanup must be called once the artifacts have been uploaded.
+type Result struct {
+	Artifacts []*Artifact
+	tempDir   string
+}
+
+// Cleanup removes any temporary files created during conversion
+func (r *Result) Cleanup() {
+	if r.tempDir != "" {
+		os.RemoveAll(r.tempDir)
+	}
+}
+
+// Registry maps file extensions to converters
+type Registry struct {
+	converters map[string]Converter
+	extensions map[string]string
+}
+
+// NewRegistry creates a Registry with the built-in converters and default extension mappings
+func NewRegistry() *Registry {
+	r := &Registry{
+		converters: make(map[string]Converter),
+		extensions: make(map[string]string),
+	}
+	r.Register(&HTMLConverter{})
+	r.Register(&ArchiveConverter{})
+	r.Register(&NotebookConverter{})
+	r.Register(&EPUBConverter{})
+
+	for ext, name := range DefaultExtensions() {
+		r.extensions[ext] = name
+	}
+	return r
+}
+
+// DefaultExtensions returns the built-in extension to converter mapping
+func DefaultExtensions() map[string]string {
+	return map[string]string{
+		".html":   "html",
+		".htm":    "html",
+		".xhtml":  "html",
+		".zip":    "archive",
+		".tar.gz": "archive",
+		".tgz":    "archive",
+		".tar":    "archive",
+		".ipynb":  "notebook",
+		".epub":   "epub",
+	}
+}
+
+// Register adds or replaces a converter, keyed by its name
+func (r *Registry) Register(c Converter) {
+	r.converters[c.Name()] = c
+}
+
+// SetExtension maps an extension (e.g. ".html") to a registered converter name.
+// Use NoneConverter to disable conversion for that extension.
+func (r *Registry) SetExtension(ext, name string) error {
+	ext = normalizeExt(ext)
+	if name == NoneConverter || name == "" {
+		delete(r.extensions, ext)
+		return nil
+	}
+	if _, ok := r.converters[name]; !ok {
+		return fmt.Errorf("unknown converter %q for extension %s", name, ext)
+	}
+	r.extensions[ext] = name
+	return nil
+}
+
+// Extensions returns a copy of the current extension mapping
+func (r *Registry) Extensions() map[string]string {
+	out := make(map[string]string, len(r.extensions))
+	for k, v := range r.extensions {
+		out[k] = v
+	}
+	return out
+}
+
+// Lookup returns the converter for the given path, or nil if none applies.
+// Multi-part extensions such as ".tar.gz" take precedence over ".gz".
+func (r *Registry) Lookup(path string) Converter {
+	base := strings.ToLower(filepath.Base(path))
+
+	// Check longest extensions first
+	exts := make([]string, 0, len(r.extensions))
+	for ext := range r.extensions {
+		exts = append(exts, ext)
+	}
+	sort.Slice(exts, func(i, j int) bool { return len(exts[i]) > len(exts[j]) })
+
+	for _, ext := range exts {
+		if strings.HasSuffix(base, ext) {
+			return r.converters[r.extensions[ext]]
+		}
+	}
+	return nil
+}
+
+// Convert runs the path through the matching converters, recursing into the
+// produced artifacts (e.g. HTML files unpacked from a zip archive).
+// Files without a matching converter are returned unchanged as a single artifact.
+func (r *Registry) Convert(ctx context.Context, path, displayName string) (*Result, error) {
+	if displayName == "" {
+		displayName = filepath.Base(path)
+	}
+	src := &Artifact{Path: path, DisplayName: displayName}
+
+	if r.Lookup(path) == nil {
+		return &Result{Artifacts: []*Artifact{src}}, nil
+	}
+
+	tempDir, err := os.MkdirTemp("", "file-search-convert-")
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{tempDir: tempDir}
+
+	artifacts, err := r.convert(ctx, src, tempDir, 0)
+	if err != nil {
+		result.Cleanup()
+		return nil, err
+	}
+	result.Artifacts = artifacts
+	return result, nil
+}
+
+func (r *Registry) convert(ctx context.Context, src *Artifact, tempDir string, depth int) ([]*Artifact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c := r.Lookup(src.Path)
+	if c == nil || depth >= maxDepth {
+		return []*Artifact{src}, nil
+	}
+
+	outDir, err := os.MkdirTemp(tempDir, c.Name()+"-")
+	if err != nil {
+		return nil, err
+	}
+
+	converted, err := c.Convert(ctx, src, outDir)
+	if err != nil {
+		return nil, fmt.Errorf("%s converter failed for %s: %w", c.Name(), src.DisplayName, err)
+	}
+
+	var artifacts []*Artifact
+	for _, a := range converted {
+		a.Provenance = mergeProvenance(src, a, c.Name())
+		nested, err := r.convert(ctx, a, tempDir, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, nested...)
+	}
+	return artifacts, nil
+}
+
+// mergeProvenance combines the provenance of the source with that of the
+// converted artifact, chaining converter names (e.g. "archive+html").
+func mergeProvenance(src, out *Artifact, converter string) map[string]string {
+	prov := make(map[string]string)
+	for k, v := range src.Provenance {
+		prov[k] = v
+	}
+	for k, v := range out.Provenance {
+		prov[k] = v
+	}
+	if _, ok := src.Provenance[MetaSourceName]; !ok {
+		prov[MetaSourceName] = src.DisplayName
+	}
+	if prev, ok := src.Provenance[MetaConverter]; ok {
+		prov[MetaConverter] = prev + "+" + converter
+	} else {
+		prov[MetaConverter] = converter
+	}
+	return prov
+}
+
+// WithProvenance merges an artifact's provenance into user metadata.
+// User-supplied keys take precedence.
+func WithProvenance(metadata, provenance map[string]string) map[string]string {
+	if len(provenance) == 0 {
+		return metadata
+	}
+	merged := make(map[string]string, len(metadata)+len(provenance))
+	for k, v := range provenance {
+		merged[k] = v
+	}
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	return merged
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// replaceExt swaps the extension of name for newExt
+func replaceExt(name, newExt string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name)) + newExt
+}
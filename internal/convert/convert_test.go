@@ -0,0 +1,309 @@
+package convert
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return p
+}
+
+func writeZip(t *testing.T, dir, name string, members map[string]string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatalf("failed to create zip: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	for n, content := range members {
+		w, err := zw.Create(n)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", n, err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	f.Close()
+	return p
+}
+
+func TestRegistryLookup(t *testing.T) {
+	r := NewRegistry()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"page.html", "html"},
+		{"PAGE.HTM", "html"},
+		{"bundle.tar.gz", "archive"},
+		{"bundle.tgz", "archive"},
+		{"notes.ipynb", "notebook"},
+		{"book.epub", "epub"},
+		{"doc.pdf", ""},
+		{"readme.md", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			c := r.Lookup(tt.path)
+			got := ""
+			if c != nil {
+				got = c.Name()
+			}
+			if got != tt.want {
+				t.Errorf("Lookup(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistrySetExtension(t *testing.T) {
+	r := NewRegistry()
+
+	if err := r.SetExtension(".html", NoneConverter); err != nil {
+		t.Fatalf("SetExtension(none) failed: %v", err)
+	}
+	if c := r.Lookup("page.html"); c != nil {
+		t.Errorf("expected html conversion to be disabled, got %s", c.Name())
+	}
+
+	if err := r.SetExtension("xml", "html"); err != nil {
+		t.Fatalf("SetExtension without dot failed: %v", err)
+	}
+	if c := r.Lookup("feed.xml"); c == nil || c.Name() != "html" {
+		t.Errorf("expected .xml to map to html converter")
+	}
+
+	if err := r.SetExtension(".foo", "missing"); err == nil {
+		t.Error("expected error for unknown converter")
+	}
+}
+
+func TestConvertPassthrough(t *testing.T) {
+	dir := t.TempDir()
+	p := writeFile(t, dir, "doc.pdf", "%PDF-1.4")
+
+	res, err := NewRegistry().Convert(context.Background(), p, "")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	defer res.Cleanup()
+
+	if len(res.Artifacts) != 1 {
+		t.Fatalf("expected 1 artifact, got %d", len(res.Artifacts))
+	}
+	a := res.Artifacts[0]
+	if a.Path != p || a.DisplayName != "doc.pdf" {
+		t.Errorf("unexpected passthrough artifact: %+v", a)
+	}
+	if len(a.Provenance) != 0 {
+		t.Errorf("expected no provenance for passthrough, got %v", a.Provenance)
+	}
+}
+
+func TestConvertHTMLProvenance(t *testing.T) {
+	dir := t.TempDir()
+	p := writeFile(t, dir, "page.html", "<html><body><h1>Title</h1><p>Body</p></body></html>")
+
+	res, err := NewRegistry().Convert(context.Background(), p, "Page")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if len(res.Artifacts) != 1 {
+		t.Fatalf("expected 1 artifact, got %d", len(res.Artifacts))
+	}
+	a := res.Artifacts[0]
+	if a.MIMEType != "text/markdown" {
+		t.Errorf("expected text/markdown, got %s", a.MIMEType)
+	}
+	if a.DisplayName != "Page" {
+		t.Errorf("expected display name to be preserved, got %s", a.DisplayName)
+	}
+	if a.Provenance[MetaConverter] != "html" || a.Provenance[MetaSourceName] != "Page" {
+		t.Errorf("unexpected provenance: %v", a.Provenance)
+	}
+
+	content, err := os.ReadFile(a.Path)
+	if err != nil {
+		t.Fatalf("failed to read artifact: %v", err)
+	}
+	if !strings.Contains(string(content), "# Title") {
+		t.Errorf("expected markdown heading, got %q", content)
+	}
+
+	res.Cleanup()
+	if _, err := os.Stat(a.Path); !os.IsNotExist(err) {
+		t.Error("expected Cleanup to remove converted artifacts")
+	}
+}
+
+func TestConvertZipRecursive(t *testing.T) {
+	dir := t.TempDir()
+	p := writeZip(t, dir, "site.zip", map[string]string{
+		"index.html":         "<p>Hello</p>",
+		"docs/guide.txt":     "plain text",
+		"__MACOSX/._ignored": "junk",
+		".hidden":            "junk",
+	})
+
+	res, err := NewRegistry().Convert(context.Background(), p, "")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	defer res.Cleanup()
+
+	if len(res.Artifacts) != 2 {
+		t.Fatalf("expected 2 artifacts, got %d", len(res.Artifacts))
+	}
+
+	byName := make(map[string]*Artifact)
+	for _, a := range res.Artifacts {
+		byName[a.DisplayName] = a
+	}
+
+	html, ok := byName["site.zip/index.html"]
+	if !ok {
+		t.Fatalf("missing converted html member, got %v", byName)
+	}
+	if html.Provenance[MetaConverter] != "archive+html" {
+		t.Errorf("expected chained converter provenance, got %s", html.Provenance[MetaConverter])
+	}
+	if html.Provenance[MetaSourceName] != "site.zip" || html.Provenance[MetaSourceMember] != "index.html" {
+		t.Errorf("unexpected provenance: %v", html.Provenance)
+	}
+
+	txt, ok := byName["site.zip/docs/guide.txt"]
+	if !ok {
+		t.Fatalf("missing text member, got %v", byName)
+	}
+	if txt.Provenance[MetaConverter] != "archive" {
+		t.Errorf("expected archive provenance, got %s", txt.Provenance[MetaConverter])
+	}
+}
+
+func TestConvertTarGz(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "bundle.tar.gz")
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	content := []byte("# Notes")
+	tw.WriteHeader(&tar.Header{Name: "notes.md", Mode: 0o600, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	tw.Write(content)
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	res, err := NewRegistry().Convert(context.Background(), p, "")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	defer res.Cleanup()
+
+	if len(res.Artifacts) != 1 || res.Artifacts[0].DisplayName != "bundle.tar.gz/notes.md" {
+		t.Fatalf("unexpected artifacts: %+v", res.Artifacts)
+	}
+}
+
+func TestExtractMemberRejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	src := &Artifact{DisplayName: "evil.zip"}
+
+	a, err := extractMember(src, "../../etc/passwd", strings.NewReader("x"), dir)
+	if err != nil {
+		t.Fatalf("expected traversal to be contained, got error: %v", err)
+	}
+	if !strings.HasPrefix(a.Path, dir) {
+		t.Errorf("member escaped output dir: %s", a.Path)
+	}
+}
+
+func TestNotebookToMarkdown(t *testing.T) {
+	nb := `{
+		"metadata": {"language_info": {"name": "python"}},
+		"cells": [
+			{"cell_type": "markdown", "source": ["# Analysis\n", "Some notes"]},
+			{"cell_type": "code", "source": "print(1)", "outputs": [
+				{"output_type": "stream", "text": ["1\n"]},
+				{"output_type": "display_data", "data": {"image/png": "AAAA", "text/plain": ["<Figure>"]}}
+			]},
+			{"cell_type": "code", "source": "", "outputs": []}
+		]
+	}`
+
+	md, err := NotebookToMarkdown([]byte(nb))
+	if err != nil {
+		t.Fatalf("NotebookToMarkdown failed: %v", err)
+	}
+
+	for _, want := range []string{"# Analysis\nSome notes", "```python\nprint(1)\n```", "Output:", "<Figure>"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "AAAA") {
+		t.Error("expected binary outputs to be dropped")
+	}
+}
+
+func TestNotebookOutputTruncation(t *testing.T) {
+	// "é" is two bytes, so a byte cut would land inside a character
+	long := strings.Repeat("é", maxNotebookOutputChars+1)
+	text := notebookOutputText([]notebookOutput{{OutputType: "stream", Text: multilineString(long)}})
+	if !utf8.ValidString(text) {
+		t.Fatal("expected truncated output to be valid UTF-8")
+	}
+	if want := strings.Repeat("é", maxNotebookOutputChars) + "\n..."; text != want {
+		t.Errorf("expected %d characters and an ellipsis, got %d characters", maxNotebookOutputChars, utf8.RuneCountInString(text))
+	}
+}
+
+func TestConvertEPUB(t *testing.T) {
+	dir := t.TempDir()
+	p := writeZip(t, dir, "book.epub", map[string]string{
+		"mimetype":               "application/epub+zip",
+		"META-INF/container.xml": `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`,
+		"OEBPS/content.opf": `<package><metadata><title>My Book</title></metadata>
+			<manifest><item id="c1" href="ch1.xhtml"/><item id="c2" href="ch2.xhtml"/></manifest>
+			<spine><itemref idref="c2"/><itemref idref="c1"/></spine></package>`,
+		"OEBPS/ch1.xhtml": "<html><body><p>Chapter one</p></body></html>",
+		"OEBPS/ch2.xhtml": "<html><body><p>Chapter two</p></body></html>",
+	})
+
+	res, err := NewRegistry().Convert(context.Background(), p, "")
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	defer res.Cleanup()
+
+	if len(res.Artifacts) != 1 {
+		t.Fatalf("expected 1 artifact, got %d", len(res.Artifacts))
+	}
+	content, _ := os.ReadFile(res.Artifacts[0].Path)
+	text := string(content)
+	if !strings.HasPrefix(text, "# My Book") {
+		t.Errorf("expected book title heading, got %q", text)
+	}
+	if strings.Index(text, "Chapter two") > strings.Index(text, "Chapter one") {
+		t.Errorf("expected spine order to be followed, got %q", text)
+	}
+}
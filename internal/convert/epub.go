@@ -0,0 +1,120 @@
+package convert
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// EPUBConverter converts an EPUB book into a single Markdown document,
+// following the reading order declared in the package spine.
+type EPUBConverter struct{}
+
+func (e *EPUBConverter) Name() string { return "epub" }
+
+type epubContainer struct {
+	RootFiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Title    string `xml:"metadata>title"`
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+func (e *EPUBConverter) Convert(ctx context.Context, src *Artifact, outDir string) ([]*Artifact, error) {
+	zr, err := zip.OpenReader(src.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var container epubContainer
+	if err := decodeZipXML(files, "META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+	if len(container.RootFiles) == 0 {
+		return nil, fmt.Errorf("EPUB container has no rootfile")
+	}
+
+	opfPath := container.RootFiles[0].FullPath
+	var pkg epubPackage
+	if err := decodeZipXML(files, opfPath, &pkg); err != nil {
+		return nil, err
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		hrefs[item.ID] = path.Join(path.Dir(opfPath), item.Href)
+	}
+
+	var sb strings.Builder
+	if pkg.Title != "" {
+		sb.WriteString("# " + strings.TrimSpace(pkg.Title) + "\n\n")
+	}
+	for _, ref := range pkg.Spine {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		f, ok := files[hrefs[ref.IDRef]]
+		if !ok {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		md, err := HTMLToMarkdown(io.LimitReader(rc, maxArchiveMemberSize))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(md + "\n")
+	}
+
+	outPath := filepath.Join(outDir, replaceExt(filepath.Base(src.Path), ".md"))
+	if err := os.WriteFile(outPath, []byte(cleanMarkdown(sb.String())), 0o600); err != nil {
+		return nil, err
+	}
+
+	return []*Artifact{{
+		Path:        outPath,
+		DisplayName: src.DisplayName,
+		MIMEType:    "text/markdown",
+	}}, nil
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("EPUB is missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLConverter converts HTML documents to Markdown, dropping navigation,
+// scripts and other page boilerplate that indexes poorly.
+type HTMLConverter struct{}
+
+func (h *HTMLConverter) Name() string { return "html" }
+
+func (h *HTMLConverter) Convert(ctx context.Context, src *Artifact, outDir string) ([]*Artifact, error) {
+	f, err := os.Open(src.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	md, err := HTMLToMarkdown(f)
+	if err != nil {
+		return nil, err
+	}
+
+	outPath := filepath.Join(outDir, replaceExt(filepath.Base(src.Path), ".md"))
+	if err := os.WriteFile(outPath, []byte(md), 0o600); err != nil {
+		return nil, err
+	}
+
+	return []*Artifact{{
+		Path:        outPath,
+		DisplayName: src.DisplayName,
+		MIMEType:    "text/markdown",
+	}}, nil
+}
+
+// boilerplateElements are skipped entirely when converting HTML
+var boilerplateElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Iframe:   true,
+	atom.Svg:      true,
+	atom.Button:   true,
+	atom.Template: true,
+	atom.Head:     true,
+}
+
+var blankLinesRe = regexp.MustCompile(`\n{3,}`)
+
+// HTMLToMarkdown renders an HTML document as Markdown
+func HTMLToMarkdown(r io.Reader) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	// Prefer <main> or <article> content when present
+	root := findElement(doc, atom.Main)
+	if root == nil {
+		root = findElement(doc, atom.Article)
+	}
+	if root == nil {
+		root = doc
+	}
+
+	w := &mdWriter{}
+	w.walk(root)
+	return cleanMarkdown(w.sb.String()), nil
+}
+
+// cleanMarkdown trims trailing whitespace and collapses runs of blank lines
+func cleanMarkdown(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	s = strings.Join(lines, "\n")
+	s = blankLinesRe.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s) + "\n"
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// mdWriter accumulates Markdown output while walking the HTML tree
+type mdWriter struct {
+	sb        strings.Builder
+	listStack []atom.Atom
+	listIndex []int
+	inPre     bool
+	quote     int
+}
+
+func (w *mdWriter) write(s string) {
+	if w.quote > 0 {
+		s = strings.ReplaceAll(s, "\n", "\n"+strings.Repeat("> ", w.quote))
+	}
+	w.sb.WriteString(s)
+}
+
+func (w *mdWriter) block() {
+	w.write("\n\n")
+}
+
+func (w *mdWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+}
+
+func (w *mdWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		if w.inPre {
+			w.write(n.Data)
+			return
+		}
+		text := strings.Join(strings.Fields(n.Data), " ")
+		if text == "" {
+			if strings.TrimSpace(n.Data) == "" && n.Data != "" {
+				w.write(" ")
+			}
+			return
+		}
+		// Preserve a separating space on either side of inline text
+		if strings.TrimLeft(n.Data, " \t\r\n") != n.Data {
+			text = " " + text
+		}
+		if strings.TrimRight(n.Data, " \t\r\n") != n.Data {
+			text += " "
+		}
+		w.write(text)
+		return
+	case html.DocumentNode:
+		w.children(n)
+		return
+	case html.ElementNode:
+		// handled below
+	default:
+		return
+	}
+
+	if boilerplateElements[n.DataAtom] {
+		return
+	}
+	if hasAttr(n, "hidden") || getAttr(n, "aria-hidden") == "true" {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		w.block()
+		w.write(strings.Repeat("#", level) + " " + strings.TrimSpace(inlineText(n)))
+		w.block()
+	case atom.P, atom.Div, atom.Section, atom.Main, atom.Article, atom.Figure, atom.Dl:
+		w.block()
+		w.children(n)
+		w.block()
+	case atom.Br:
+		w.write("\n")
+	case atom.Hr:
+		w.block()
+		w.write("---")
+		w.block()
+	case atom.Strong, atom.B:
+		w.wrapInline(n, "**")
+	case atom.Em, atom.I:
+		w.wrapInline(n, "*")
+	case atom.Code:
+		if w.inPre {
+			w.children(n)
+		} else {
+			w.wrapInline(n, "`")
+		}
+	case atom.Pre:
+		w.block()
+		w.write("```\n")
+		w.inPre = true
+		w.children(n)
+		w.inPre = false
+		w.write("\n```")
+		w.block()
+	case atom.A:
+		text := strings.TrimSpace(inlineText(n))
+		href := getAttr(n, "href")
+		if text == "" {
+			return
+		}
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
+			w.write(text)
+		} else {
+			w.write(fmt.Sprintf("[%s](%s)", text, href))
+		}
+	case atom.Img:
+		if alt := strings.TrimSpace(getAttr(n, "alt")); alt != "" {
+			w.write(fmt.Sprintf("![%s](%s)", alt, getAttr(n, "src")))
+		}
+	case atom.Ul, atom.Ol:
+		w.listStack = append(w.listStack, n.DataAtom)
+		w.listIndex = append(w.listIndex, 0)
+		w.write("\n")
+		w.children(n)
+		w.listStack = w.listStack[:len(w.listStack)-1]
+		w.listIndex = w.listIndex[:len(w.listIndex)-1]
+		if len(w.listStack) == 0 {
+			w.block()
+		}
+	case atom.Li:
+		depth := len(w.listStack)
+		marker := "- "
+		if depth > 0 && w.listStack[depth-1] == atom.Ol {
+			w.listIndex[depth-1]++
+			marker = fmt.Sprintf("%d. ", w.listIndex[depth-1])
+		}
+		indent := ""
+		if depth > 1 {
+			indent = strings.Repeat("  ", depth-1)
+		}
+		w.write("\n" + indent + marker + strings.TrimSpace(inlineText(n)))
+		// Render nested lists after the item text
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.DataAtom == atom.Ul || c.DataAtom == atom.Ol) {
+				w.walk(c)
+			}
+		}
+	case atom.Dt:
+		w.write("\n**" + strings.TrimSpace(inlineText(n)) + "**")
+	case atom.Dd:
+		w.write("\n: " + strings.TrimSpace(inlineText(n)))
+	case atom.Blockquote:
+		w.block()
+		w.quote++
+		w.write("> ")
+		w.children(n)
+		w.quote--
+		w.block()
+	case atom.Table:
+		w.block()
+		w.table(n)
+		w.block()
+	default:
+		w.children(n)
+	}
+}
+
+func (w *mdWriter) wrapInline(n *html.Node, marker string) {
+	text := strings.TrimSpace(inlineText(n))
+	if text == "" {
+		return
+	}
+	w.write(marker + text + marker)
+}
+
+// table renders a table as a Markdown pipe table, using the first row as header
+func (w *mdWriter) table(n *html.Node) {
+	var rows [][]string
+	var collect func(*html.Node)
+	collect = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.DataAtom == atom.Tr {
+			var cells []string
+			for c := node.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.DataAtom == atom.Td || c.DataAtom == atom.Th) {
+					cell := strings.TrimSpace(inlineText(c))
+					cells = append(cells, strings.ReplaceAll(cell, "|", "\\|"))
+				}
+			}
+			rows = append(rows, cells)
+			return
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	if len(rows) == 0 {
+		return
+	}
+
+	cols := 0
+	for _, row := range rows {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		w.write("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			w.write("|" + strings.Repeat(" --- |", cols) + "\n")
+		}
+	}
+}
+
+// inlineText renders the inline content of a node, ignoring nested lists
+func inlineText(n *html.Node) string {
+	w := &mdWriter{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (c.DataAtom == atom.Ul || c.DataAtom == atom.Ol) {
+			continue
+		}
+		w.walk(c)
+	}
+	return strings.Join(strings.Fields(w.sb.String()), " ")
+}
@@ -0,0 +1,87 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "headings and paragraphs",
+			input:    "<h1>Title</h1><p>First <b>bold</b> and <em>em</em>.</p><h2>Sub</h2>",
+			contains: []string{"# Title", "First **bold** and *em*.", "## Sub"},
+		},
+		{
+			name:     "strips boilerplate",
+			input:    "<nav>Menu</nav><header>Site</header><script>var x;</script><style>p{}</style><p>Content</p><footer>Copyright</footer>",
+			contains: []string{"Content"},
+			excludes: []string{"Menu", "Site", "var x", "p{}", "Copyright"},
+		},
+		{
+			name:     "prefers main element",
+			input:    "<div>Sidebar junk</div><main><p>Main body</p></main>",
+			contains: []string{"Main body"},
+			excludes: []string{"Sidebar junk"},
+		},
+		{
+			name:     "links",
+			input:    `<p><a href="https://example.com">Example</a> <a href="#top">Top</a></p>`,
+			contains: []string{"[Example](https://example.com)", "Top"},
+			excludes: []string{"(#top)"},
+		},
+		{
+			name:     "lists",
+			input:    "<ul><li>One</li><li>Two<ul><li>Nested</li></ul></li></ul><ol><li>First</li><li>Second</li></ol>",
+			contains: []string{"- One", "- Two", "  - Nested", "1. First", "2. Second"},
+		},
+		{
+			name:     "preformatted code",
+			input:    "<pre><code>func main() {\n\treturn\n}</code></pre>",
+			contains: []string{"```\nfunc main() {\n\treturn\n}\n```"},
+		},
+		{
+			name:     "tables",
+			input:    "<table><tr><th>Pin</th><th>Voltage</th></tr><tr><td>VCC</td><td>3.3V</td></tr></table>",
+			contains: []string{"| Pin | Voltage |", "| --- | --- |", "| VCC | 3.3V |"},
+		},
+		{
+			name:     "hidden content",
+			input:    `<p>Visible</p><div hidden>Secret</div><span aria-hidden="true">Icon</span>`,
+			contains: []string{"Visible"},
+			excludes: []string{"Secret", "Icon"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTMLToMarkdown(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("HTMLToMarkdown failed: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, got)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("expected output not to contain %q, got:\n%s", unwanted, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanMarkdown(t *testing.T) {
+	got := cleanMarkdown("\n\nline one   \n\n\n\n\nline two\t\n\n")
+	want := "line one\n\nline two\n"
+	if got != want {
+		t.Errorf("cleanMarkdown() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxNotebookOutputChars caps how much of each cell's text output is kept
+const maxNotebookOutputChars = 2000
+
+// NotebookConverter flattens Jupyter notebooks into Markdown. Markdown cells
+// are kept as-is, code cells become fenced blocks and text outputs are kept,
+// while rich outputs (images, widgets) are dropped.
+type NotebookConverter struct{}
+
+func (n *NotebookConverter) Name() string { return "notebook" }
+
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+type notebookCell struct {
+	CellType string           `json:"cell_type"`
+	Source   multilineString  `json:"source"`
+	Outputs  []notebookOutput `json:"outputs"`
+}
+
+type notebookOutput struct {
+	OutputType string                     `json:"output_type"`
+	Text       multilineString            `json:"text"`
+	Data       map[string]multilineString `json:"data"`
+}
+
+// multilineString accepts the nbformat convention of a string or list of strings
+type multilineString string
+
+func (m *multilineString) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*m = multilineString(s)
+		return nil
+	}
+	var parts []string
+	if err := json.Unmarshal(b, &parts); err != nil {
+		// Non-text payloads (e.g. JSON widget state) are ignored
+		*m = ""
+		return nil
+	}
+	*m = multilineString(strings.Join(parts, ""))
+	return nil
+}
+
+func (n *NotebookConverter) Convert(ctx context.Context, src *Artifact, outDir string) ([]*Artifact, error) {
+	data, err := os.ReadFile(src.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	md, err := NotebookToMarkdown(data)
+	if err != nil {
+		return nil, err
+	}
+
+	outPath := filepath.Join(outDir, replaceExt(filepath.Base(src.Path), ".md"))
+	if err := os.WriteFile(outPath, []byte(md), 0o600); err != nil {
+		return nil, err
+	}
+
+	return []*Artifact{{
+		Path:        outPath,
+		DisplayName: src.DisplayName,
+		MIMEType:    "text/markdown",
+	}}, nil
+}
+
+// NotebookToMarkdown renders an .ipynb document as Markdown
+func NotebookToMarkdown(data []byte) (string, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return "", fmt.Errorf("failed to parse notebook: %w", err)
+	}
+
+	lang := nb.Metadata.LanguageInfo.Name
+	if lang == "" {
+		lang = nb.Metadata.KernelSpec.Language
+	}
+
+	var sb strings.Builder
+	for _, cell := range nb.Cells {
+		source := strings.TrimSpace(string(cell.Source))
+		switch cell.CellType {
+		case "markdown":
+			if source != "" {
+				sb.WriteString(source + "\n\n")
+			}
+		case "code":
+			if source != "" {
+				sb.WriteString("```" + lang + "\n" + source + "\n```\n\n")
+			}
+			if out := notebookOutputText(cell.Outputs); out != "" {
+				sb.WriteString("Output:\n\n```\n" + out + "\n```\n\n")
+			}
+		case "raw":
+			if source != "" {
+				sb.WriteString(source + "\n\n")
+			}
+		}
+	}
+	return cleanMarkdown(sb.String()), nil
+}
+
+func notebookOutputText(outputs []notebookOutput) string {
+	var parts []string
+	for _, out := range outputs {
+		switch out.OutputType {
+		case "stream":
+			parts = append(parts, string(out.Text))
+		case "execute_result", "display_data":
+			if text, ok := out.Data["text/plain"]; ok {
+				parts = append(parts, string(text))
+			}
+		}
+	}
+	text := strings.TrimSpace(strings.Join(parts, "\n"))
+	// Cut on a character boundary so the output stays valid UTF-8
+	if utf8.RuneCountInString(text) > maxNotebookOutputChars {
+		text = string([]rune(text)[:maxNotebookOutputChars]) + "\n..."
+	}
+	return text
+}
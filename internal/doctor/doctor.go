@@ -0,0 +1,259 @@
+// Package doctor audits a File Search store for failed and stuck documents,
+// duplicates, missing metadata, size outliers and unimported files that are
+// about to expire.
+package doctor
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"google.golang.org/genai"
+)
+
+// Defaults for Options
+const (
+	DefaultPendingAfter  = time.Hour
+	DefaultExpiryWindow  = 12 * time.Hour
+	DefaultOutlierFactor = 10
+)
+
+// minOutlierDocs is the fewest sized documents for a median to say anything
+// about outliers. Empty documents are flagged regardless.
+const minOutlierDocs = 5
+
+// Options configures the checks
+type Options struct {
+	// Now is the time pending and expiry ages are measured from
+	Now time.Time
+	// PendingAfter is how long a document may stay pending before it is flagged
+	PendingAfter time.Duration
+	// ExpiryWindow flags files that expire within it and aren't in the store
+	ExpiryWindow time.Duration
+	// ExpectedMetadata lists the metadata keys every document should have
+	ExpectedMetadata []string
+	// OutlierFactor flags documents this many times larger than the median
+	OutlierFactor float64
+}
+
+// DocumentIssue is a document flagged by a check
+type DocumentIssue struct {
+	Name        string            `json:"name"`
+	DisplayName string            `json:"display_name"`
+	State       string            `json:"state"`
+	SizeBytes   int64             `json:"size_bytes"`
+	CreateTime  time.Time         `json:"create_time,omitzero"`
+	UpdateTime  time.Time         `json:"update_time,omitzero"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	// Detail explains the issue, e.g. the missing keys
+	Detail string `json:"detail,omitempty"`
+}
+
+// FileIssue is a Files API file that expires soon and was never imported
+type FileIssue struct {
+	Name           string    `json:"name"`
+	DisplayName    string    `json:"display_name"`
+	SizeBytes      int64     `json:"size_bytes"`
+	ExpirationTime time.Time `json:"expiration_time"`
+	ExpiresIn      string    `json:"expires_in"`
+}
+
+// Report is the outcome of checking a store
+type Report struct {
+	Store           string                  `json:"store"`
+	DisplayName     string                  `json:"display_name"`
+	Documents       int                     `json:"documents"`
+	Active          int                     `json:"active"`
+	Pending         int                     `json:"pending"`
+	Failed          int                     `json:"failed"`
+	Healthy         bool                    `json:"healthy"`
+	Issues          int                     `json:"issues"`
+	FailedDocuments []DocumentIssue         `json:"failed_documents"`
+	StalePending    []DocumentIssue         `json:"stale_pending"`
+	Duplicates      []gemini.DuplicateGroup `json:"duplicates"`
+	MissingMetadata []DocumentIssue         `json:"missing_metadata"`
+	SizeOutliers    []DocumentIssue         `json:"size_outliers"`
+	ExpiringFiles   []FileIssue             `json:"expiring_files"`
+}
+
+// Check audits the documents of store and the files in the Files API
+func Check(store *genai.FileSearchStore, docs []*genai.Document, files []*genai.File, opts Options) *Report {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.PendingAfter <= 0 {
+		opts.PendingAfter = DefaultPendingAfter
+	}
+	if opts.ExpiryWindow <= 0 {
+		opts.ExpiryWindow = DefaultExpiryWindow
+	}
+	if opts.OutlierFactor <= 1 {
+		opts.OutlierFactor = DefaultOutlierFactor
+	}
+
+	r := &Report{
+		Store:           store.Name,
+		DisplayName:     store.DisplayName,
+		Documents:       len(docs),
+		FailedDocuments: []DocumentIssue{},
+		StalePending:    []DocumentIssue{},
+		MissingMetadata: []DocumentIssue{},
+		SizeOutliers:    []DocumentIssue{},
+		ExpiringFiles:   []FileIssue{},
+	}
+
+	for _, doc := range docs {
+		switch doc.State {
+		case genai.DocumentStateActive:
+			r.Active++
+		case genai.DocumentStatePending:
+			r.Pending++
+			if age := opts.Now.Sub(doc.CreateTime); !doc.CreateTime.IsZero() && age > opts.PendingAfter {
+				r.StalePending = append(r.StalePending, issue(doc, fmt.Sprintf("pending for %s", age.Round(time.Minute))))
+			}
+		case genai.DocumentStateFailed:
+			r.Failed++
+			r.FailedDocuments = append(r.FailedDocuments, issue(doc, ""))
+		}
+
+		if missing := missingKeys(doc, opts.ExpectedMetadata); len(missing) > 0 {
+			r.MissingMetadata = append(r.MissingMetadata, issue(doc, "missing "+strings.Join(missing, ", ")))
+		}
+	}
+
+	r.Duplicates, _ = gemini.GroupDuplicates(docs, gemini.KeepNewest)
+	if r.Duplicates == nil {
+		r.Duplicates = []gemini.DuplicateGroup{}
+	}
+	r.SizeOutliers = append(r.SizeOutliers, sizeOutliers(docs, opts.OutlierFactor)...)
+	r.ExpiringFiles = append(r.ExpiringFiles, expiringFiles(docs, files, opts.Now, opts.ExpiryWindow)...)
+
+	r.Issues = len(r.FailedDocuments) + len(r.StalePending) + len(r.Duplicates) +
+		len(r.MissingMetadata) + len(r.SizeOutliers) + len(r.ExpiringFiles)
+	r.Healthy = r.Issues == 0
+	return r
+}
+
+func issue(doc *genai.Document, detail string) DocumentIssue {
+	return DocumentIssue{
+		Name:        doc.Name,
+		DisplayName: doc.DisplayName,
+		State:       strings.ToLower(strings.TrimPrefix(string(doc.State), "STATE_")),
+		SizeBytes:   doc.SizeBytes,
+		CreateTime:  doc.CreateTime,
+		UpdateTime:  doc.UpdateTime,
+		Metadata:    Metadata(doc),
+		Detail:      detail,
+	}
+}
+
+// Metadata returns a document's custom metadata as strings
+func Metadata(doc *genai.Document) map[string]string {
+	if len(doc.CustomMetadata) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(doc.CustomMetadata))
+	for _, m := range doc.CustomMetadata {
+		switch {
+		case m == nil:
+		case m.StringListValue != nil:
+			out[m.Key] = strings.Join(m.StringListValue.Values, ",")
+		case m.NumericValue != nil:
+			out[m.Key] = fmt.Sprint(*m.NumericValue)
+		default:
+			out[m.Key] = m.StringValue
+		}
+	}
+	return out
+}
+
+// missingKeys returns the expected keys a document has no metadata for
+func missingKeys(doc *genai.Document, expected []string) []string {
+	if len(expected) == 0 {
+		return nil
+	}
+	have := Metadata(doc)
+	var missing []string
+	for _, key := range expected {
+		if _, ok := have[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// sizeOutliers flags empty documents, and documents more than factor times
+// the median size when there are enough to tell
+func sizeOutliers(docs []*genai.Document, factor float64) []DocumentIssue {
+	var sizes []int64
+	for _, doc := range docs {
+		if doc.SizeBytes > 0 {
+			sizes = append(sizes, doc.SizeBytes)
+		}
+	}
+	slices.Sort(sizes)
+	var median int64
+	if len(sizes) >= minOutlierDocs {
+		median = sizes[len(sizes)/2]
+	}
+
+	var issues []DocumentIssue
+	for _, doc := range docs {
+		switch {
+		case doc.SizeBytes == 0 && doc.State == genai.DocumentStateActive:
+			issues = append(issues, issue(doc, "empty"))
+		case median > 0 && float64(doc.SizeBytes) > factor*float64(median):
+			issues = append(issues, issue(doc, fmt.Sprintf("%.0fx the median size of %d bytes", float64(doc.SizeBytes)/float64(median), median)))
+		}
+	}
+	return issues
+}
+
+// expiringFiles returns the files expiring within window that have no
+// document of the same name or content in the store
+func expiringFiles(docs []*genai.Document, files []*genai.File, now time.Time, window time.Duration) []FileIssue {
+	names := make(map[string]bool, len(docs))
+	hashes := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		names[doc.DisplayName] = true
+		if h := gemini.DocumentContentHash(doc); h != "" {
+			hashes[h] = true
+		}
+	}
+
+	var issues []FileIssue
+	for _, f := range files {
+		if f.ExpirationTime.IsZero() || f.ExpirationTime.Before(now) || f.ExpirationTime.Sub(now) > window {
+			continue
+		}
+		if (f.DisplayName != "" && names[f.DisplayName]) || hashes[gemini.FileContentHash(f)] {
+			continue
+		}
+		var size int64
+		if f.SizeBytes != nil {
+			size = *f.SizeBytes
+		}
+		issues = append(issues, FileIssue{
+			Name:           f.Name,
+			DisplayName:    f.DisplayName,
+			SizeBytes:      size,
+			ExpirationTime: f.ExpirationTime,
+			ExpiresIn:      f.ExpirationTime.Sub(now).Round(time.Minute).String(),
+		})
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].ExpirationTime.Before(issues[j].ExpirationTime) })
+	return issues
+}
+
+// FormatMetadata renders metadata as sorted key=value pairs
+func FormatMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for _, k := range slices.Sorted(maps.Keys(metadata)) {
+		pairs = append(pairs, k+"="+metadata[k])
+	}
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,80 @@
+package doctor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"google.golang.org/genai"
+)
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	meta := func(kv ...string) []*genai.CustomMetadata {
+		var out []*genai.CustomMetadata
+		for i := 0; i < len(kv); i += 2 {
+			out = append(out, &genai.CustomMetadata{Key: kv[i], StringValue: kv[i+1]})
+		}
+		return out
+	}
+	doc := func(name string, state genai.DocumentState, size int64, age time.Duration, md ...string) *genai.Document {
+		return &genai.Document{Name: "fileSearchStores/s/documents/" + name, DisplayName: name, State: state, SizeBytes: size, MIMEType: "application/pdf", CreateTime: now.Add(-age), CustomMetadata: meta(md...)}
+	}
+	docs := []*genai.Document{
+		doc("a.pdf", genai.DocumentStateActive, 100, 48*time.Hour, "team", "hw", gemini.ContentHashKey, "aaa"),
+		doc("a.pdf", genai.DocumentStateActive, 100, 24*time.Hour, "team", "hw", gemini.ContentHashKey, "aaa"),
+		doc("b.pdf", genai.DocumentStateActive, 120, 24*time.Hour, "team", "hw"),
+		doc("c.pdf", genai.DocumentStateActive, 90, 24*time.Hour),
+		doc("huge.pdf", genai.DocumentStateActive, 5000, 24*time.Hour, "team", "hw"),
+		doc("empty.pdf", genai.DocumentStateActive, 0, 24*time.Hour, "team", "hw"),
+		doc("broken.pdf", genai.DocumentStateFailed, 110, 24*time.Hour, "team", "hw", "source_path", "/tmp/broken.pdf"),
+		doc("slow.pdf", genai.DocumentStatePending, 0, 3*time.Hour, "team", "hw"),
+		doc("fresh.pdf", genai.DocumentStatePending, 0, 10*time.Minute, "team", "hw"),
+	}
+	size := int64(42)
+	files := []*genai.File{
+		{Name: "files/1", DisplayName: "orphan.pdf", SizeBytes: &size, ExpirationTime: now.Add(2 * time.Hour)},
+		{Name: "files/2", DisplayName: "b.pdf", ExpirationTime: now.Add(2 * time.Hour)},
+		{Name: "files/3", DisplayName: "later.pdf", ExpirationTime: now.Add(40 * time.Hour)},
+		{Name: "files/4", DisplayName: "gone.pdf", ExpirationTime: now.Add(-time.Hour)},
+	}
+	store := &genai.FileSearchStore{Name: "fileSearchStores/s", DisplayName: "Datasheets"}
+
+	r := Check(store, docs, files, Options{Now: now, ExpectedMetadata: []string{"team"}})
+
+	if r.Documents != 9 || r.Active != 6 || r.Pending != 2 || r.Failed != 1 {
+		t.Errorf("counts: %d documents, %d active, %d pending, %d failed", r.Documents, r.Active, r.Pending, r.Failed)
+	}
+	if len(r.FailedDocuments) != 1 || r.FailedDocuments[0].Metadata["source_path"] != "/tmp/broken.pdf" || r.FailedDocuments[0].State != "failed" {
+		t.Errorf("failed documents: %+v", r.FailedDocuments)
+	}
+	if len(r.StalePending) != 1 || r.StalePending[0].DisplayName != "slow.pdf" || r.StalePending[0].Detail != "pending for 3h0m0s" {
+		t.Errorf("stale pending: %+v", r.StalePending)
+	}
+	if len(r.Duplicates) != 1 || r.Duplicates[0].DisplayName != "a.pdf" {
+		t.Errorf("duplicates: %+v", r.Duplicates)
+	}
+	if len(r.MissingMetadata) != 1 || r.MissingMetadata[0].DisplayName != "c.pdf" || r.MissingMetadata[0].Detail != "missing team" {
+		t.Errorf("missing metadata: %+v", r.MissingMetadata)
+	}
+	if len(r.SizeOutliers) != 2 || r.SizeOutliers[0].DisplayName != "huge.pdf" || r.SizeOutliers[1].Detail != "empty" {
+		t.Errorf("size outliers: %+v", r.SizeOutliers)
+	}
+	if len(r.ExpiringFiles) != 1 || r.ExpiringFiles[0].Name != "files/1" || r.ExpiringFiles[0].ExpiresIn != "2h0m0s" || r.ExpiringFiles[0].SizeBytes != 42 {
+		t.Errorf("expiring files: %+v", r.ExpiringFiles)
+	}
+	if r.Healthy || r.Issues != 7 {
+		t.Errorf("healthy %v with %d issues, want false with 7", r.Healthy, r.Issues)
+	}
+
+	healthy := Check(store, docs[2:3], nil, Options{Now: now})
+	if !healthy.Healthy || healthy.Issues != 0 || healthy.Duplicates == nil || healthy.FailedDocuments == nil {
+		t.Errorf("expected a healthy report with empty lists, got %+v", healthy)
+	}
+}
+
+func TestFormatMetadata(t *testing.T) {
+	if got := FormatMetadata(map[string]string{"team": "hw", "kind": "spec"}); got != "kind=spec, team=hw" {
+		t.Errorf("FormatMetadata() = %q", got)
+	}
+}
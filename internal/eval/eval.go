@@ -0,0 +1,237 @@
+// Package eval scores query answers against a suite of expected sources and
+// facts, so retrieval and answer quality can be compared between runs.
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/genai"
+)
+
+// Suite is a set of evaluation cases. Store, Model and MetadataFilter are
+// defaults for the cases and may be overridden on the command line.
+type Suite struct {
+	Name           string `yaml:"name"`
+	Store          string `yaml:"store"`
+	Model          string `yaml:"model"`
+	MetadataFilter string `yaml:"metadata_filter"`
+	Cases          []Case `yaml:"cases"`
+}
+
+// Case is a question and what a good answer to it looks like
+type Case struct {
+	Name           string      `yaml:"name"`
+	Question       string      `yaml:"question"`
+	MetadataFilter string      `yaml:"metadata_filter"`
+	Expect         Expectation `yaml:"expect"`
+
+	patterns []*regexp.Regexp
+}
+
+// Expectation describes the sources an answer should be grounded in and
+// what it should say
+type Expectation struct {
+	// Sources are documents that should be retrieved and cited
+	Sources []ExpectedSource `yaml:"sources"`
+	// Facts must appear in the answer (case-insensitive)
+	Facts []string `yaml:"facts"`
+	// Patterns are regular expressions the answer must match
+	Patterns []string `yaml:"patterns"`
+}
+
+// ExpectedSource is a document, and optionally pages of it, that should
+// ground an answer
+type ExpectedSource struct {
+	// Document is matched against the source title or document resource
+	// name, case-insensitively, and may be a glob such as "lm317*.pdf"
+	Document string `yaml:"document"`
+	// Pages, if set, must overlap the source's page span
+	Pages []int `yaml:"pages"`
+}
+
+// Load reads and validates a suite file
+func Load(filename string) (*Suite, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var s Suite
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	if len(s.Cases) == 0 {
+		return nil, fmt.Errorf("%s has no cases", filename)
+	}
+
+	names := make(map[string]bool, len(s.Cases))
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case-%d", i+1)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate case name %q", c.Name)
+		}
+		names[c.Name] = true
+		if strings.TrimSpace(c.Question) == "" {
+			return nil, fmt.Errorf("case %s: question is required", c.Name)
+		}
+		for _, src := range c.Expect.Sources {
+			if src.Document == "" {
+				return nil, fmt.Errorf("case %s: expected sources need a document", c.Name)
+			}
+		}
+		for _, p := range c.Expect.Patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("case %s: invalid pattern %q: %w", c.Name, p, err)
+			}
+			c.patterns = append(c.patterns, re)
+		}
+	}
+	return &s, nil
+}
+
+// CaseResult is the score of one case. Checks that the case has no
+// expectations for are nil.
+type CaseResult struct {
+	Name     string `json:"name"`
+	Question string `json:"question"`
+	Answer   string `json:"answer,omitempty"`
+	// Retrieved lists the retrieved documents
+	Retrieved []string `json:"retrieved,omitempty"`
+	// RetrievalHit is set when an expected source was retrieved
+	RetrievalHit *bool `json:"retrieval_hit,omitempty"`
+	// Cited and CitedExpected count the distinct sources cited by the
+	// answer and how many of those were expected
+	Cited         int   `json:"cited"`
+	CitedExpected int   `json:"cited_expected"`
+	AnswerPass    *bool `json:"answer_pass,omitempty"`
+	// Failures describes each check that failed
+	Failures  []string `json:"failures,omitempty"`
+	LatencyMS int64    `json:"latency_ms"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Passed reports whether the case ran and none of its checks failed
+func (r *CaseResult) Passed() bool {
+	return r.Error == "" && len(r.Failures) == 0
+}
+
+// Failed returns the result of a case whose query failed
+func Failed(c Case, err error, latency time.Duration) CaseResult {
+	return CaseResult{Name: c.Name, Question: c.Question, LatencyMS: latency.Milliseconds(), Error: err.Error()}
+}
+
+// Score checks a query response against the case's expectations
+func Score(c Case, resp *genai.GenerateContentResponse, latency time.Duration) CaseResult {
+	cited := gemini.NewCitedAnswer(resp)
+	r := CaseResult{Name: c.Name, Question: c.Question, Answer: cited.Answer, LatencyMS: latency.Milliseconds()}
+
+	sources := make(map[int]gemini.Source, len(cited.Sources))
+	for _, src := range cited.Sources {
+		sources[src.Index] = src
+		label := src.Title
+		if src.FirstPage > 0 {
+			label += fmt.Sprintf(" p. %d", src.FirstPage)
+			if src.LastPage > src.FirstPage {
+				label += fmt.Sprintf("-%d", src.LastPage)
+			}
+		}
+		r.Retrieved = append(r.Retrieved, label)
+	}
+
+	if len(c.Expect.Sources) > 0 {
+		hit := false
+		for _, src := range cited.Sources {
+			if c.expected(src) {
+				hit = true
+				break
+			}
+		}
+		r.RetrievalHit = &hit
+		if !hit {
+			r.Failures = append(r.Failures, "no expected source was retrieved")
+		}
+
+		citedIdx := make(map[int]bool)
+		for _, citation := range cited.Citations {
+			for _, idx := range citation.Sources {
+				citedIdx[idx] = true
+			}
+		}
+		r.Cited = len(citedIdx)
+		for idx := range citedIdx {
+			if src, ok := sources[idx]; ok && c.expected(src) {
+				r.CitedExpected++
+			}
+		}
+	}
+
+	if len(c.Expect.Facts) > 0 || len(c.patterns) > 0 {
+		pass := true
+		answer := strings.ToLower(cited.Answer)
+		for _, fact := range c.Expect.Facts {
+			if !strings.Contains(answer, strings.ToLower(fact)) {
+				pass = false
+				r.Failures = append(r.Failures, fmt.Sprintf("answer is missing %q", fact))
+			}
+		}
+		for _, re := range c.patterns {
+			if !re.MatchString(cited.Answer) {
+				pass = false
+				r.Failures = append(r.Failures, fmt.Sprintf("answer does not match /%s/", re))
+			}
+		}
+		r.AnswerPass = &pass
+	}
+	return r
+}
+
+// expected reports whether src matches one of the case's expected sources
+func (c *Case) expected(src gemini.Source) bool {
+	for _, want := range c.Expect.Sources {
+		if !matchDocument(want.Document, src) {
+			continue
+		}
+		if len(want.Pages) == 0 {
+			return true
+		}
+		for _, page := range want.Pages {
+			if src.FirstPage > 0 && page >= src.FirstPage && page <= max(src.LastPage, src.FirstPage) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchDocument matches a document name or glob against a source's title
+// and document resource name
+func matchDocument(pattern string, src gemini.Source) bool {
+	pattern = strings.ToLower(pattern)
+	for _, name := range []string{src.Title, src.Document} {
+		if name == "" {
+			continue
+		}
+		name = strings.ToLower(name)
+		if name == pattern {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
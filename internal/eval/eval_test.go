@@ -0,0 +1,113 @@
+package eval
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const testSuite = `
+store: Datasheets
+cases:
+  - name: vin
+    question: What is the maximum input voltage?
+    expect:
+      sources:
+        - document: LM317*.pdf
+          pages: [3]
+      facts: ["40 V"]
+      patterns: ['\bvolts?\b|\bV\b']
+  - question: Who makes it?
+`
+
+func loadTestSuite(t *testing.T) *Suite {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "datasheets.yaml")
+	if err := os.WriteFile(path, []byte(testSuite), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	suite, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return suite
+}
+
+func TestLoad(t *testing.T) {
+	suite := loadTestSuite(t)
+	if suite.Name != "datasheets" || suite.Store != "Datasheets" || len(suite.Cases) != 2 {
+		t.Errorf("unexpected suite: %+v", suite)
+	}
+	if suite.Cases[1].Name != "case-2" {
+		t.Errorf("expected a generated case name, got %q", suite.Cases[1].Name)
+	}
+
+	for name, yaml := range map[string]string{
+		"no cases":        "name: empty\n",
+		"no question":     "cases:\n  - name: a\n",
+		"bad pattern":     "cases:\n  - question: q\n    expect:\n      patterns: ['(']\n",
+		"duplicate names": "cases:\n  - {name: a, question: q}\n  - {name: a, question: r}\n",
+	} {
+		path := filepath.Join(t.TempDir(), "suite.yaml")
+		os.WriteFile(path, []byte(yaml), 0o644)
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func response(answer string, chunks []*genai.GroundingChunk, cited ...int32) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		Content: genai.NewContentFromText(answer, genai.RoleModel),
+		GroundingMetadata: &genai.GroundingMetadata{
+			GroundingChunks: chunks,
+			GroundingSupports: []*genai.GroundingSupport{
+				{Segment: &genai.Segment{EndIndex: int32(len(answer)), Text: answer}, GroundingChunkIndices: cited},
+			},
+		},
+	}}}
+}
+
+func chunk(title string, page int32) *genai.GroundingChunk {
+	return &genai.GroundingChunk{RetrievedContext: &genai.GroundingChunkRetrievedContext{Title: title, PageNumber: genai.Ptr(page)}}
+}
+
+func TestScore(t *testing.T) {
+	c := loadTestSuite(t).Cases[0]
+
+	t.Run("pass", func(t *testing.T) {
+		resp := response("The maximum input voltage is 40 V.", []*genai.GroundingChunk{chunk("lm317.pdf", 3), chunk("notes.md", 1)}, 0, 1)
+		r := Score(c, resp, 1500*time.Millisecond)
+		if !r.Passed() || r.RetrievalHit == nil || !*r.RetrievalHit || r.AnswerPass == nil || !*r.AnswerPass {
+			t.Errorf("expected the case to pass: %+v", r)
+		}
+		if r.Cited != 2 || r.CitedExpected != 1 {
+			t.Errorf("cited = %d/%d, want 1/2", r.CitedExpected, r.Cited)
+		}
+		if r.LatencyMS != 1500 || r.Retrieved[0] != "lm317.pdf p. 3" {
+			t.Errorf("unexpected result: %+v", r)
+		}
+	})
+
+	t.Run("wrong page and missing fact", func(t *testing.T) {
+		resp := response("It is 35 volts.", []*genai.GroundingChunk{chunk("lm317.pdf", 9)}, 0)
+		r := Score(c, resp, 0)
+		if r.Passed() || *r.RetrievalHit || *r.AnswerPass {
+			t.Errorf("expected the case to fail: %+v", r)
+		}
+		if len(r.Failures) != 2 || !strings.Contains(r.Failures[1], `"40 V"`) {
+			t.Errorf("unexpected failures: %q", r.Failures)
+		}
+	})
+
+	t.Run("no expectations", func(t *testing.T) {
+		r := Score(loadTestSuite(t).Cases[1], response("Texas Instruments.", nil), 0)
+		if !r.Passed() || r.RetrievalHit != nil || r.AnswerPass != nil {
+			t.Errorf("expected unchecked results: %+v", r)
+		}
+	})
+}
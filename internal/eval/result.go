@@ -0,0 +1,289 @@
+package eval
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Metrics summarizes a run. Rates are nil when no case has the
+// expectations they need.
+type Metrics struct {
+	Cases  int `json:"cases"`
+	Passed int `json:"passed"`
+	Errors int `json:"errors"`
+	// RetrievalHitRate is the share of cases with expected sources where
+	// at least one was retrieved
+	RetrievalHitRate *float64 `json:"retrieval_hit_rate,omitempty"`
+	// CitationPrecision is the share of cited sources that were expected
+	CitationPrecision *float64 `json:"citation_precision,omitempty"`
+	// AnswerPassRate is the share of cases with expected facts or patterns
+	// whose answer had all of them
+	AnswerPassRate *float64 `json:"answer_pass_rate,omitempty"`
+}
+
+// Result is a scored run of a suite
+type Result struct {
+	Suite   string       `json:"suite"`
+	Store   string       `json:"store,omitempty"`
+	Model   string       `json:"model"`
+	Created time.Time    `json:"created"`
+	Metrics Metrics      `json:"metrics"`
+	Cases   []CaseResult `json:"cases"`
+}
+
+// Summarize computes the metrics of a run
+func Summarize(suite, store, model string, cases []CaseResult) *Result {
+	r := &Result{Suite: suite, Store: store, Model: model, Created: time.Now(), Cases: cases}
+	m := &r.Metrics
+	m.Cases = len(cases)
+
+	var hits, hitCases, cited, citedExpected, passes, passCases int
+	for _, c := range cases {
+		if c.Passed() {
+			m.Passed++
+		}
+		if c.Error != "" {
+			m.Errors++
+		}
+		if c.RetrievalHit != nil {
+			hitCases++
+			if *c.RetrievalHit {
+				hits++
+			}
+		}
+		cited += c.Cited
+		citedExpected += c.CitedExpected
+		if c.AnswerPass != nil {
+			passCases++
+			if *c.AnswerPass {
+				passes++
+			}
+		}
+	}
+	m.RetrievalHitRate = rate(hits, hitCases)
+	m.CitationPrecision = rate(citedExpected, cited)
+	m.AnswerPassRate = rate(passes, passCases)
+	return r
+}
+
+// PassRate is the share of cases that passed, counting errored cases as
+// failed
+func (m *Metrics) PassRate() float64 {
+	if m.Cases == 0 {
+		return 1
+	}
+	return float64(m.Passed) / float64(m.Cases)
+}
+
+func rate(n, total int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	r := float64(n) / float64(total)
+	return &r
+}
+
+// Save writes r as JSON so it can be used as a baseline
+func (r *Result) Save(filename string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// LoadResult reads a result saved with Save
+func LoadResult(filename string) (*Result, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var r Result
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	return &r, nil
+}
+
+// Comparison is a run compared against a baseline
+type Comparison struct {
+	Baseline *Result `json:"-"`
+	// Regressions and Improvements name the cases that started failing or
+	// passing since the baseline
+	Regressions  []string `json:"regressions,omitempty"`
+	Improvements []string `json:"improvements,omitempty"`
+}
+
+// Compare compares r against a baseline run of the same suite
+func Compare(baseline, r *Result) *Comparison {
+	cmp := &Comparison{Baseline: baseline}
+	before := make(map[string]bool, len(baseline.Cases))
+	for _, c := range baseline.Cases {
+		before[c.Name] = c.Passed()
+	}
+	for _, c := range r.Cases {
+		passed, ok := before[c.Name]
+		switch {
+		case !ok:
+		case passed && !c.Passed():
+			cmp.Regressions = append(cmp.Regressions, c.Name)
+		case !passed && c.Passed():
+			cmp.Improvements = append(cmp.Improvements, c.Name)
+		}
+	}
+	return cmp
+}
+
+// Regressed reports whether a case or metric got worse than the baseline
+func (c *Comparison) Regressed(r *Result) bool {
+	if len(c.Regressions) > 0 {
+		return true
+	}
+	b := c.Baseline.Metrics
+	return worse(b.RetrievalHitRate, r.Metrics.RetrievalHitRate) ||
+		worse(b.CitationPrecision, r.Metrics.CitationPrecision) ||
+		worse(b.AnswerPassRate, r.Metrics.AnswerPassRate)
+}
+
+func worse(before, after *float64) bool {
+	return before != nil && after != nil && *after < *before
+}
+
+// WriteSummary writes the metrics of r as a table, with the change from the
+// baseline when cmp is set, followed by the failed cases
+func WriteSummary(w io.Writer, r *Result, cmp *Comparison) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "METRIC\tVALUE"
+	if cmp != nil {
+		header += "\tBASELINE\tCHANGE"
+	}
+	fmt.Fprintln(tw, header)
+
+	row := func(name string, value, baseline *float64) {
+		line := name + "\t" + percent(value)
+		if cmp != nil {
+			line += "\t" + percent(baseline) + "\t" + change(baseline, value)
+		}
+		fmt.Fprintln(tw, line)
+	}
+	var b Metrics
+	if cmp != nil {
+		b = cmp.Baseline.Metrics
+	}
+	row("Retrieval hit rate", r.Metrics.RetrievalHitRate, b.RetrievalHitRate)
+	row("Citation precision", r.Metrics.CitationPrecision, b.CitationPrecision)
+	row("Answer pass rate", r.Metrics.AnswerPassRate, b.AnswerPassRate)
+	passed := fmt.Sprintf("Cases passed\t%d/%d", r.Metrics.Passed, r.Metrics.Cases)
+	if cmp != nil {
+		passed += fmt.Sprintf("\t%d/%d\t", b.Passed, b.Cases)
+	}
+	fmt.Fprintln(tw, passed)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, c := range r.Cases {
+		if c.Passed() {
+			continue
+		}
+		fmt.Fprintf(w, "\n✗ %s: %s\n", c.Name, c.Question)
+		if c.Error != "" {
+			fmt.Fprintf(w, "    error: %s\n", c.Error)
+		}
+		for _, f := range c.Failures {
+			fmt.Fprintf(w, "    %s\n", f)
+		}
+		if len(c.Retrieved) > 0 && c.RetrievalHit != nil && !*c.RetrievalHit {
+			fmt.Fprintf(w, "    retrieved: %s\n", strings.Join(c.Retrieved, ", "))
+		}
+	}
+	if cmp != nil {
+		if len(cmp.Regressions) > 0 {
+			fmt.Fprintf(w, "\nRegressed since baseline: %s\n", strings.Join(cmp.Regressions, ", "))
+		}
+		if len(cmp.Improvements) > 0 {
+			fmt.Fprintf(w, "\nFixed since baseline: %s\n", strings.Join(cmp.Improvements, ", "))
+		}
+	}
+	return nil
+}
+
+func percent(v *float64) string {
+	if v == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", *v*100)
+}
+
+func change(before, after *float64) string {
+	if before == nil || after == nil {
+		return ""
+	}
+	return fmt.Sprintf("%+.1f", (*after-*before)*100)
+}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes r as a JUnit XML test suite with one test case per case
+func WriteJUnit(w io.Writer, r *Result) error {
+	suite := junitSuite{Name: r.Suite, Tests: len(r.Cases), Errors: r.Metrics.Errors}
+	var total int64
+	for _, c := range r.Cases {
+		total += c.LatencyMS
+		tc := junitCase{Name: c.Name, ClassName: r.Suite, Time: seconds(c.LatencyMS)}
+		switch {
+		case c.Error != "":
+			tc.Error = &junitMessage{Message: c.Error, Body: c.Question}
+		case len(c.Failures) > 0:
+			suite.Failures++
+			tc.Failure = &junitMessage{
+				Message: c.Failures[0],
+				Body:    fmt.Sprintf("%s\n\n%s\n\nAnswer:\n%s", c.Question, strings.Join(c.Failures, "\n"), c.Answer),
+			}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
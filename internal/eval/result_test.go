@@ -0,0 +1,93 @@
+package eval
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testCases() []CaseResult {
+	yes, no := true, false
+	return []CaseResult{
+		{Name: "a", RetrievalHit: &yes, Cited: 2, CitedExpected: 2, AnswerPass: &yes, LatencyMS: 1000},
+		{Name: "b", RetrievalHit: &no, Cited: 2, AnswerPass: &no, Failures: []string{"no expected source was retrieved", `answer is missing "40 V"`}},
+		{Name: "c", Error: "quota exceeded"},
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	r := Summarize("suite", "Datasheets", "m", testCases())
+	m := r.Metrics
+	if m.Cases != 3 || m.Passed != 1 || m.Errors != 1 {
+		t.Errorf("unexpected counts: %+v", m)
+	}
+	if *m.RetrievalHitRate != 0.5 || *m.CitationPrecision != 0.5 || *m.AnswerPassRate != 0.5 {
+		t.Errorf("unexpected rates: %v %v %v", *m.RetrievalHitRate, *m.CitationPrecision, *m.AnswerPassRate)
+	}
+	if got := m.PassRate(); got != 1.0/3 {
+		t.Errorf("expected errored cases to count as failed, got pass rate %v", got)
+	}
+
+	empty := Summarize("suite", "", "m", []CaseResult{{Name: "x"}})
+	if empty.Metrics.RetrievalHitRate != nil || empty.Metrics.AnswerPassRate != nil {
+		t.Errorf("expected no rates without expectations: %+v", empty.Metrics)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := Summarize("suite", "", "m", []CaseResult{
+		{Name: "a", Failures: []string{"x"}},
+		{Name: "b"},
+		{Name: "c"},
+	})
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := baseline.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := LoadResult(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := Summarize("suite", "", "m", testCases())
+	cmp := Compare(baseline, r)
+	if len(cmp.Improvements) != 1 || cmp.Improvements[0] != "a" {
+		t.Errorf("improvements = %v", cmp.Improvements)
+	}
+	if len(cmp.Regressions) != 2 || !cmp.Regressed(r) {
+		t.Errorf("regressions = %v", cmp.Regressions)
+	}
+
+	var b strings.Builder
+	if err := WriteSummary(&b, r, cmp); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{"Retrieval hit rate  50.0%", "Cases passed        1/3", "✗ b", "error: quota exceeded", "Regressed since baseline: b, c"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var b strings.Builder
+	if err := WriteJUnit(&b, Summarize("datasheets", "", "m", testCases())); err != nil {
+		t.Fatal(err)
+	}
+
+	var suite junitSuite
+	if err := xml.Unmarshal([]byte(b.String()), &suite); err != nil {
+		t.Fatal(err)
+	}
+	if suite.Name != "datasheets" || suite.Tests != 3 || suite.Failures != 1 || suite.Errors != 1 {
+		t.Errorf("unexpected suite: %+v", suite)
+	}
+	if suite.Cases[0].Failure != nil || suite.Cases[0].Time != "1.000" {
+		t.Errorf("unexpected passing case: %+v", suite.Cases[0])
+	}
+	if suite.Cases[1].Failure == nil || suite.Cases[2].Error == nil {
+		t.Errorf("expected a failure and an error: %+v", suite.Cases)
+	}
+}
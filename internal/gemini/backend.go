@@ -0,0 +1,93 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Backend names accepted by --backend and the "backend" config key
+const (
+	BackendGemini = "gemini"
+	BackendVertex = "vertex"
+)
+
+// ErrUnsupportedBackend is returned for operations the selected backend does
+// not offer, e.g. File Search stores and the Files API on Vertex AI
+var ErrUnsupportedBackend = errors.New("not supported by the selected backend")
+
+// ClientOptions selects the API backend and its credentials
+type ClientOptions struct {
+	// Backend is BackendGemini (the default) or BackendVertex
+	Backend string
+	// APIKey authenticates with the Gemini API
+	APIKey string
+	// Project and Location select the Vertex AI project and region.
+	// Vertex AI uses application-default credentials.
+	Project  string
+	Location string
+	// HTTPClient overrides the transport (used by tests)
+	HTTPClient *http.Client
+	// OnUsage, if set, receives the token usage of queries and indexing
+	OnUsage UsageHook
+	// Cache, if set, answers repeated queries from stored responses
+	Cache ResponseCache
+	// OnOperation, if set, is called when an upload or import operation starts
+	OnOperation OperationHook
+	// Pick, if set, chooses between ambiguous or similar names when resolving
+	Pick Picker
+	// NameCacheTTL limits how long listings used to resolve names are kept.
+	// Zero keeps them for the life of the client, or until it changes them.
+	NameCacheTTL time.Duration
+}
+
+// NewClientWithOptions creates a client for the backend in opts
+func NewClientWithOptions(ctx context.Context, opts ClientOptions) (*Client, error) {
+	cfg := &genai.ClientConfig{HTTPClient: opts.HTTPClient}
+
+	switch strings.ToLower(opts.Backend) {
+	case "", BackendGemini:
+		if opts.APIKey == "" {
+			return nil, fmt.Errorf("GEMINI_API_KEY not set")
+		}
+		cfg.Backend = genai.BackendGeminiAPI
+		cfg.APIKey = opts.APIKey
+	case BackendVertex, "vertexai":
+		if opts.Project == "" || opts.Location == "" {
+			return nil, fmt.Errorf("the vertex backend requires a project and location (--project/--location, or project/location in config)")
+		}
+		cfg.Backend = genai.BackendVertexAI
+		cfg.Project = opts.Project
+		cfg.Location = opts.Location
+	default:
+		return nil, fmt.Errorf("unknown backend %q (valid: %s, %s)", opts.Backend, BackendGemini, BackendVertex)
+	}
+
+	client, err := genai.NewClient(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{client: client, backend: cfg.Backend, onUsage: opts.OnUsage, cache: opts.Cache, onOperation: opts.OnOperation, pick: opts.Pick, names: newNameIndex(opts.NameCacheTTL)}, nil
+}
+
+// Backend returns the name of the backend the client talks to
+func (c *Client) Backend() string {
+	if c.backend == genai.BackendVertexAI {
+		return BackendVertex
+	}
+	return BackendGemini
+}
+
+// requireGeminiAPI fails with ErrUnsupportedBackend unless the client uses
+// the Gemini API, which is the only backend with File Search and the Files API
+func (c *Client) requireGeminiAPI(feature string) error {
+	if c.backend == genai.BackendVertexAI {
+		return fmt.Errorf("%w (%s): %s is only available with --backend %s", ErrUnsupportedBackend, BackendVertex, feature, BackendGemini)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeTransport records requests and answers them with a canned JSON body
+type fakeTransport struct {
+	requests []*http.Request
+	body     string
+}
+
+func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.requests = append(f.requests, req)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(f.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestNewClientWithOptionsValidation(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		opts    ClientOptions
+		wantErr string
+	}{
+		{"gemini without key", ClientOptions{}, "GEMINI_API_KEY"},
+		{"vertex without project", ClientOptions{Backend: BackendVertex, Location: "us-central1"}, "project and location"},
+		{"unknown backend", ClientOptions{Backend: "bedrock", APIKey: "k"}, "unknown backend"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClientWithOptions(ctx, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVertexBackend(t *testing.T) {
+	ctx := context.Background()
+	transport := &fakeTransport{body: `{"candidates":[{"content":{"role":"model","parts":[{"text":"hello"}]}}]}`}
+	client, err := NewClientWithOptions(ctx, ClientOptions{
+		Backend:    BackendVertex,
+		Project:    "my-project",
+		Location:   "us-central1",
+		HTTPClient: &http.Client{Transport: transport},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Backend() != BackendVertex {
+		t.Errorf("Backend() = %q, want %q", client.Backend(), BackendVertex)
+	}
+
+	// Plain generation works and goes to the Vertex endpoint
+	resp, err := client.Query(ctx, "hi", "", "gemini-2.5-flash", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "hello" {
+		t.Errorf("unexpected response text %q", resp.Text())
+	}
+	if len(transport.requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(transport.requests))
+	}
+	if path := transport.requests[0].URL.Path; !strings.Contains(path, "projects/my-project/locations/us-central1") {
+		t.Errorf("request not sent to Vertex AI: %s", path)
+	}
+
+	// File Search features fail up front without any request
+	transport.requests = nil
+	checks := map[string]func() error{
+		"ListStores": func() error { _, err := client.ListStores(ctx); return err },
+		"ListFiles":  func() error { _, err := client.ListFiles(ctx); return err },
+		"Query with store": func() error {
+			_, err := client.Query(ctx, "hi", "fileSearchStores/abc", "gemini-2.5-flash", nil)
+			return err
+		},
+		"DeleteDocument": func() error { return client.DeleteDocument(ctx, "fileSearchStores/a/documents/b", false) },
+	}
+	for name, check := range checks {
+		if err := check(); !errors.Is(err, ErrUnsupportedBackend) {
+			t.Errorf("%s: expected ErrUnsupportedBackend, got %v", name, err)
+		}
+	}
+	if len(transport.requests) != 0 {
+		t.Errorf("unsupported operations sent %d requests", len(transport.requests))
+	}
+}
@@ -0,0 +1,220 @@
+package gemini
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"google.golang.org/genai"
+)
+
+// Span is a segment of an answer
+type Span struct {
+	Text string `json:"text"`
+	// Start and End are byte offsets of the segment in the answer
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Citation links a segment of an answer to the sources that support it
+type Citation struct {
+	Span
+	// Sources are the indexes of the supporting sources
+	Sources    []int     `json:"sources"`
+	Confidence []float32 `json:"confidence,omitempty"`
+}
+
+// CitedAnswer is a query answer with citations mapped onto its text
+type CitedAnswer struct {
+	Answer    string     `json:"answer"`
+	Citations []Citation `json:"citations,omitempty"`
+	Sources   []Source   `json:"sources"`
+	// Unsupported lists the sentences of a grounded answer that no source
+	// supports
+	Unsupported []Span `json:"unsupported,omitempty"`
+	Usage       *Usage `json:"usage,omitempty"`
+}
+
+// NewCitedAnswer maps the grounding supports of the first candidate in resp
+// onto its answer text
+func NewCitedAnswer(resp *genai.GenerateContentResponse) *CitedAnswer {
+	a := &CitedAnswer{Sources: Sources(resp), Usage: UsageOf(resp)}
+	if a.Sources == nil {
+		a.Sources = []Source{}
+	}
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return a
+	}
+	cand := resp.Candidates[0]
+
+	// Segment offsets are relative to their part; the answer joins the
+	// non-thought text parts
+	offsets := make(map[int]int, len(cand.Content.Parts))
+	var answer strings.Builder
+	for i, part := range cand.Content.Parts {
+		if part.Text == "" || part.Thought {
+			continue
+		}
+		offsets[i] = answer.Len()
+		answer.WriteString(part.Text)
+	}
+	a.Answer = answer.String()
+
+	if cand.GroundingMetadata == nil {
+		return a
+	}
+	for _, support := range cand.GroundingMetadata.GroundingSupports {
+		if support.Segment == nil || len(support.GroundingChunkIndices) == 0 {
+			continue
+		}
+		base, ok := offsets[int(support.Segment.PartIndex)]
+		if !ok {
+			continue
+		}
+		span, ok := a.locate(support.Segment, base)
+		if !ok {
+			continue
+		}
+		citation := Citation{Span: span, Confidence: support.ConfidenceScores}
+		for _, idx := range support.GroundingChunkIndices {
+			citation.Sources = append(citation.Sources, int(idx)+1)
+		}
+		a.Citations = append(a.Citations, citation)
+	}
+	sort.SliceStable(a.Citations, func(i, j int) bool { return a.Citations[i].Start < a.Citations[j].Start })
+
+	if len(cand.GroundingMetadata.GroundingChunks) > 0 {
+		a.Unsupported = a.unsupportedSentences()
+	}
+	return a
+}
+
+// locate returns the answer span of a segment. Offsets that don't match the
+// segment text fall back to searching for the text.
+func (a *CitedAnswer) locate(seg *genai.Segment, base int) (Span, bool) {
+	start, end := base+int(seg.StartIndex), base+int(seg.EndIndex)
+	if start >= 0 && start < end && end <= len(a.Answer) && (seg.Text == "" || a.Answer[start:end] == seg.Text) {
+		return Span{Text: a.Answer[start:end], Start: start, End: end}, true
+	}
+	if seg.Text == "" {
+		return Span{}, false
+	}
+	if i := strings.Index(a.Answer, seg.Text); i >= 0 {
+		return Span{Text: seg.Text, Start: i, End: i + len(seg.Text)}, true
+	}
+	return Span{}, false
+}
+
+// unsupportedSentences returns the sentences that no citation overlaps
+func (a *CitedAnswer) unsupportedSentences() []Span {
+	var unsupported []Span
+	for _, sentence := range sentences(a.Answer) {
+		supported := false
+		for _, c := range a.Citations {
+			if c.Start < sentence.End && c.End > sentence.Start {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			unsupported = append(unsupported, sentence)
+		}
+	}
+	return unsupported
+}
+
+// sentences splits text into sentences ending at ., ! or ? followed by
+// whitespace, or at a line break. Spans without letters or digits (e.g.
+// Markdown rules) are skipped.
+func sentences(text string) []Span {
+	var spans []Span
+	add := func(start, end int) {
+		s := strings.TrimSpace(text[start:end])
+		if s == "" || !strings.ContainsFunc(s, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+			return
+		}
+		start += strings.Index(text[start:end], s)
+		spans = append(spans, Span{Text: s, Start: start, End: start + len(s)})
+	}
+
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\n':
+			add(start, i)
+			start = i + 1
+		case '.', '!', '?':
+			if i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t' || text[i+1] == '\n' {
+				add(start, i+1)
+				start = i + 1
+			}
+		}
+	}
+	if start < len(text) {
+		add(start, len(text))
+	}
+	return spans
+}
+
+// Render returns the answer with [n] citation markers after each cited
+// segment. If markUnsupported is set, it rewrites each unsupported sentence.
+func (a *CitedAnswer) Render(markUnsupported func(string) string) string {
+	type insert struct {
+		pos  int
+		text string
+	}
+	var inserts []insert
+	for _, c := range a.Citations {
+		// Keep markers before trailing whitespace, e.g. a line break
+		pos := c.Start + len(strings.TrimRightFunc(c.Text, unicode.IsSpace))
+		var markers strings.Builder
+		for _, idx := range c.Sources {
+			fmt.Fprintf(&markers, "[%d]", idx)
+		}
+		inserts = append(inserts, insert{pos, markers.String()})
+	}
+	sort.SliceStable(inserts, func(i, j int) bool { return inserts[i].pos < inserts[j].pos })
+
+	var replaced []Span
+	if markUnsupported != nil {
+		replaced = a.Unsupported
+	}
+
+	var b strings.Builder
+	pos := 0
+	for _, ins := range inserts {
+		// Citations never overlap unsupported sentences, so these can be
+		// written in order
+		for len(replaced) > 0 && replaced[0].End <= ins.pos {
+			b.WriteString(a.Answer[pos:replaced[0].Start])
+			b.WriteString(markUnsupported(replaced[0].Text))
+			pos = replaced[0].End
+			replaced = replaced[1:]
+		}
+		if ins.pos < pos {
+			ins.pos = pos
+		}
+		b.WriteString(a.Answer[pos:ins.pos])
+		b.WriteString(ins.text)
+		pos = ins.pos
+	}
+	for _, s := range replaced {
+		b.WriteString(a.Answer[pos:s.Start])
+		b.WriteString(markUnsupported(s.Text))
+		pos = s.End
+	}
+	b.WriteString(a.Answer[pos:])
+	return b.String()
+}
+
+// Compact returns the answer with inline citation markers and the sources
+// without their chunk text
+func (a *CitedAnswer) Compact() *CitedAnswer {
+	sources := make([]Source, len(a.Sources))
+	for i, src := range a.Sources {
+		src.Text = ""
+		sources[i] = src
+	}
+	return &CitedAnswer{Answer: a.Render(nil), Sources: sources}
+}
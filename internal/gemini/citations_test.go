@@ -0,0 +1,72 @@
+package gemini
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestNewCitedAnswer(t *testing.T) {
+	answer := "The LM317 accepts up to 40 V. It is popular. Dropout is about 2 V.\n"
+	resp := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		Content: &genai.Content{Parts: []*genai.Part{
+			{Text: "thinking", Thought: true},
+			{Text: answer},
+		}},
+		GroundingMetadata: &genai.GroundingMetadata{
+			GroundingChunks: []*genai.GroundingChunk{
+				{RetrievedContext: &genai.GroundingChunkRetrievedContext{Title: "lm317.pdf", Text: "40 V"}},
+				{RetrievedContext: &genai.GroundingChunkRetrievedContext{Title: "notes.md", Text: "2 V"}},
+			},
+			GroundingSupports: []*genai.GroundingSupport{
+				{
+					Segment:               &genai.Segment{PartIndex: 1, StartIndex: 0, EndIndex: 29, Text: "The LM317 accepts up to 40 V."},
+					GroundingChunkIndices: []int32{0},
+				},
+				{
+					// Offsets that don't match the text are located by the text
+					Segment:               &genai.Segment{PartIndex: 1, StartIndex: 3, EndIndex: 9, Text: "Dropout is about 2 V."},
+					GroundingChunkIndices: []int32{0, 1},
+					ConfidenceScores:      []float32{0.5, 0.9},
+				},
+			},
+		},
+	}}}
+
+	cited := NewCitedAnswer(resp)
+	if cited.Answer != answer {
+		t.Errorf("thought parts should be skipped, got %q", cited.Answer)
+	}
+	if len(cited.Citations) != 2 || cited.Citations[1].Start != 45 || cited.Citations[1].Sources[1] != 2 {
+		t.Fatalf("unexpected citations: %+v", cited.Citations)
+	}
+	if len(cited.Unsupported) != 1 || cited.Unsupported[0].Text != "It is popular." {
+		t.Errorf("unexpected unsupported sentences: %+v", cited.Unsupported)
+	}
+
+	want := "The LM317 accepts up to 40 V.[1] It is popular. Dropout is about 2 V.[1][2]\n"
+	if got := cited.Render(nil); got != want {
+		t.Errorf("Render(nil) = %q, want %q", got, want)
+	}
+	want = "The LM317 accepts up to 40 V.[1] <It is popular.> Dropout is about 2 V.[1][2]\n"
+	if got := cited.Render(func(s string) string { return "<" + s + ">" }); got != want {
+		t.Errorf("Render(mark) = %q, want %q", got, want)
+	}
+
+	compact := cited.Compact()
+	if compact.Answer != cited.Render(nil) || compact.Citations != nil || compact.Sources[0].Text != "" {
+		t.Errorf("unexpected compact answer: %+v", compact)
+	}
+	if cited.Sources[0].Text == "" {
+		t.Error("Compact should not modify the original sources")
+	}
+}
+
+func TestNewCitedAnswerUngrounded(t *testing.T) {
+	cited := NewCitedAnswer(&genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		Content: genai.NewContentFromText("No store was searched.", genai.RoleModel),
+	}}})
+	if cited.Unsupported != nil || cited.Citations != nil || cited.Render(nil) != "No store was searched." {
+		t.Errorf("unexpected answer: %+v", cited)
+	}
+}
@@ -3,7 +3,10 @@ package gemini
 import (
 	"context"
 	"fmt"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -31,27 +34,23 @@ type OperationStatus struct {
 	DocumentName string         `json:"documentName,omitempty"`
 }
 
+// OperationHook receives the name and type of each long-running operation a
+// client starts, and the store it writes to
+type OperationHook func(name string, opType OperationType, store string)
+
 type Client struct {
-	client *genai.Client
+	client      *genai.Client
+	backend     genai.Backend
+	onUsage     UsageHook
+	cache       ResponseCache
+	onOperation OperationHook
+	pick        Picker
+	names       *nameIndex
 }
 
+// NewClient creates a Gemini API client authenticated with apiKey
 func NewClient(ctx context.Context, apiKey string, httpClient *http.Client) (*Client, error) {
-	if apiKey == "" {
-		return nil, fmt.Errorf("GEMINI_API_KEY not set")
-	}
-
-	cfg := &genai.ClientConfig{
-		APIKey:     apiKey,
-		Backend:    genai.BackendGeminiAPI,
-		HTTPClient: httpClient,
-	}
-
-	client, err := genai.NewClient(ctx, cfg)
-	if err != nil {
-		return nil, err
-	}
-
-	return &Client{client: client}, nil
+	return NewClientWithOptions(ctx, ClientOptions{APIKey: apiKey, HTTPClient: httpClient})
 }
 
 func (c *Client) Close() {
@@ -59,6 +58,9 @@ func (c *Client) Close() {
 }
 
 func (c *Client) ListStores(ctx context.Context) ([]*genai.FileSearchStore, error) {
+	if err := c.requireGeminiAPI("File Search store management"); err != nil {
+		return nil, err
+	}
 	resp, err := c.client.FileSearchStores.List(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -96,8 +98,12 @@ func (c *Client) ListModels(ctx context.Context) ([]*genai.Model, error) {
 	return models, nil
 }
 
-// ResolveStoreName resolves a display name or partial name to a full store resource name.
+// ResolveStoreName resolves a display name to a full store resource name.
 // If the input is already a resource name (starts with "fileSearchStores/"), returns it as-is.
+// Names shared by several stores return an *AmbiguousNameError, and unknown
+// names a *NotFoundError with similar names, unless the client's picker
+// chooses one. Listings are cached for the life of the client and dropped
+// by its own changes, so resolving many names lists stores once.
 func (c *Client) ResolveStoreName(ctx context.Context, nameOrID string) (string, error) {
 	// If already a resource name, return as-is
 	if strings.HasPrefix(nameOrID, constants.StoreResourcePrefix) {
@@ -105,18 +111,17 @@ func (c *Client) ResolveStoreName(ctx context.Context, nameOrID string) (string,
 	}
 
 	// Otherwise, search for display name
-	stores, err := c.ListStores(ctx)
-	if err != nil {
-		return "", err
-	}
-
-	for _, s := range stores {
-		if s.DisplayName == nameOrID {
-			return s.Name, nil
+	return c.resolveIndexed(ctx, "store", nameOrID, "", namesStores, func(ctx context.Context) ([]Candidate, error) {
+		stores, err := c.ListStores(ctx)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	return "", fmt.Errorf("store not found: %s", nameOrID)
+		candidates := make([]Candidate, 0, len(stores))
+		for _, s := range stores {
+			candidates = append(candidates, Candidate{Name: s.Name, DisplayName: s.DisplayName, CreateTime: s.CreateTime})
+		}
+		return candidates, nil
+	})
 }
 
 // ResolveFileName resolves a file display name to a full file resource name.
@@ -128,18 +133,17 @@ func (c *Client) ResolveFileName(ctx context.Context, nameOrID string) (string,
 	}
 
 	// Otherwise, search for display name
-	files, err := c.ListFiles(ctx)
-	if err != nil {
-		return "", err
-	}
-
-	for _, f := range files {
-		if f.DisplayName == nameOrID {
-			return f.Name, nil
+	return c.resolveIndexed(ctx, "file", nameOrID, "", namesFiles, func(ctx context.Context) ([]Candidate, error) {
+		files, err := c.ListFiles(ctx)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	return "", fmt.Errorf("file not found: %s", nameOrID)
+		candidates := make([]Candidate, 0, len(files))
+		for _, f := range files {
+			candidates = append(candidates, Candidate{Name: f.Name, DisplayName: f.DisplayName, CreateTime: f.CreateTime})
+		}
+		return candidates, nil
+	})
 }
 
 // ResolveDocumentName resolves a document display name to a full document resource name.
@@ -158,18 +162,22 @@ func (c *Client) ResolveDocumentName(ctx context.Context, storeNameOrID, docName
 	}
 
 	// Search for document by display name
-	docs, err := c.ListDocuments(ctx, storeID)
-	if err != nil {
-		return "", err
-	}
+	return c.resolveIndexed(ctx, "document", docNameOrID, storeID, documentsKey(storeID), c.listDocumentCandidates(storeID))
+}
 
-	for _, doc := range docs {
-		if doc.DisplayName == docNameOrID {
-			return doc.Name, nil
+// listDocumentCandidates returns a name index listing of a store's documents
+func (c *Client) listDocumentCandidates(storeID string) func(context.Context) ([]Candidate, error) {
+	return func(ctx context.Context) ([]Candidate, error) {
+		docs, err := c.ListDocuments(ctx, storeID)
+		if err != nil {
+			return nil, err
+		}
+		candidates := make([]Candidate, 0, len(docs))
+		for _, doc := range docs {
+			candidates = append(candidates, Candidate{Name: doc.Name, DisplayName: doc.DisplayName, CreateTime: doc.CreateTime, contentHash: DocumentContentHash(doc)})
 		}
+		return candidates, nil
 	}
-
-	return "", fmt.Errorf("document not found in store %s: %s", storeID, docNameOrID)
 }
 
 // GetStoreNames returns a list of all store display names for completion.
@@ -215,23 +223,41 @@ func (c *Client) GetDocumentNames(ctx context.Context, storeID string) ([]string
 }
 
 func (c *Client) GetStore(ctx context.Context, name string) (*genai.FileSearchStore, error) {
+	if err := c.requireGeminiAPI("File Search store management"); err != nil {
+		return nil, err
+	}
 	return c.client.FileSearchStores.Get(ctx, name, nil)
 }
 
 func (c *Client) DeleteStore(ctx context.Context, name string, force bool) error {
+	if err := c.requireGeminiAPI("File Search store management"); err != nil {
+		return err
+	}
 	// Build optional delete config
 	cfg := &genai.DeleteFileSearchStoreConfig{}
 	if force {
 		cfg.Force = new(bool)
 		*cfg.Force = true
 	}
-	return c.client.FileSearchStores.Delete(ctx, name, cfg)
+	if err := c.client.FileSearchStores.Delete(ctx, name, cfg); err != nil {
+		return err
+	}
+	c.names.invalidate(namesStores, documentsKey(name))
+	return nil
 }
 
 func (c *Client) CreateStore(ctx context.Context, displayName string) (*genai.FileSearchStore, error) {
-	return c.client.FileSearchStores.Create(ctx, &genai.CreateFileSearchStoreConfig{
+	if err := c.requireGeminiAPI("File Search store management"); err != nil {
+		return nil, err
+	}
+	store, err := c.client.FileSearchStores.Create(ctx, &genai.CreateFileSearchStoreConfig{
 		DisplayName: displayName,
 	})
+	if err != nil {
+		return nil, err
+	}
+	c.names.invalidate(namesStores)
+	return store, nil
 }
 
 type UploadFileOptions struct {
@@ -242,63 +268,216 @@ type UploadFileOptions struct {
 	ChunkOverlap   int
 	Metadata       map[string]string
 	Quiet          bool
+	// OnDuplicate applies to store uploads; empty uploads without checking
+	OnDuplicate DuplicatePolicy
 }
 
 type ImportFileOptions struct {
-	Quiet bool
+	MaxChunkTokens int
+	ChunkOverlap   int
+	Metadata       map[string]string
+	Quiet          bool
+	// OnDuplicate applies to the imported document; empty imports without checking
+	OnDuplicate DuplicatePolicy
+}
+
+// chunkingConfig builds a white-space chunking config, or nil if neither value is set
+func chunkingConfig(maxChunkTokens, chunkOverlap int) *genai.ChunkingConfig {
+	if maxChunkTokens <= 0 && chunkOverlap <= 0 {
+		return nil
+	}
+	cfg := &genai.ChunkingConfig{WhiteSpaceConfig: &genai.WhiteSpaceConfig{}}
+	if maxChunkTokens > 0 {
+		maxTokens := int32(maxChunkTokens)
+		cfg.WhiteSpaceConfig.MaxTokensPerChunk = &maxTokens
+	}
+	if chunkOverlap > 0 {
+		overlapTokens := int32(chunkOverlap)
+		cfg.WhiteSpaceConfig.MaxOverlapTokens = &overlapTokens
+	}
+	return cfg
+}
+
+// customMetadata converts a key/value map to document custom metadata
+func customMetadata(metadata map[string]string) []*genai.CustomMetadata {
+	if len(metadata) == 0 {
+		return nil
+	}
+	out := make([]*genai.CustomMetadata, 0, len(metadata))
+	for key, value := range metadata {
+		out = append(out, &genai.CustomMetadata{
+			Key:         key,
+			StringValue: value,
+		})
+	}
+	return out
+}
+
+// extraMIMETypes covers common document extensions missing from the system MIME table
+var extraMIMETypes = map[string]string{
+	".md":       "text/markdown",
+	".markdown": "text/markdown",
+	".rst":      "text/x-rst",
+	".adoc":     "text/asciidoc",
+	".yaml":     "application/x-yaml",
+	".yml":      "application/x-yaml",
+	".go":       "text/x-go",
+	".py":       "text/x-python",
+	".ts":       "application/typescript",
+}
+
+// DetectMIMEType determines the MIME type of a local file from its extension,
+// falling back to content sniffing. Returns an empty string if unknown.
+func DetectMIMEType(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if t, ok := extraMIMETypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		if mediaType, _, err := mime.ParseMediaType(t); err == nil {
+			return mediaType
+		}
+		return t
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, _ := f.Read(buf)
+	if n == 0 {
+		return ""
+	}
+	t := http.DetectContentType(buf[:n])
+	if t == "application/octet-stream" {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(t)
+	if err != nil {
+		return t
+	}
+	return mediaType
+}
+
+// ValidateUpload checks a local file before any data is transferred, so that
+// oversized or unsupported files fail fast with a clear error. It returns the
+// MIME type to upload with: mimeType if set, otherwise the detected type.
+func ValidateUpload(path, mimeType string, toStore bool) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() == 0 {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+
+	limit := int64(constants.MaxFileUploadBytes)
+	if toStore {
+		limit = constants.MaxStoreUploadBytes
+	}
+	if info.Size() > limit {
+		return "", fmt.Errorf("%s is %s, which exceeds the %s per-file limit; use --split to upload it in parts",
+			filepath.Base(path), FormatBytes(info.Size()), FormatBytes(limit))
+	}
+
+	if mimeType == "" {
+		mimeType = DetectMIMEType(path)
+		if mimeType == "" {
+			return "", fmt.Errorf("could not determine the MIME type of %s; set it explicitly with --mime-type", filepath.Base(path))
+		}
+	} else if _, _, err := mime.ParseMediaType(mimeType); err != nil {
+		return "", fmt.Errorf("invalid MIME type %q: %w", mimeType, err)
+	}
+
+	if toStore && (strings.HasPrefix(mimeType, "audio/") || strings.HasPrefix(mimeType, "video/")) {
+		return "", fmt.Errorf("%s has MIME type %s, which File Search stores cannot index", filepath.Base(path), mimeType)
+	}
+
+	return mimeType, nil
+}
+
+// FormatBytes renders a byte count in human-readable binary units
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
 }
 
 // UploadFile uploads a file and optionally indexes it in a store.
 // It returns the created File (if no store) or nil (if store upload, as operation handles it).
-// For store uploads, it polls until completion.
+// For store uploads, it polls until completion and returns an error unless the
+// new document is active.
 func (c *Client) UploadFile(ctx context.Context, path string, opts *UploadFileOptions) (*genai.File, error) {
+	if err := c.requireGeminiAPI("the Files API"); err != nil {
+		return nil, err
+	}
 	if opts == nil {
 		opts = &UploadFileOptions{}
 	}
 
+	// Pre-flight checks before transferring any data
+	mimeType, err := ValidateUpload(path, opts.MIMEType, opts.StoreName != "")
+	if err != nil {
+		return nil, err
+	}
+
 	// If storeName is provided, use UploadToFileSearchStoreFromPath (direct)
 	// If not, just UploadFromPath (Files API only)
 
 	if opts.StoreName != "" {
+		// Documents carry their content hash so re-uploads under another
+		// name are recognised
+		hash, err := hashFile(path)
+		if err != nil {
+			return nil, err
+		}
+		displayName := opts.DisplayName
+		if displayName == "" {
+			displayName = filepath.Base(path)
+		}
+		replaced, err := c.checkDuplicates(ctx, opts.OnDuplicate, opts.StoreName, displayName, hash)
+		if err != nil {
+			return nil, err
+		}
+
 		if !opts.Quiet {
 			fmt.Printf("Uploading %s to store %s...\n", path, opts.StoreName)
 		}
 
+		// The display name is always set, so the document can be indexed
+		// under the name its duplicates were checked against
 		config := &genai.UploadToFileSearchStoreConfig{
-			DisplayName: opts.DisplayName,
-			MIMEType:    opts.MIMEType,
-		}
-
-		// Add chunking config if specified
-		if opts.MaxChunkTokens > 0 || opts.ChunkOverlap > 0 {
-			config.ChunkingConfig = &genai.ChunkingConfig{
-				WhiteSpaceConfig: &genai.WhiteSpaceConfig{},
-			}
-			if opts.MaxChunkTokens > 0 {
-				maxTokens := int32(opts.MaxChunkTokens)
-				config.ChunkingConfig.WhiteSpaceConfig.MaxTokensPerChunk = &maxTokens
-			}
-			if opts.ChunkOverlap > 0 {
-				overlapTokens := int32(opts.ChunkOverlap)
-				config.ChunkingConfig.WhiteSpaceConfig.MaxOverlapTokens = &overlapTokens
-			}
+			DisplayName: displayName,
+			MIMEType:    mimeType,
 		}
 
-		// Add metadata if specified
-		if len(opts.Metadata) > 0 {
-			config.CustomMetadata = make([]*genai.CustomMetadata, 0, len(opts.Metadata))
-			for key, value := range opts.Metadata {
-				config.CustomMetadata = append(config.CustomMetadata, &genai.CustomMetadata{
-					Key:         key,
-					StringValue: value,
-				})
-			}
-		}
+		// Add chunking config and metadata if specified
+		config.ChunkingConfig = chunkingConfig(opts.MaxChunkTokens, opts.ChunkOverlap)
+		config.CustomMetadata = customMetadata(withContentHash(opts.Metadata, hash))
 
 		op, err := c.client.FileSearchStores.UploadToFileSearchStoreFromPath(ctx, path, opts.StoreName, config)
 		if err != nil {
 			return nil, err
 		}
+		if c.onOperation != nil {
+			c.onOperation(op.Name, OperationTypeUpload, opts.StoreName)
+		}
+		var indexing Usage
+		if info, err := os.Stat(path); err == nil {
+			indexing.IndexingTokens = EstimateIndexingTokens(info.Size())
+		}
 
 		// Poll with optional progress indicator
 		startTime := time.Now()
@@ -317,19 +496,37 @@ func (c *Client) UploadFile(ctx context.Context, path string, opts *UploadFileOp
 				if !opts.Quiet {
 					fmt.Println() // New line before error
 				}
+				c.names.invalidate(documentsKey(opts.StoreName))
 				return nil, err
 			}
 		}
 		if !opts.Quiet {
-			fmt.Println("\n✓ Upload and index complete.")
+			fmt.Println()
+		}
+		if op.Error != nil {
+			return nil, fmt.Errorf("upload of %s failed: %s", path, operationErrorMessage(op.Error))
+		}
+		var docName string
+		if op.Response != nil {
+			docName = op.Response.DocumentName
 		}
-		return nil, nil
+		// Replaced copies are only deleted once the new document is usable
+		if err := c.checkDocumentActive(ctx, opts.StoreName, docName, displayName, hash); err != nil {
+			return nil, err
+		}
+		c.indexDocument(opts.StoreName, docName, displayName, hash)
+		if !opts.Quiet {
+			fmt.Println("✓ Upload and index complete.")
+			fmt.Printf("Estimated indexing tokens: %d\n", indexing.IndexingTokens)
+		}
+		c.reportUsage(ctx, UsageEvent{Operation: UsageIndex, Store: opts.StoreName, Usage: indexing})
+		return nil, c.replaceDuplicates(ctx, replaced)
 	}
 
 	// Just upload to Files API
 	config := &genai.UploadFileConfig{
 		DisplayName: opts.DisplayName,
-		MIMEType:    opts.MIMEType,
+		MIMEType:    mimeType,
 	}
 	// Note: metadata might not be supported for Files API uploads
 	// Only chunking config is for store uploads
@@ -338,6 +535,7 @@ func (c *Client) UploadFile(ctx context.Context, path string, opts *UploadFileOp
 	if err != nil {
 		return nil, err
 	}
+	c.names.invalidate(namesFiles)
 	return res, nil
 }
 
@@ -345,18 +543,47 @@ func (c *Client) UploadFile(ctx context.Context, path string, opts *UploadFileOp
 // fileID should be a file resource name (e.g., "files/abc123").
 // storeID should be a store resource name (e.g., "fileSearchStores/xyz789").
 func (c *Client) ImportFile(ctx context.Context, fileID, storeID string, opts *ImportFileOptions) error {
+	if err := c.requireGeminiAPI("File Search store management"); err != nil {
+		return err
+	}
 	if opts == nil {
 		opts = &ImportFileOptions{}
 	}
 
+	// The file's name and hash identify duplicates, and its size estimates
+	// indexing usage. Without a duplicate check the import doesn't depend on it.
+	file, fileErr := c.client.Files.Get(ctx, fileID, nil)
+	var replaced []Duplicate
+	var hash, displayName string
+	if fileErr == nil {
+		hash = FileContentHash(file)
+		displayName = file.DisplayName
+		checkName := displayName
+		if checkName == "" {
+			checkName = fileID
+		}
+		var err error
+		if replaced, err = c.checkDuplicates(ctx, opts.OnDuplicate, storeID, checkName, hash); err != nil {
+			return err
+		}
+	} else if opts.OnDuplicate != "" && opts.OnDuplicate != DuplicateKeep {
+		return fileErr
+	}
+
 	if !opts.Quiet {
 		fmt.Printf("Importing file %s into store %s...\n", fileID, storeID)
 	}
 
-	op, err := c.client.FileSearchStores.ImportFile(ctx, storeID, fileID, &genai.ImportFileConfig{})
+	op, err := c.client.FileSearchStores.ImportFile(ctx, storeID, fileID, &genai.ImportFileConfig{
+		ChunkingConfig: chunkingConfig(opts.MaxChunkTokens, opts.ChunkOverlap),
+		CustomMetadata: customMetadata(withContentHash(opts.Metadata, hash)),
+	})
 	if err != nil {
 		return err
 	}
+	if c.onOperation != nil {
+		c.onOperation(op.Name, OperationTypeImport, storeID)
+	}
 
 	// Poll operation until complete with optional progress indicator
 	startTime := time.Now()
@@ -376,16 +603,39 @@ func (c *Client) ImportFile(ctx context.Context, fileID, storeID string, opts *I
 			if !opts.Quiet {
 				fmt.Println() // New line before error
 			}
+			c.names.invalidate(documentsKey(storeID))
 			return err
 		}
 	}
 	if !opts.Quiet {
-		fmt.Println("\n✓ Import complete.")
+		fmt.Println()
 	}
-	return nil
+	if op.Error != nil {
+		return fmt.Errorf("import of %s failed: %s", fileID, operationErrorMessage(op.Error))
+	}
+	var docName string
+	if op.Response != nil {
+		docName = op.Response.DocumentName
+	}
+	// Imported documents take the file's display name
+	if err := c.checkDocumentActive(ctx, storeID, docName, displayName, hash); err != nil {
+		return err
+	}
+	c.indexDocument(storeID, docName, displayName, hash)
+	if !opts.Quiet {
+		fmt.Println("✓ Import complete.")
+	}
+	// The import response doesn't include the file, so use its size from above
+	if fileErr == nil && file.SizeBytes != nil {
+		c.reportUsage(ctx, UsageEvent{Operation: UsageIndex, Store: storeID, Usage: Usage{IndexingTokens: EstimateIndexingTokens(*file.SizeBytes)}})
+	}
+	return c.replaceDuplicates(ctx, replaced)
 }
 
 func (c *Client) ListFiles(ctx context.Context) ([]*genai.File, error) {
+	if err := c.requireGeminiAPI("the Files API"); err != nil {
+		return nil, err
+	}
 	resp, err := c.client.Files.List(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -405,10 +655,16 @@ func (c *Client) ListFiles(ctx context.Context) ([]*genai.File, error) {
 }
 
 func (c *Client) GetFile(ctx context.Context, name string) (*genai.File, error) {
+	if err := c.requireGeminiAPI("the Files API"); err != nil {
+		return nil, err
+	}
 	return c.client.Files.Get(ctx, name, nil)
 }
 
 func (c *Client) ListDocuments(ctx context.Context, storeName string) ([]*genai.Document, error) {
+	if err := c.requireGeminiAPI("File Search store management"); err != nil {
+		return nil, err
+	}
 	resp, err := c.client.FileSearchStores.Documents.List(ctx, storeName, nil)
 	if err != nil {
 		return nil, err
@@ -428,40 +684,54 @@ func (c *Client) ListDocuments(ctx context.Context, storeName string) ([]*genai.
 }
 
 func (c *Client) GetDocument(ctx context.Context, name string) (*genai.Document, error) {
+	if err := c.requireGeminiAPI("File Search store management"); err != nil {
+		return nil, err
+	}
 	return c.client.FileSearchStores.Documents.Get(ctx, name, nil)
 }
 
 func (c *Client) DeleteDocument(ctx context.Context, name string, force bool) error {
+	if err := c.requireGeminiAPI("File Search store management"); err != nil {
+		return err
+	}
 	cfg := &genai.DeleteDocumentConfig{}
 	if force {
 		cfg.Force = new(bool)
 		*cfg.Force = true
 	}
-	return c.client.FileSearchStores.Documents.Delete(ctx, name, cfg)
+	if err := c.client.FileSearchStores.Documents.Delete(ctx, name, cfg); err != nil {
+		return err
+	}
+	c.names.remove(documentsKey(documentStore(name)), name)
+	return nil
 }
 
 func (c *Client) DeleteFile(ctx context.Context, name string) error {
-	_, err := c.client.Files.Delete(ctx, name, nil)
-	return err
+	if err := c.requireGeminiAPI("the Files API"); err != nil {
+		return err
+	}
+	if _, err := c.client.Files.Delete(ctx, name, nil); err != nil {
+		return err
+	}
+	c.names.invalidate(namesFiles)
+	return nil
 }
 
-func (c *Client) Query(ctx context.Context, text string, storeName string, modelName string, metadataFilter string) (*genai.GenerateContentResponse, error) {
-	var config *genai.GenerateContentConfig
-
-	if storeName != "" {
-		fs := &genai.FileSearch{FileSearchStoreNames: []string{storeName}}
-		if metadataFilter != "" {
-			fs.MetadataFilter = metadataFilter
-		}
-		config = &genai.GenerateContentConfig{Tools: []*genai.Tool{{FileSearch: fs}}}
+// CountTokens returns the number of tokens the model uses to represent text
+func (c *Client) CountTokens(ctx context.Context, modelName, text string) (int, error) {
+	resp, err := c.client.Models.CountTokens(ctx, modelName, genai.Text(text), nil)
+	if err != nil {
+		return 0, err
 	}
-
-	return c.client.Models.GenerateContent(ctx, modelName, genai.Text(text), config)
+	return int(resp.TotalTokens), nil
 }
 
 // GetOperation retrieves the status of a long-running operation.
 // If operationType is empty, it will try both import and upload types.
 func (c *Client) GetOperation(ctx context.Context, operationName string, operationType OperationType) (*OperationStatus, error) {
+	if err := c.requireGeminiAPI("File Search store management"); err != nil {
+		return nil, err
+	}
 	// Validate operation name format
 	if !strings.HasPrefix(operationName, constants.StoreResourcePrefix) {
 		return nil, fmt.Errorf("invalid operation name: must start with '%s'", constants.StoreResourcePrefix)
@@ -487,6 +757,33 @@ func (c *Client) GetOperation(ctx context.Context, operationName string, operati
 	return c.getUploadOperation(ctx, operationName)
 }
 
+// operationErrorMessage returns the message of a failed operation's error
+func operationErrorMessage(e map[string]any) string {
+	if msg, ok := e["message"].(string); ok {
+		return msg
+	}
+	return fmt.Sprintf("%v", e)
+}
+
+// checkDocumentActive confirms that the document an upload or import
+// created was indexed. A failed document is still indexed by name, since it
+// is in the store until deleted.
+func (c *Client) checkDocumentActive(ctx context.Context, storeID, docName, displayName, hash string) error {
+	if docName == "" {
+		return fmt.Errorf("operation finished without a document in store %s", storeID)
+	}
+	doc, err := c.GetDocument(ctx, docName)
+	if err != nil {
+		c.names.invalidate(documentsKey(storeID))
+		return fmt.Errorf("failed to check document %s: %w", docName, err)
+	}
+	if doc.State != genai.DocumentStateActive {
+		c.indexDocument(storeID, docName, displayName, hash)
+		return fmt.Errorf("document %s is %s after indexing", docName, doc.State)
+	}
+	return nil
+}
+
 func (c *Client) getImportOperation(ctx context.Context, operationName string) (*OperationStatus, error) {
 	op := &genai.ImportFileOperation{Name: operationName}
 	result, err := c.client.Operations.GetImportFileOperation(ctx, op, nil)
@@ -503,11 +800,7 @@ func (c *Client) getImportOperation(ctx context.Context, operationName string) (
 
 	if result.Error != nil {
 		status.Failed = true
-		if msg, ok := result.Error["message"].(string); ok {
-			status.ErrorMessage = msg
-		} else {
-			status.ErrorMessage = fmt.Sprintf("%v", result.Error)
-		}
+		status.ErrorMessage = operationErrorMessage(result.Error)
 	}
 
 	if result.Response != nil {
@@ -534,11 +827,7 @@ func (c *Client) getUploadOperation(ctx context.Context, operationName string) (
 
 	if result.Error != nil {
 		status.Failed = true
-		if msg, ok := result.Error["message"].(string); ok {
-			status.ErrorMessage = msg
-		} else {
-			status.ErrorMessage = fmt.Sprintf("%v", result.Error)
-		}
+		status.ErrorMessage = operationErrorMessage(result.Error)
 	}
 
 	if result.Response != nil {
@@ -66,7 +66,7 @@ func TestIntegration_Query(t *testing.T) {
 		t.Skip("No stores available to test query")
 	}
 
-	resp, err := client.Query(ctx, "What is in this document?", stores[0].Name, "gemini-2.5-flash", "")
+	resp, err := client.Query(ctx, "What is in this document?", stores[0].Name, "gemini-2.5-flash", nil)
 	if err != nil {
 		t.Fatalf("Query failed: %v", err)
 	}
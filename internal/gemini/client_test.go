@@ -2,6 +2,8 @@ package gemini
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -655,3 +657,69 @@ func TestOperationStatusFailedState(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateUpload(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		mimeType string
+		toStore  bool
+		wantMIME string
+		wantErr  string
+	}{
+		{"markdown detected", write("notes.md", []byte("# hi")), "", true, "text/markdown", ""},
+		{"explicit MIME type kept", write("data.bin", []byte{0x00, 0x01}), "text/plain", true, "text/plain", ""},
+		{"missing file", filepath.Join(dir, "missing.txt"), "", true, "", "cannot read"},
+		{"directory", dir, "", true, "", "is a directory"},
+		{"empty file", write("empty.txt", nil), "", true, "", "is empty"},
+		{"unknown type", write("blob.zzz", []byte{0x00, 0x01, 0x02}), "", true, "", "--mime-type"},
+		{"invalid MIME type", write("x.txt", []byte("x")), "not a mime;;", true, "", "invalid MIME type"},
+		{"audio rejected for stores", write("song.mp3", []byte("ID3")), "", true, "", "cannot index"},
+		{"audio allowed for Files API", write("clip.mp3", []byte("ID3")), "", false, "audio/mpeg", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateUpload(tt.path, tt.mimeType, tt.toStore)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.wantMIME {
+				t.Errorf("expected MIME type %q, got %q", tt.wantMIME, got)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{512, "512 B"},
+		{1536, "1.5 KiB"},
+		{100 << 20, "100.0 MiB"},
+		{2 << 30, "2.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package gemini
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// ContentHashKey is the custom metadata key holding the hex SHA-256 of a
+// document's content, used to recognise re-uploads under another name
+const ContentHashKey = "content_sha256"
+
+// DuplicatePolicy decides what an upload does when the store already has a
+// document with the same display name or content
+type DuplicatePolicy string
+
+const (
+	// DuplicateSkip leaves the existing document and skips an upload whose
+	// content is already in the store. A new version under an existing name
+	// fails instead, since skipping it would silently keep the old content.
+	DuplicateSkip DuplicatePolicy = "skip"
+	// DuplicateReplace uploads, then deletes the existing documents, so the
+	// store is never without a copy
+	DuplicateReplace DuplicatePolicy = "replace"
+	// DuplicateKeep uploads another copy without checking
+	DuplicateKeep DuplicatePolicy = "keep"
+	// DuplicateError fails the upload
+	DuplicateError DuplicatePolicy = "error"
+)
+
+// DuplicatePolicies lists the accepted policy names
+var DuplicatePolicies = []string{string(DuplicateSkip), string(DuplicateReplace), string(DuplicateKeep), string(DuplicateError)}
+
+// ParseDuplicatePolicy validates a policy name. An empty name is DuplicateSkip.
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	p := DuplicatePolicy(strings.ToLower(strings.TrimSpace(s)))
+	switch p {
+	case "":
+		return DuplicateSkip, nil
+	case DuplicateSkip, DuplicateReplace, DuplicateKeep, DuplicateError:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid duplicate policy %q (valid: %s)", s, strings.Join(DuplicatePolicies, ", "))
+}
+
+// Duplicate is an existing document that matches an upload
+type Duplicate struct {
+	Document    string `json:"document"`
+	DisplayName string `json:"display_name"`
+	SameName    bool   `json:"same_name"`
+	SameContent bool   `json:"same_content"`
+}
+
+func (d Duplicate) String() string {
+	switch {
+	case d.SameName && d.SameContent:
+		return d.Document + " (same content)"
+	case d.SameName:
+		return d.Document + " (same name, different content)"
+	default:
+		return fmt.Sprintf("%s (same content, named %q)", d.Document, d.DisplayName)
+	}
+}
+
+// ErrDuplicateSkipped is wrapped by the *DuplicateDocumentError of uploads
+// skipped under DuplicateSkip, so callers can tell them from failures
+var ErrDuplicateSkipped = errors.New("duplicate skipped")
+
+// DuplicateDocumentError is returned when an upload matches existing
+// documents under DuplicateSkip or DuplicateError
+type DuplicateDocumentError struct {
+	Store       string      `json:"store"`
+	DisplayName string      `json:"display_name"`
+	Duplicates  []Duplicate `json:"duplicates"`
+	Skipped     bool        `json:"skipped"`
+}
+
+func (e *DuplicateDocumentError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s already exists in store %s as ", e.DisplayName, e.Store)
+	for i, d := range e.Duplicates {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(d.String())
+	}
+	if !e.Skipped && !slices.ContainsFunc(e.Duplicates, func(d Duplicate) bool { return d.SameContent }) {
+		b.WriteString(" (use the replace duplicate policy to update it, or keep to add another copy)")
+	}
+	return b.String()
+}
+
+func (e *DuplicateDocumentError) Unwrap() error {
+	if e.Skipped {
+		return ErrDuplicateSkipped
+	}
+	return nil
+}
+
+// FindDuplicates returns the documents in a store named displayName or whose
+// content hash is contentHash. An empty hash matches by name only. Documents
+// are read from the client's name index, so a batch lists the store once.
+func (c *Client) FindDuplicates(ctx context.Context, storeID, displayName, contentHash string) ([]Duplicate, error) {
+	docs, _, err := c.names.lookup(ctx, documentsKey(storeID), c.listDocumentCandidates(storeID))
+	if err != nil {
+		return nil, err
+	}
+	var dups []Duplicate
+	for _, doc := range docs {
+		d := Duplicate{
+			Document:    doc.Name,
+			DisplayName: doc.DisplayName,
+			SameName:    doc.DisplayName == displayName,
+			SameContent: contentHash != "" && doc.contentHash == contentHash,
+		}
+		if d.SameName || d.SameContent {
+			dups = append(dups, d)
+		}
+	}
+	return dups, nil
+}
+
+// checkDuplicates applies policy to the documents matching an upload. It
+// returns the documents to delete once the upload succeeds.
+func (c *Client) checkDuplicates(ctx context.Context, policy DuplicatePolicy, storeID, displayName, contentHash string) ([]Duplicate, error) {
+	if policy == "" || policy == DuplicateKeep {
+		return nil, nil
+	}
+	dups, err := c.FindDuplicates(ctx, storeID, displayName, contentHash)
+	if err != nil || len(dups) == 0 {
+		return nil, err
+	}
+	switch policy {
+	case DuplicateReplace:
+		return dups, nil
+	case DuplicateSkip:
+		// Only content already in the store is skipped. A name match alone,
+		// or against a document without a hash, may be a new version.
+		skip := slices.ContainsFunc(dups, func(d Duplicate) bool { return d.SameContent })
+		return nil, &DuplicateDocumentError{Store: storeID, DisplayName: displayName, Duplicates: dups, Skipped: skip}
+	default:
+		return nil, &DuplicateDocumentError{Store: storeID, DisplayName: displayName, Duplicates: dups}
+	}
+}
+
+// replaceDuplicates deletes the documents an upload replaced
+func (c *Client) replaceDuplicates(ctx context.Context, dups []Duplicate) error {
+	for _, d := range dups {
+		if err := c.DeleteDocument(ctx, d.Document, true); err != nil {
+			return fmt.Errorf("uploaded, but failed to delete replaced document %s: %w", d.Document, err)
+		}
+	}
+	return nil
+}
+
+// DocumentContentHash returns the content hash stored in a document's
+// metadata, or "" if it has none
+func DocumentContentHash(doc *genai.Document) string {
+	for _, m := range doc.CustomMetadata {
+		if m != nil && m.Key == ContentHashKey {
+			return strings.ToLower(m.StringValue)
+		}
+	}
+	return ""
+}
+
+// FileContentHash returns the SHA-256 the Files API reports for a file as
+// hex, or "" if it has none. The API sends the hex digest base64-encoded (as
+// the SDK's upload example decodes it); base64 of the raw digest and plain
+// hex are accepted too.
+func FileContentHash(f *genai.File) string {
+	if f == nil || f.Sha256Hash == "" {
+		return ""
+	}
+	if raw, err := base64.StdEncoding.DecodeString(f.Sha256Hash); err == nil {
+		switch {
+		case len(raw) == sha256.Size:
+			return hex.EncodeToString(raw)
+		case isHexDigest(string(raw)):
+			return strings.ToLower(string(raw))
+		}
+	}
+	if isHexDigest(f.Sha256Hash) {
+		return strings.ToLower(f.Sha256Hash)
+	}
+	return ""
+}
+
+// isHexDigest reports whether s is a hex-encoded SHA-256 digest
+func isHexDigest(s string) bool {
+	if len(s) != 2*sha256.Size {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// hashFile returns the hex SHA-256 of the file at path
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// withContentHash adds the content hash to upload metadata unless it is
+// empty or already set
+func withContentHash(metadata map[string]string, hash string) map[string]string {
+	if hash == "" {
+		return metadata
+	}
+	if _, ok := metadata[ContentHashKey]; ok {
+		return metadata
+	}
+	merged := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	merged[ContentHashKey] = hash
+	return merged
+}
+
+// Which document of a duplicate group `store dedupe` keeps
+const (
+	KeepNewest = "newest"
+	KeepOldest = "oldest"
+)
+
+// DuplicateGroup is a set of documents with the same display name, size,
+// MIME type and content hash, split into the one to keep and the rest
+type DuplicateGroup struct {
+	DisplayName string      `json:"display_name"`
+	SizeBytes   int64       `json:"size_bytes"`
+	MIMEType    string      `json:"mime_type"`
+	ContentHash string      `json:"content_sha256,omitempty"`
+	Keep        Candidate   `json:"keep"`
+	Delete      []Candidate `json:"delete"`
+}
+
+// GroupDuplicates returns the groups of two or more identical documents,
+// keeping the newest or oldest of each, preferring active documents over
+// failed or pending ones. Groups are ordered by display name.
+func GroupDuplicates(docs []*genai.Document, keep string) ([]DuplicateGroup, error) {
+	if keep != KeepNewest && keep != KeepOldest {
+		return nil, fmt.Errorf("invalid keep policy %q (valid: %s, %s)", keep, KeepNewest, KeepOldest)
+	}
+
+	type groupKey struct {
+		name, mimeType, hash string
+		size                 int64
+	}
+	var order []groupKey
+	groups := make(map[groupKey][]*genai.Document)
+	for _, doc := range docs {
+		k := groupKey{doc.DisplayName, doc.MIMEType, DocumentContentHash(doc), doc.SizeBytes}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], doc)
+	}
+
+	var result []DuplicateGroup
+	for _, k := range order {
+		members := groups[k]
+		if len(members) < 2 {
+			continue
+		}
+		// Oldest first, with the name breaking ties so the choice is stable
+		sort.SliceStable(members, func(i, j int) bool {
+			if !members[i].CreateTime.Equal(members[j].CreateTime) {
+				return members[i].CreateTime.Before(members[j].CreateTime)
+			}
+			return members[i].Name < members[j].Name
+		})
+		if keep == KeepNewest {
+			slices.Reverse(members)
+		}
+		// A failed or pending copy is never kept over an active one, so a
+		// broken re-upload can't cost the store its only searchable copy
+		sort.SliceStable(members, func(i, j int) bool {
+			return members[i].State == genai.DocumentStateActive && members[j].State != genai.DocumentStateActive
+		})
+		g := DuplicateGroup{DisplayName: k.name, SizeBytes: k.size, MIMEType: k.mimeType, ContentHash: k.hash}
+		for i, doc := range members {
+			c := Candidate{Name: doc.Name, DisplayName: doc.DisplayName, CreateTime: doc.CreateTime}
+			if i == 0 {
+				g.Keep = c
+			} else {
+				g.Delete = append(g.Delete, c)
+			}
+		}
+		result = append(result, g)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].DisplayName < result[j].DisplayName })
+	return result, nil
+}
@@ -0,0 +1,405 @@
+package gemini
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	for in, want := range map[string]DuplicatePolicy{"": DuplicateSkip, "Replace": DuplicateReplace, " keep ": DuplicateKeep, "error": DuplicateError} {
+		if got, err := ParseDuplicatePolicy(in); err != nil || got != want {
+			t.Errorf("ParseDuplicatePolicy(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseDuplicatePolicy("overwrite"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
+func TestFileContentHash(t *testing.T) {
+	const hex = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	for in, want := range map[string]string{
+		// The Files API's form: base64 of the hex digest
+		base64.StdEncoding.EncodeToString([]byte(hex)): hex,
+		"LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=": hex,
+		strings.ToUpper(hex):                           hex,
+		"":                                             "",
+		"not-a-hash":                                   "",
+	} {
+		if got := FileContentHash(&genai.File{Sha256Hash: in}); got != want {
+			t.Errorf("FileContentHash(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// docsTransport lists a fixed set of documents and records deletions
+type docsTransport struct {
+	mu      sync.Mutex
+	docs    string
+	deleted []string
+}
+
+func (d *docsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	body := `{}`
+	switch {
+	case req.Method == http.MethodDelete:
+		d.deleted = append(d.deleted, strings.TrimPrefix(req.URL.Path, "/v1beta/"))
+	case strings.HasSuffix(req.URL.Path, "/documents"):
+		body = d.docs
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestCheckDuplicates(t *testing.T) {
+	ctx := context.Background()
+	const store = "fileSearchStores/s"
+	transport := &docsTransport{docs: `{"documents":[
+		{"name":"fileSearchStores/s/documents/a","displayName":"datasheet.pdf","customMetadata":[{"key":"content_sha256","stringValue":"aaa"}]},
+		{"name":"fileSearchStores/s/documents/b","displayName":"datasheet-v2.pdf","customMetadata":[{"key":"content_sha256","stringValue":"bbb"}]},
+		{"name":"fileSearchStores/s/documents/c","displayName":"other.pdf"}
+	]}`}
+	client, err := NewClientWithOptions(ctx, ClientOptions{APIKey: "k", HTTPClient: &http.Client{Transport: transport}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dups, err := client.FindDuplicates(ctx, store, "datasheet.pdf", "bbb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dups) != 2 || !dups[0].SameName || dups[0].SameContent || !dups[1].SameContent || dups[1].SameName {
+		t.Fatalf("unexpected duplicates %+v", dups)
+	}
+
+	if replaced, err := client.checkDuplicates(ctx, DuplicateKeep, store, "datasheet.pdf", "bbb"); err != nil || replaced != nil {
+		t.Errorf("keep: %v, %v", replaced, err)
+	}
+	if replaced, err := client.checkDuplicates(ctx, DuplicateSkip, store, "new.pdf", "ccc"); err != nil || replaced != nil {
+		t.Errorf("no duplicates: %v, %v", replaced, err)
+	}
+
+	_, err = client.checkDuplicates(ctx, DuplicateSkip, store, "datasheet.pdf", "aaa")
+	var dup *DuplicateDocumentError
+	if !errors.As(err, &dup) || !errors.Is(err, ErrDuplicateSkipped) {
+		t.Fatalf("skip: expected a skipped duplicate error, got %v", err)
+	}
+	if want := "datasheet.pdf already exists in store fileSearchStores/s as fileSearchStores/s/documents/a (same content)"; err.Error() != want {
+		t.Errorf("skip message = %q, want %q", err.Error(), want)
+	}
+
+	// A new version under an existing name fails rather than being skipped
+	_, err = client.checkDuplicates(ctx, DuplicateSkip, store, "datasheet.pdf", "ccc")
+	if !errors.As(err, &dup) || errors.Is(err, ErrDuplicateSkipped) {
+		t.Fatalf("skip new version: expected an unskipped duplicate error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "(same name, different content)") || !strings.Contains(err.Error(), "replace duplicate policy") {
+		t.Errorf("skip new version message = %q", err.Error())
+	}
+	// So does a name match against a document without a hash
+	_, err = client.checkDuplicates(ctx, DuplicateSkip, store, "other.pdf", "ccc")
+	if !errors.As(err, &dup) || errors.Is(err, ErrDuplicateSkipped) {
+		t.Fatalf("skip unhashed: expected an unskipped duplicate error, got %v", err)
+	}
+
+	_, err = client.checkDuplicates(ctx, DuplicateError, store, "datasheet.pdf", "bbb")
+	if !errors.As(err, &dup) || errors.Is(err, ErrDuplicateSkipped) {
+		t.Fatalf("error: expected an unskipped duplicate error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `documents/b (same content, named "datasheet-v2.pdf")`) {
+		t.Errorf("error message = %q", err.Error())
+	}
+
+	replaced, err := client.checkDuplicates(ctx, DuplicateReplace, store, "datasheet.pdf", "bbb")
+	if err != nil || len(replaced) != 2 {
+		t.Fatalf("replace: %v, %v", replaced, err)
+	}
+	if len(transport.deleted) != 0 {
+		t.Fatal("replace deleted documents before the upload")
+	}
+	if err := client.replaceDuplicates(ctx, replaced); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(transport.deleted, ",") != "fileSearchStores/s/documents/a,fileSearchStores/s/documents/b" {
+		t.Errorf("deleted %v", transport.deleted)
+	}
+}
+
+func TestWithContentHash(t *testing.T) {
+	in := map[string]string{"team": "hw"}
+	out := withContentHash(in, "abc")
+	if out[ContentHashKey] != "abc" || out["team"] != "hw" || len(in) != 1 {
+		t.Errorf("withContentHash() = %v, input %v", out, in)
+	}
+	if out := withContentHash(map[string]string{ContentHashKey: "mine"}, "abc"); out[ContentHashKey] != "mine" {
+		t.Errorf("expected an explicit hash to win, got %v", out)
+	}
+	if out := withContentHash(nil, ""); out != nil {
+		t.Errorf("expected no metadata without a hash, got %v", out)
+	}
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 0, 0, 0, 0, time.UTC) }
+	hash := func(h string) []*genai.CustomMetadata {
+		return []*genai.CustomMetadata{{Key: ContentHashKey, StringValue: h}}
+	}
+	docs := []*genai.Document{
+		{Name: "d/1", DisplayName: "spec.pdf", SizeBytes: 10, MIMEType: "application/pdf", CreateTime: day(1), CustomMetadata: hash("aaa")},
+		{Name: "d/2", DisplayName: "spec.pdf", SizeBytes: 10, MIMEType: "application/pdf", CreateTime: day(3), CustomMetadata: hash("aaa")},
+		{Name: "d/3", DisplayName: "spec.pdf", SizeBytes: 10, MIMEType: "application/pdf", CreateTime: day(2), CustomMetadata: hash("aaa")},
+		// Same name but different content
+		{Name: "d/4", DisplayName: "spec.pdf", SizeBytes: 10, MIMEType: "application/pdf", CreateTime: day(4), CustomMetadata: hash("bbb")},
+		// Unhashed copies group by name, size and type
+		{Name: "d/5", DisplayName: "notes.md", SizeBytes: 5, MIMEType: "text/markdown", CreateTime: day(1)},
+		{Name: "d/6", DisplayName: "notes.md", SizeBytes: 5, MIMEType: "text/markdown", CreateTime: day(2)},
+		{Name: "d/7", DisplayName: "notes.md", SizeBytes: 6, MIMEType: "text/markdown", CreateTime: day(3)},
+	}
+
+	groups, err := GroupDuplicates(docs, KeepNewest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[0].DisplayName != "notes.md" || groups[1].DisplayName != "spec.pdf" {
+		t.Fatalf("unexpected groups %+v", groups)
+	}
+	spec := groups[1]
+	if spec.Keep.Name != "d/2" || len(spec.Delete) != 2 || spec.Delete[0].Name != "d/3" || spec.Delete[1].Name != "d/1" || spec.ContentHash != "aaa" {
+		t.Errorf("keep newest: %+v", spec)
+	}
+
+	groups, _ = GroupDuplicates(docs, KeepOldest)
+	if groups[1].Keep.Name != "d/1" || groups[0].Keep.Name != "d/5" || groups[0].Delete[0].Name != "d/6" {
+		t.Errorf("keep oldest: %+v", groups)
+	}
+
+	if _, err := GroupDuplicates(docs, "first"); err == nil {
+		t.Error("expected an error for an unknown keep policy")
+	}
+}
+
+func TestGroupDuplicatesPrefersActive(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 0, 0, 0, 0, time.UTC) }
+	doc := func(name string, d int, state genai.DocumentState) *genai.Document {
+		return &genai.Document{Name: name, DisplayName: "spec.pdf", SizeBytes: 10, MIMEType: "application/pdf", CreateTime: day(d), State: state}
+	}
+	docs := []*genai.Document{
+		doc("d/old", 1, genai.DocumentStateActive),
+		doc("d/failed", 3, genai.DocumentStateFailed),
+		doc("d/pending", 2, genai.DocumentStatePending),
+	}
+	for _, keep := range []string{KeepNewest, KeepOldest} {
+		groups, err := GroupDuplicates(docs, keep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(groups) != 1 || groups[0].Keep.Name != "d/old" || len(groups[0].Delete) != 2 {
+			t.Errorf("keep %s: %+v", keep, groups)
+		}
+	}
+
+	// Without an active copy the keep policy decides
+	groups, _ := GroupDuplicates(docs[1:], KeepNewest)
+	if len(groups) != 1 || groups[0].Keep.Name != "d/failed" {
+		t.Errorf("no active copy: %+v", groups)
+	}
+}
+
+// importTransport serves files, imports them as documents and counts the
+// store's document listings
+type importTransport struct {
+	mu    sync.Mutex
+	lists int
+}
+
+func (d *importTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	body := `{}`
+	path := strings.TrimPrefix(req.URL.Path, "/v1beta/")
+	switch {
+	case strings.Contains(path, "/documents/") && req.Method == http.MethodGet:
+		body = fmt.Sprintf(`{"name":"%s","state":"STATE_ACTIVE"}`, path)
+	case strings.HasSuffix(path, "/documents"):
+		d.lists++
+		body = `{"documents":[{"name":"fileSearchStores/s/documents/old","displayName":"old.pdf"}]}`
+	case strings.HasPrefix(path, "files/"):
+		id := strings.TrimPrefix(path, "files/")
+		hash := sha256.Sum256([]byte(id))
+		body = fmt.Sprintf(`{"name":"files/%s","displayName":"%s.pdf","sha256Hash":"%x"}`, id, id, hash)
+	case strings.HasSuffix(path, ":importFile"):
+		var in struct {
+			FileName string `json:"fileName"`
+		}
+		json.NewDecoder(req.Body).Decode(&in)
+		body = fmt.Sprintf(`{"name":"op","done":true,"response":{"documentName":"fileSearchStores/s/documents/%s"}}`, strings.TrimPrefix(in.FileName, "files/"))
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestImportFileUsesNameIndex(t *testing.T) {
+	ctx := context.Background()
+	const store = "fileSearchStores/s"
+	transport := &importTransport{}
+	client, err := NewClientWithOptions(ctx, ClientOptions{APIKey: "k", HTTPClient: &http.Client{Transport: transport}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			if err := client.ImportFile(ctx, fmt.Sprintf("files/f%d", i), store, &ImportFileOptions{Quiet: true, OnDuplicate: DuplicateSkip}); err != nil {
+				t.Errorf("import f%d: %v", i, err)
+			}
+		})
+	}
+	wg.Wait()
+	if transport.lists != 1 {
+		t.Fatalf("20 imports listed documents %d times, want 1", transport.lists)
+	}
+
+	// Imported documents are in the index without listing again
+	if err := client.ImportFile(ctx, "files/f3", store, &ImportFileOptions{Quiet: true, OnDuplicate: DuplicateSkip}); !errors.Is(err, ErrDuplicateSkipped) {
+		t.Errorf("re-import: expected a skip, got %v", err)
+	}
+	if name, err := client.ResolveDocumentName(ctx, store, "f3.pdf"); err != nil || name != store+"/documents/f3" {
+		t.Errorf("ResolveDocumentName() = %q, %v", name, err)
+	}
+
+	// So are deletions
+	if err := client.DeleteDocument(ctx, store+"/documents/f3", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ResolveDocumentName(ctx, store, "f3.pdf"); err == nil {
+		t.Error("expected the deleted document not to resolve")
+	}
+	if transport.lists != 1 {
+		t.Errorf("listed documents %d times, want 1", transport.lists)
+	}
+}
+
+// replaceTransport serves a store holding an older doc.txt, and uploads or
+// imports a new one whose operation and document end as configured
+type replaceTransport struct {
+	mu      sync.Mutex
+	opError bool
+	state   genai.DocumentState
+	deleted []string
+}
+
+func (d *replaceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	const newDoc = "fileSearchStores/s/documents/new"
+	op := fmt.Sprintf(`{"name":"op","done":true,"response":{"documentName":"%s"}}`, newDoc)
+	if d.opError {
+		op = `{"name":"op","done":true,"error":{"code":3,"message":"unsupported file"}}`
+	}
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	body := `{}`
+	path := strings.TrimPrefix(req.URL.Path, "/v1beta/")
+	switch {
+	case req.Header.Get("X-Goog-Upload-Command") == "start":
+		header.Set("X-Goog-Upload-Url", "https://generativelanguage.googleapis.com/upload/session")
+	case strings.Contains(req.Header.Get("X-Goog-Upload-Command"), "finalize"):
+		header.Set("X-Goog-Upload-Status", "final")
+		body = op
+	case req.Method == http.MethodDelete:
+		d.deleted = append(d.deleted, path)
+	case path == newDoc:
+		body = fmt.Sprintf(`{"name":"%s","state":"%s"}`, newDoc, d.state)
+	case strings.HasSuffix(path, "/documents"):
+		body = `{"documents":[{"name":"fileSearchStores/s/documents/old","displayName":"doc.txt","state":"STATE_ACTIVE"}]}`
+	case path == "files/doc":
+		body = `{"name":"files/doc","displayName":"doc.txt","sizeBytes":"11"}`
+	case strings.HasSuffix(path, ":importFile"):
+		body = op
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestReplaceKeepsOldCopyUntilActive(t *testing.T) {
+	ctx := context.Background()
+	const store = "fileSearchStores/s"
+	path := filepath.Join(t.TempDir(), "doc.txt")
+	os.WriteFile(path, []byte("new version"), 0o600)
+
+	tests := []struct {
+		name    string
+		opError bool
+		state   genai.DocumentState
+		wantErr string
+	}{
+		{"operation error", true, "", "unsupported file"},
+		{"failed document", false, genai.DocumentStateFailed, "STATE_FAILED"},
+		{"active document", false, genai.DocumentStateActive, ""},
+	}
+	for _, tt := range tests {
+		for _, op := range []string{"upload", "import"} {
+			t.Run(tt.name+"/"+op, func(t *testing.T) {
+				var usage int
+				transport := &replaceTransport{opError: tt.opError, state: tt.state}
+				client, err := NewClientWithOptions(ctx, ClientOptions{
+					APIKey:     "k",
+					HTTPClient: &http.Client{Transport: transport},
+					OnUsage:    func(context.Context, UsageEvent) { usage++ },
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if op == "upload" {
+					_, err = client.UploadFile(ctx, path, &UploadFileOptions{StoreName: store, Quiet: true, OnDuplicate: DuplicateReplace})
+				} else {
+					err = client.ImportFile(ctx, "files/doc", store, &ImportFileOptions{Quiet: true, OnDuplicate: DuplicateReplace})
+				}
+				if tt.wantErr == "" {
+					if err != nil {
+						t.Fatal(err)
+					}
+					if len(transport.deleted) != 1 || transport.deleted[0] != store+"/documents/old" || usage != 1 {
+						t.Errorf("expected the old copy replaced and usage recorded, got %v and %d usage events", transport.deleted, usage)
+					}
+					return
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				if len(transport.deleted) != 0 || usage != 0 {
+					t.Errorf("expected nothing deleted or recorded, got deletions %v and %d usage events", transport.deleted, usage)
+				}
+			})
+		}
+	}
+}
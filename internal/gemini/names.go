@@ -0,0 +1,203 @@
+package gemini
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Name index keys. Documents are keyed per store by documentsKey.
+const (
+	namesStores    = "stores"
+	namesFiles     = "files"
+	namesDocuments = "docs:"
+)
+
+// relistAfter is how old a listing must be before a name missing from it
+// lists again, so a batch full of unknown names doesn't list once per name
+const relistAfter = 5 * time.Second
+
+// nameIndex caches the display names listed for resolution, so resolving
+// many names in one session lists each collection once. Concurrent lookups
+// of the same key share a single listing.
+type nameIndex struct {
+	// ttl is how long a listing is used; zero keeps it until invalidated
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]*nameEntry
+}
+
+type nameEntry struct {
+	ready      chan struct{}
+	listed     time.Time
+	candidates []Candidate
+	err        error
+}
+
+func newNameIndex(ttl time.Duration) *nameIndex {
+	return &nameIndex{ttl: ttl, now: time.Now, entries: make(map[string]*nameEntry)}
+}
+
+// lookup returns the candidates cached for key, calling list on a miss. It
+// also returns when they were listed.
+func (x *nameIndex) lookup(ctx context.Context, key string, list func(context.Context) ([]Candidate, error)) ([]Candidate, time.Time, error) {
+	if x == nil {
+		candidates, err := list(ctx)
+		return candidates, time.Now(), err
+	}
+
+	x.mu.Lock()
+	e, ok := x.entries[key]
+	if ok && e.loaded() && (e.err != nil || x.expired(e)) {
+		ok = false
+	}
+	if !ok {
+		e = &nameEntry{ready: make(chan struct{})}
+		x.entries[key] = e
+		x.mu.Unlock()
+
+		e.candidates, e.err = list(ctx)
+		e.listed = x.now()
+		close(e.ready)
+		if e.err != nil {
+			x.forget(key, e)
+		}
+		return e.candidates, e.listed, e.err
+	}
+	x.mu.Unlock()
+
+	select {
+	case <-e.ready:
+		return e.candidates, e.listed, e.err
+	case <-ctx.Done():
+		return nil, time.Time{}, ctx.Err()
+	}
+}
+
+// stale reports whether a listing made at listed is old enough to list again
+// when a name is missing from it
+func (x *nameIndex) stale(listed time.Time) bool {
+	return x != nil && x.now().Sub(listed) >= relistAfter
+}
+
+// invalidate drops the listings whose keys start with any of the prefixes
+func (x *nameIndex) invalidate(prefixes ...string) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for key := range x.entries {
+		for _, p := range prefixes {
+			if strings.HasPrefix(key, p) {
+				delete(x.entries, key)
+				break
+			}
+		}
+	}
+}
+
+// add records a resource the client created in the listing cached for key
+func (x *nameIndex) add(key string, c Candidate) {
+	x.update(key, func(candidates []Candidate) []Candidate {
+		candidates = slices.DeleteFunc(candidates, func(old Candidate) bool { return old.Name == c.Name })
+		return append(candidates, c)
+	})
+}
+
+// remove drops a resource the client deleted from the listing cached for key
+func (x *nameIndex) remove(key, name string) {
+	x.update(key, func(candidates []Candidate) []Candidate {
+		return slices.DeleteFunc(candidates, func(old Candidate) bool { return old.Name == name })
+	})
+}
+
+// update replaces the listing cached for key with edit applied to a copy of
+// it. A listing still in flight may or may not see the change, so it is
+// dropped instead.
+func (x *nameIndex) update(key string, edit func([]Candidate) []Candidate) {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	e, ok := x.entries[key]
+	switch {
+	case !ok:
+	case !e.loaded():
+		delete(x.entries, key)
+	case e.err == nil:
+		// Entries are replaced rather than edited, since lookups read them
+		// without the lock
+		ready := make(chan struct{})
+		close(ready)
+		x.entries[key] = &nameEntry{ready: ready, listed: e.listed, candidates: edit(slices.Clone(e.candidates))}
+	}
+}
+
+// forget drops e unless it has already been replaced
+func (x *nameIndex) forget(key string, e *nameEntry) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.entries[key] == e {
+		delete(x.entries, key)
+	}
+}
+
+func (x *nameIndex) expired(e *nameEntry) bool {
+	return x.ttl > 0 && x.now().Sub(e.listed) >= x.ttl
+}
+
+func (e *nameEntry) loaded() bool {
+	select {
+	case <-e.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// resolveIndexed resolves ref against the listing cached under key. A name
+// missing from an older listing lists again once, in case it was created
+// since.
+func (c *Client) resolveIndexed(ctx context.Context, kind, ref, scope, key string, list func(context.Context) ([]Candidate, error)) (string, error) {
+	candidates, listed, err := c.names.lookup(ctx, key, list)
+	if err != nil {
+		return "", err
+	}
+	if len(matchName(ref, candidates)) == 0 && c.names.stale(listed) {
+		c.names.invalidate(key)
+		if candidates, _, err = c.names.lookup(ctx, key, list); err != nil {
+			return "", err
+		}
+	}
+	return c.resolve(kind, ref, scope, candidates)
+}
+
+// indexDocument records a document the client created in its store's
+// listing, so later resolutions and duplicate checks in the session see it
+// without listing again. Without its name the listing is dropped instead.
+func (c *Client) indexDocument(store, name, displayName, contentHash string) {
+	key := documentsKey(store)
+	if name == "" || displayName == "" {
+		c.names.invalidate(key)
+		return
+	}
+	c.names.add(key, Candidate{Name: name, DisplayName: displayName, CreateTime: time.Now(), contentHash: contentHash})
+}
+
+// documentsKey returns the name index key of a store's documents. The
+// trailing slash keeps invalidating one store from matching another whose
+// name it prefixes.
+func documentsKey(store string) string {
+	return namesDocuments + store + "/"
+}
+
+// documentStore returns the store resource name a document belongs to
+func documentStore(docName string) string {
+	store, _, _ := strings.Cut(docName, "/documents/")
+	return store
+}
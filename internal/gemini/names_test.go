@@ -0,0 +1,148 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNameIndex(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	x := newNameIndex(time.Minute)
+	x.now = func() time.Time { return now }
+
+	var lists atomic.Int32
+	release := make(chan struct{})
+	list := func(context.Context) ([]Candidate, error) {
+		lists.Add(1)
+		<-release
+		return []Candidate{{Name: "files/a", DisplayName: "a.pdf"}}, nil
+	}
+
+	// Concurrent lookups share one listing
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if got, _, err := x.lookup(ctx, namesFiles, list); err != nil || len(got) != 1 {
+				t.Errorf("lookup() = %v, %v", got, err)
+			}
+		})
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := lists.Load(); n != 1 {
+		t.Errorf("listed %d times, want 1", n)
+	}
+
+	x.invalidate(namesStores, documentsKey("fileSearchStores/a"))
+	x.lookup(ctx, namesFiles, list)
+	if n := lists.Load(); n != 1 {
+		t.Errorf("unrelated invalidation: listed %d times, want 1", n)
+	}
+	x.invalidate(namesFiles)
+	x.lookup(ctx, namesFiles, list)
+	if n := lists.Load(); n != 2 {
+		t.Errorf("after invalidate: listed %d times, want 2", n)
+	}
+
+	now = now.Add(time.Minute)
+	x.lookup(ctx, namesFiles, list)
+	if n := lists.Load(); n != 3 {
+		t.Errorf("after ttl: listed %d times, want 3", n)
+	}
+
+	// Failed listings are not kept
+	failing := func(context.Context) ([]Candidate, error) {
+		lists.Add(1)
+		return nil, fmt.Errorf("unavailable")
+	}
+	x.lookup(ctx, namesStores, failing)
+	x.lookup(ctx, namesStores, failing)
+	if n := lists.Load(); n != 5 {
+		t.Errorf("after errors: listed %d times, want 5", n)
+	}
+}
+
+func TestDocumentsKey(t *testing.T) {
+	x := newNameIndex(0)
+	x.entries[documentsKey("fileSearchStores/ab")] = &nameEntry{}
+	x.entries[documentsKey("fileSearchStores/abc")] = &nameEntry{}
+	x.invalidate(documentsKey(documentStore("fileSearchStores/ab/documents/d1")))
+	if len(x.entries) != 1 || x.entries[documentsKey("fileSearchStores/abc")] == nil {
+		t.Errorf("expected only fileSearchStores/ab to be invalidated, got %v", x.entries)
+	}
+}
+
+// filesTransport lists a fixed set of files and counts the listings
+type filesTransport struct {
+	mu    sync.Mutex
+	files string
+	lists int
+}
+
+func (f *filesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body := `{}`
+	if req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/files") {
+		f.lists++
+		body = f.files
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestResolveFileNameCached(t *testing.T) {
+	ctx := context.Background()
+	transport := &filesTransport{files: `{"files":[{"name":"files/a","displayName":"a.pdf"},{"name":"files/b","displayName":"b.pdf"}]}`}
+	client, err := NewClientWithOptions(ctx, ClientOptions{APIKey: "k", HTTPClient: &http.Client{Transport: transport}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 200 {
+		wg.Go(func() {
+			name := []string{"a.pdf", "b.pdf"}[i%2]
+			if _, err := client.ResolveFileName(ctx, name); err != nil {
+				t.Errorf("ResolveFileName(%q): %v", name, err)
+			}
+		})
+	}
+	wg.Wait()
+	if transport.lists != 1 {
+		t.Errorf("200 resolutions listed files %d times, want 1", transport.lists)
+	}
+
+	// A name missing from a recent listing doesn't list again
+	if _, err := client.ResolveFileName(ctx, "c.pdf"); err == nil {
+		t.Error("expected c.pdf not to be found")
+	}
+	if transport.lists != 1 {
+		t.Errorf("missing name listed files %d times, want 1", transport.lists)
+	}
+
+	// Deleting a file drops the listing
+	if err := client.DeleteFile(ctx, "files/b"); err != nil {
+		t.Fatal(err)
+	}
+	transport.files = `{"files":[{"name":"files/a","displayName":"a.pdf"}]}`
+	if _, err := client.ResolveFileName(ctx, "b.pdf"); err == nil {
+		t.Error("expected deleted b.pdf not to resolve")
+	}
+	if transport.lists != 2 {
+		t.Errorf("after delete: listed files %d times, want 2", transport.lists)
+	}
+}
@@ -0,0 +1,123 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// passagesInstruction keeps generation to a minimum when only the retrieved
+// chunks are wanted
+const passagesInstruction = "Always search the File Search store for passages relevant to the request. " +
+	"Do not answer, summarize or explain: after searching, reply with the single word OK."
+
+// minPassageOverlap is the shortest shared text, in bytes, for two chunks of
+// the same document to be merged
+const minPassageOverlap = 32
+
+// SearchPassages retrieves the chunks of storeName relevant to text without
+// generating an answer. Passages are ranked in retrieval order, with
+// duplicate and overlapping chunks merged. opts may set a metadata filter;
+// its system instruction and response schema are ignored.
+func (c *Client) SearchPassages(ctx context.Context, text string, storeName string, modelName string, opts *QueryOptions) ([]Source, error) {
+	if storeName == "" {
+		return nil, fmt.Errorf("a store is required to search passages")
+	}
+	searchOpts := QueryOptions{}
+	if opts != nil {
+		searchOpts = *opts
+	}
+	searchOpts.SystemInstruction = passagesInstruction
+	searchOpts.ResponseSchema = nil
+
+	resp, err := c.Query(ctx, text, storeName, modelName, &searchOpts)
+	if err != nil {
+		return nil, err
+	}
+	return Passages(resp, storeName), nil
+}
+
+// Passages returns the document chunks retrieved for resp. Chunks repeated
+// or contained in an earlier chunk of the same document are dropped, chunks
+// that overlap an earlier one (e.g. through chunk overlap) are merged into it,
+// and the remaining passages are numbered from 1 in retrieval order. store
+// is reported for chunks that don't name their store.
+func Passages(resp *genai.GenerateContentResponse, store string) []Source {
+	var passages []Source
+	for _, src := range Sources(resp) {
+		if src.Type != "document" || strings.TrimSpace(src.Text) == "" {
+			continue
+		}
+		if src.Store == "" {
+			src.Store = store
+		}
+		src.Text = strings.TrimSpace(src.Text)
+
+		merged := false
+		for i := range passages {
+			if mergePassage(&passages[i], src) {
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			passages = append(passages, src)
+		}
+	}
+	for i := range passages {
+		passages[i].Index = i + 1
+	}
+	return passages
+}
+
+// mergePassage folds src into p when both come from the same document and
+// their texts overlap, reporting whether it did
+func mergePassage(p *Source, src Source) bool {
+	if passageKey(*p) != passageKey(src) {
+		return false
+	}
+
+	switch {
+	case strings.Contains(p.Text, src.Text):
+	case strings.Contains(src.Text, p.Text):
+		p.Text = src.Text
+	default:
+		if n := textOverlap(p.Text, src.Text); n > 0 {
+			p.Text += src.Text[n:]
+		} else if n := textOverlap(src.Text, p.Text); n > 0 {
+			p.Text = src.Text + p.Text[n:]
+		} else {
+			return false
+		}
+	}
+
+	// Widen the page span to cover both chunks
+	if src.FirstPage > 0 && (p.FirstPage == 0 || src.FirstPage < p.FirstPage) {
+		p.FirstPage = src.FirstPage
+	}
+	if src.LastPage > p.LastPage {
+		p.LastPage = src.LastPage
+	}
+	return true
+}
+
+// passageKey identifies the document a passage was retrieved from
+func passageKey(s Source) string {
+	if s.Document != "" {
+		return s.Document
+	}
+	return s.Store + "\x00" + s.Title + "\x00" + s.Part + "\x00" + s.URI
+}
+
+// textOverlap returns the length of the longest suffix of a that is a prefix
+// of b, or 0 if it is shorter than minPassageOverlap
+func textOverlap(a, b string) int {
+	for n := min(len(a), len(b)); n >= minPassageOverlap; n-- {
+		if strings.HasSuffix(a, b[:n]) {
+			return n
+		}
+	}
+	return 0
+}
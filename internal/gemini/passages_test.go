@@ -0,0 +1,51 @@
+package gemini
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestPassages(t *testing.T) {
+	shared := strings.Repeat("overlapping chunk text ", 3)
+	chunk := func(doc, text string, page int32) *genai.GroundingChunk {
+		return &genai.GroundingChunk{RetrievedContext: &genai.GroundingChunkRetrievedContext{
+			DocumentName: doc,
+			Title:        doc,
+			Text:         text,
+			PageNumber:   genai.Ptr(page),
+		}}
+	}
+	resp := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		GroundingMetadata: &genai.GroundingMetadata{GroundingChunks: []*genai.GroundingChunk{
+			chunk("docs/a", "first part "+shared, 1),
+			{Web: &genai.GroundingChunkWeb{Title: "web", URI: "https://example.com"}},
+			chunk("docs/b", "unrelated text", 4),
+			chunk("docs/a", shared+"second part", 2),
+			chunk("docs/b", "unrelated", 4),
+			chunk("docs/c", "", 1),
+		}},
+	}}}
+
+	passages := Passages(resp, "fileSearchStores/abc")
+	if len(passages) != 2 {
+		t.Fatalf("expected 2 passages, got %+v", passages)
+	}
+
+	a := passages[0]
+	if a.Index != 1 || a.Text != "first part "+shared+"second part" {
+		t.Errorf("overlapping chunks not merged: %+v", a)
+	}
+	if a.FirstPage != 1 || a.LastPage != 2 {
+		t.Errorf("expected pages 1-2, got %d-%d", a.FirstPage, a.LastPage)
+	}
+	if a.Store != "fileSearchStores/abc" {
+		t.Errorf("expected the queried store, got %q", a.Store)
+	}
+
+	b := passages[1]
+	if b.Index != 2 || b.Text != "unrelated text" {
+		t.Errorf("contained chunk not dropped: %+v", b)
+	}
+}
@@ -0,0 +1,253 @@
+package gemini
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// QueryOptions controls how a query answer is generated.
+// Nil pointers and zero values leave the model's defaults in place.
+type QueryOptions struct {
+	MetadataFilter    string
+	SystemInstruction string
+	Temperature       *float32
+	TopP              *float32
+	MaxOutputTokens   int32
+	ThinkingBudget    *int32
+	// ThinkingLevel is minimal, low, medium or high
+	ThinkingLevel string
+	// SafetySettings maps harm categories to block thresholds,
+	// e.g. {"dangerous_content": "block_only_high"}
+	SafetySettings map[string]string
+	StopSequences  []string
+	// ResponseSchema is a JSON Schema document (e.g. json.RawMessage) the
+	// answer must follow. Setting it makes the model answer in JSON.
+	ResponseSchema any
+	// NoCache skips the client's response cache
+	NoCache bool
+}
+
+// StructuredAnswer is a JSON answer that matched the requested schema,
+// with the sources it was grounded in
+type StructuredAnswer struct {
+	Answer  any      `json:"answer"`
+	Sources []Source `json:"sources"`
+	Usage   *Usage   `json:"usage,omitempty"`
+}
+
+// Query asks the model a question, grounded in storeName when it is set
+func (c *Client) Query(ctx context.Context, text string, storeName string, modelName string, opts *QueryOptions) (*genai.GenerateContentResponse, error) {
+	if opts == nil {
+		opts = &QueryOptions{}
+	}
+	config, err := opts.generateConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if storeName != "" {
+		if err := c.requireGeminiAPI("File Search"); err != nil {
+			return nil, err
+		}
+		fs := &genai.FileSearch{FileSearchStoreNames: []string{storeName}}
+		if opts.MetadataFilter != "" {
+			fs.MetadataFilter = opts.MetadataFilter
+		}
+		config.Tools = []*genai.Tool{{FileSearch: fs}}
+	}
+
+	var key, fingerprint string
+	if c.cache != nil && !opts.NoCache {
+		key, err = cacheKey(text, storeName, modelName, opts.MetadataFilter, config)
+		if err == nil {
+			fingerprint, err = c.storeFingerprint(ctx, storeName)
+		}
+		if err != nil {
+			// The cache is optional, so a query it can't key, e.g. because
+			// the store can't be read, is generated without it
+			key = ""
+		} else if data, ok := c.cache.Get(key, fingerprint); ok {
+			var resp genai.GenerateContentResponse
+			if err := json.Unmarshal(data, &resp); err == nil {
+				return &resp, nil
+			}
+		}
+	}
+
+	resp, err := c.client.Models.GenerateContent(ctx, modelName, genai.Text(text), config)
+	if err != nil {
+		return nil, err
+	}
+	if u := UsageOf(resp); u != nil {
+		c.reportUsage(ctx, UsageEvent{Operation: UsageQuery, Model: modelName, Store: storeName, Usage: *u})
+	}
+	if key != "" {
+		// A failed write only costs a future cache hit
+		if data, err := json.Marshal(resp); err == nil {
+			c.cache.Put(key, fingerprint, data)
+		}
+	}
+	return resp, nil
+}
+
+// ResponseCache stores query responses under a key, along with a
+// fingerprint of the stores they were grounded in. Get must miss when the
+// fingerprint differs from the one the response was stored with.
+type ResponseCache interface {
+	Get(key, fingerprint string) ([]byte, bool)
+	Put(key, fingerprint string, data []byte) error
+}
+
+// cacheKey identifies a query by its normalized text (case and whitespace
+// are ignored), store, model, metadata filter and generation config
+func cacheKey(text, storeName, modelName, filter string, config *genai.GenerateContentConfig) (string, error) {
+	generation, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, part := range []string{
+		strings.ToLower(strings.Join(strings.Fields(text), " ")),
+		storeName,
+		strings.TrimPrefix(modelName, "models/"),
+		filter,
+		string(generation),
+	} {
+		// Length-prefix each part so adjacent parts can't run together
+		fmt.Fprintf(h, "%d:%s\n", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// storeFingerprint summarizes the state of a store, so cached answers are
+// discarded once documents are added, removed or reindexed
+func (c *Client) storeFingerprint(ctx context.Context, storeName string) (string, error) {
+	if storeName == "" {
+		return "", nil
+	}
+	store, err := c.GetStore(ctx, storeName)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s|%d|%d|%d|%d", store.UpdateTime.UTC().Format(time.RFC3339Nano),
+		store.ActiveDocumentsCount, store.PendingDocumentsCount, store.FailedDocumentsCount, store.SizeBytes), nil
+}
+
+// generateConfig builds the generation config for the options
+func (o *QueryOptions) generateConfig() (*genai.GenerateContentConfig, error) {
+	config := &genai.GenerateContentConfig{
+		Temperature:     o.Temperature,
+		TopP:            o.TopP,
+		MaxOutputTokens: o.MaxOutputTokens,
+		StopSequences:   o.StopSequences,
+	}
+	if o.ResponseSchema != nil {
+		// ResponseJsonSchema takes standard JSON Schema as-is, unlike
+		// ResponseSchema's OpenAPI subset
+		config.ResponseMIMEType = "application/json"
+		config.ResponseJsonSchema = o.ResponseSchema
+	}
+	if o.SystemInstruction != "" {
+		config.SystemInstruction = genai.NewContentFromText(o.SystemInstruction, genai.RoleUser)
+	}
+
+	if o.ThinkingBudget != nil || o.ThinkingLevel != "" {
+		level, err := ParseThinkingLevel(o.ThinkingLevel)
+		if err != nil {
+			return nil, err
+		}
+		config.ThinkingConfig = &genai.ThinkingConfig{ThinkingBudget: o.ThinkingBudget, ThinkingLevel: level}
+	}
+
+	settings, err := ParseSafetySettings(o.SafetySettings)
+	if err != nil {
+		return nil, err
+	}
+	config.SafetySettings = settings
+	return config, nil
+}
+
+var thinkingLevels = map[string]genai.ThinkingLevel{
+	"minimal": genai.ThinkingLevelMinimal,
+	"low":     genai.ThinkingLevelLow,
+	"medium":  genai.ThinkingLevelMedium,
+	"high":    genai.ThinkingLevelHigh,
+}
+
+// ParseThinkingLevel converts minimal, low, medium or high (any case) to a
+// thinking level. An empty string returns an empty level.
+func ParseThinkingLevel(s string) (genai.ThinkingLevel, error) {
+	if s == "" {
+		return "", nil
+	}
+	level, ok := thinkingLevels[strings.ToLower(s)]
+	if !ok {
+		return "", fmt.Errorf("invalid thinking level %q (valid: minimal, low, medium, high)", s)
+	}
+	return level, nil
+}
+
+var harmCategories = map[string]genai.HarmCategory{
+	"harassment":        genai.HarmCategoryHarassment,
+	"hate_speech":       genai.HarmCategoryHateSpeech,
+	"sexually_explicit": genai.HarmCategorySexuallyExplicit,
+	"dangerous_content": genai.HarmCategoryDangerousContent,
+	"civic_integrity":   genai.HarmCategoryCivicIntegrity,
+}
+
+var harmThresholds = map[string]genai.HarmBlockThreshold{
+	"block_low_and_above":    genai.HarmBlockThresholdBlockLowAndAbove,
+	"block_medium_and_above": genai.HarmBlockThresholdBlockMediumAndAbove,
+	"block_only_high":        genai.HarmBlockThresholdBlockOnlyHigh,
+	"block_none":             genai.HarmBlockThresholdBlockNone,
+	"off":                    genai.HarmBlockThresholdOff,
+}
+
+// ParseSafetySettings converts category/threshold pairs such as
+// dangerous_content=block_only_high into safety settings. Names are
+// case-insensitive and may also use the API's HARM_CATEGORY_ prefix.
+func ParseSafetySettings(settings map[string]string) ([]*genai.SafetySetting, error) {
+	if len(settings) == 0 {
+		return nil, nil
+	}
+
+	// Sort for a stable request order
+	categories := make([]string, 0, len(settings))
+	for category := range settings {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	result := make([]*genai.SafetySetting, 0, len(settings))
+	for _, name := range categories {
+		key := strings.TrimPrefix(strings.ToLower(name), "harm_category_")
+		category, ok := harmCategories[key]
+		if !ok {
+			return nil, fmt.Errorf("invalid safety category %q (valid: %s)", name, mapKeys(harmCategories))
+		}
+		threshold, ok := harmThresholds[strings.ToLower(settings[name])]
+		if !ok {
+			return nil, fmt.Errorf("invalid safety threshold %q for %s (valid: %s)", settings[name], name, mapKeys(harmThresholds))
+		}
+		result = append(result, &genai.SafetySetting{Category: category, Threshold: threshold})
+	}
+	return result, nil
+}
+
+// mapKeys returns the sorted keys of m as a comma-separated list
+func mapKeys[V any](m map[string]V) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
@@ -0,0 +1,193 @@
+package gemini
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestQueryOptionsGenerateConfig(t *testing.T) {
+	opts := &QueryOptions{
+		SystemInstruction: "Answer in one sentence.",
+		Temperature:       genai.Ptr[float32](0),
+		MaxOutputTokens:   128,
+		ThinkingBudget:    genai.Ptr[int32](0),
+		SafetySettings:    map[string]string{"DANGEROUS_CONTENT": "block_only_high"},
+		StopSequences:     []string{"###"},
+	}
+	config, err := opts.generateConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.SystemInstruction == nil || config.SystemInstruction.Parts[0].Text != "Answer in one sentence." {
+		t.Errorf("system instruction not set: %+v", config.SystemInstruction)
+	}
+	if config.Temperature == nil || *config.Temperature != 0 {
+		t.Errorf("expected explicit zero temperature, got %v", config.Temperature)
+	}
+	if config.TopP != nil {
+		t.Errorf("expected TopP to be left unset, got %v", *config.TopP)
+	}
+	if config.MaxOutputTokens != 128 {
+		t.Errorf("MaxOutputTokens = %d", config.MaxOutputTokens)
+	}
+	if config.ThinkingConfig == nil || *config.ThinkingConfig.ThinkingBudget != 0 {
+		t.Errorf("expected zero thinking budget, got %+v", config.ThinkingConfig)
+	}
+	if len(config.SafetySettings) != 1 || config.SafetySettings[0].Category != genai.HarmCategoryDangerousContent ||
+		config.SafetySettings[0].Threshold != genai.HarmBlockThresholdBlockOnlyHigh {
+		t.Errorf("unexpected safety settings: %+v", config.SafetySettings)
+	}
+	if len(config.StopSequences) != 1 {
+		t.Errorf("StopSequences = %v", config.StopSequences)
+	}
+
+	empty, err := (&QueryOptions{}).generateConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.ThinkingConfig != nil || empty.SystemInstruction != nil || empty.SafetySettings != nil {
+		t.Errorf("expected an empty config, got %+v", empty)
+	}
+}
+
+func TestParseSafetySettings(t *testing.T) {
+	settings, err := ParseSafetySettings(map[string]string{
+		"harm_category_harassment": "BLOCK_NONE",
+		"hate_speech":              "block_low_and_above",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(settings) != 2 || settings[0].Category != genai.HarmCategoryHarassment || settings[1].Threshold != genai.HarmBlockThresholdBlockLowAndAbove {
+		t.Errorf("unexpected settings: %+v %+v", settings[0], settings[1])
+	}
+
+	if _, err := ParseSafetySettings(map[string]string{"violence": "off"}); err == nil {
+		t.Error("expected an error for an unknown category")
+	}
+	if _, err := ParseSafetySettings(map[string]string{"harassment": "sometimes"}); err == nil {
+		t.Error("expected an error for an unknown threshold")
+	}
+}
+
+func TestParseThinkingLevel(t *testing.T) {
+	if level, err := ParseThinkingLevel("High"); err != nil || level != genai.ThinkingLevelHigh {
+		t.Errorf("ParseThinkingLevel(High) = (%q, %v)", level, err)
+	}
+	if _, err := ParseThinkingLevel("extreme"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestQueryOptionsResponseSchema(t *testing.T) {
+	schema := map[string]any{"type": "object"}
+	config, err := (&QueryOptions{ResponseSchema: schema}).generateConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ResponseMIMEType != "application/json" || config.ResponseJsonSchema == nil {
+		t.Errorf("expected a JSON response config, got %q %v", config.ResponseMIMEType, config.ResponseJsonSchema)
+	}
+}
+
+// memoryCache is a ResponseCache for tests
+type memoryCache map[string][2]string
+
+func (m memoryCache) Get(key, fingerprint string) ([]byte, bool) {
+	e, ok := m[key]
+	if !ok || e[0] != fingerprint {
+		return nil, false
+	}
+	return []byte(e[1]), true
+}
+
+func (m memoryCache) Put(key, fingerprint string, data []byte) error {
+	m[key] = [2]string{fingerprint, string(data)}
+	return nil
+}
+
+// storeTransport answers store lookups with the current store body and
+// everything else with a generated answer
+type storeTransport struct {
+	store     string
+	generated int
+	// storeFails makes store lookups fail
+	storeFails bool
+}
+
+func (s *storeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, status := s.store, http.StatusOK
+	if strings.HasSuffix(req.URL.Path, ":generateContent") {
+		s.generated++
+		body = `{"candidates":[{"content":{"role":"model","parts":[{"text":"40 V"}]}}],"usageMetadata":{"promptTokenCount":10,"totalTokenCount":12}}`
+	} else if s.storeFails {
+		body, status = `{"error":{"code":403,"message":"permission denied","status":"PERMISSION_DENIED"}}`, http.StatusForbidden
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestQueryCache(t *testing.T) {
+	ctx := context.Background()
+	transport := &storeTransport{store: `{"name":"fileSearchStores/abc","updateTime":"2025-06-01T00:00:00Z","activeDocumentsCount":"3"}`}
+	cache := memoryCache{}
+	var usage int
+	client, err := NewClientWithOptions(ctx, ClientOptions{
+		APIKey:     "k",
+		HTTPClient: &http.Client{Transport: transport},
+		Cache:      cache,
+		OnUsage:    func(context.Context, UsageEvent) { usage++ },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := func(text string, opts *QueryOptions) {
+		t.Helper()
+		resp, err := client.Query(ctx, text, "fileSearchStores/abc", "gemini-2.5-flash", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Text() != "40 V" {
+			t.Errorf("unexpected answer %q", resp.Text())
+		}
+	}
+
+	query("What is the max input voltage?", nil)
+	query("  what is the MAX input   voltage? ", nil)
+	if transport.generated != 1 || usage != 1 {
+		t.Errorf("normalized repeat: generated %d times with %d usage events, want 1 and 1", transport.generated, usage)
+	}
+
+	temp := float32(0)
+	query("What is the max input voltage?", &QueryOptions{Temperature: &temp})
+	query("What is the max input voltage?", &QueryOptions{NoCache: true})
+	if transport.generated != 3 {
+		t.Errorf("different config and NoCache: generated %d times, want 3", transport.generated)
+	}
+
+	// Adding a document changes the store fingerprint
+	transport.store = `{"name":"fileSearchStores/abc","updateTime":"2025-06-02T00:00:00Z","activeDocumentsCount":"4"}`
+	query("What is the max input voltage?", nil)
+	if transport.generated != 4 {
+		t.Errorf("changed store: generated %d times, want 4", transport.generated)
+	}
+
+	// A store that can't be fingerprinted skips the cache rather than failing
+	transport.storeFails = true
+	query("What is the max input voltage?", nil)
+	query("What is the max input voltage?", nil)
+	if transport.generated != 6 {
+		t.Errorf("unreadable store: generated %d times, want 6", transport.generated)
+	}
+}
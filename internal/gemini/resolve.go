@@ -0,0 +1,194 @@
+package gemini
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Candidate is a resource that a display name may refer to
+type Candidate struct {
+	Name        string    `json:"name"`
+	DisplayName string    `json:"display_name"`
+	CreateTime  time.Time `json:"create_time,omitzero"`
+	// contentHash is a document's content_sha256, for duplicate checks
+	contentHash string
+}
+
+func (c Candidate) String() string {
+	s := fmt.Sprintf("%s (%s", c.DisplayName, c.Name)
+	if !c.CreateTime.IsZero() {
+		s += ", created " + c.CreateTime.Local().Format(time.DateTime)
+	}
+	return s + ")"
+}
+
+// Picker chooses one of several candidates for ref, e.g. by prompting the
+// user. It returns false to leave the name unresolved.
+type Picker func(kind, ref string, candidates []Candidate) (Candidate, bool)
+
+// AmbiguousNameError is returned when several resources of a kind share the
+// display name that was given
+type AmbiguousNameError struct {
+	// Kind is "store", "file" or "document"
+	Kind       string      `json:"kind"`
+	Ref        string      `json:"ref"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+func (e *AmbiguousNameError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s name %q is ambiguous; use one of these IDs instead:", e.Kind, e.Ref)
+	for _, c := range e.Candidates {
+		b.WriteString("\n  " + c.String())
+	}
+	return b.String()
+}
+
+// NotFoundError is returned when no resource has the given display name.
+// Suggestions lists resources with similar names.
+type NotFoundError struct {
+	Kind string `json:"kind"`
+	Ref  string `json:"ref"`
+	// Scope is the store searched for documents
+	Scope       string      `json:"scope,omitempty"`
+	Suggestions []Candidate `json:"suggestions,omitempty"`
+}
+
+func (e *NotFoundError) Error() string {
+	var b strings.Builder
+	if e.Scope != "" {
+		fmt.Fprintf(&b, "%s not found in store %s: %s", e.Kind, e.Scope, e.Ref)
+	} else {
+		fmt.Fprintf(&b, "%s not found: %s", e.Kind, e.Ref)
+	}
+	if len(e.Suggestions) > 0 {
+		b.WriteString("\nDid you mean:")
+		for _, c := range e.Suggestions {
+			b.WriteString("\n  " + c.String())
+		}
+	}
+	return b.String()
+}
+
+// maxSuggestions caps the "did you mean" list
+const maxSuggestions = 5
+
+// resolve picks the candidate whose display name is ref. An exact match
+// wins, then a case-insensitive one. Several matches are ambiguous; no match
+// suggests similar names. The client's picker, if set, is offered the
+// candidates in either case.
+func (c *Client) resolve(kind, ref, scope string, candidates []Candidate) (string, error) {
+	matches := matchName(ref, candidates)
+	if len(matches) == 1 {
+		return matches[0].Name, nil
+	}
+
+	var err error
+	var choices []Candidate
+	if len(matches) > 1 {
+		sortNewestFirst(matches)
+		err = &AmbiguousNameError{Kind: kind, Ref: ref, Candidates: matches}
+		choices = matches
+	} else {
+		suggestions := suggest(ref, candidates)
+		err = &NotFoundError{Kind: kind, Ref: ref, Scope: scope, Suggestions: suggestions}
+		choices = suggestions
+	}
+	if c.pick != nil && len(choices) > 0 {
+		if choice, ok := c.pick(kind, ref, choices); ok {
+			return choice.Name, nil
+		}
+	}
+	return "", err
+}
+
+// matchName returns the candidates named ref, falling back to a
+// case-insensitive comparison when there is no exact match
+func matchName(ref string, candidates []Candidate) []Candidate {
+	var exact, folded []Candidate
+	for _, c := range candidates {
+		switch {
+		case c.DisplayName == ref:
+			exact = append(exact, c)
+		case strings.EqualFold(c.DisplayName, ref):
+			folded = append(folded, c)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return folded
+}
+
+// suggest returns the candidates whose names start with or contain ref, or
+// are a few edits away from it, closest first
+func suggest(ref string, candidates []Candidate) []Candidate {
+	type scored struct {
+		Candidate
+		score int
+	}
+	lower := strings.ToLower(ref)
+	maxDistance := max(2, len([]rune(lower))/3)
+
+	var results []scored
+	for _, c := range candidates {
+		name := strings.ToLower(c.DisplayName)
+		if name == "" {
+			continue
+		}
+		var score int
+		switch {
+		case strings.HasPrefix(name, lower):
+			score = 0
+		case strings.Contains(name, lower):
+			score = 1
+		default:
+			d := levenshtein(lower, name)
+			if d > maxDistance {
+				continue
+			}
+			score = 1 + d
+		}
+		results = append(results, scored{c, score})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].score < results[j].score })
+
+	var suggestions []Candidate
+	for _, r := range results {
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+		suggestions = append(suggestions, r.Candidate)
+	}
+	return suggestions
+}
+
+func sortNewestFirst(candidates []Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].CreateTime.After(candidates[j].CreateTime)
+	})
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,114 @@
+package gemini
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	older := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.AddDate(0, 1, 0)
+	candidates := []Candidate{
+		{Name: "documents/a", DisplayName: "datasheet.pdf", CreateTime: older},
+		{Name: "documents/b", DisplayName: "datasheet.pdf", CreateTime: newer},
+		{Name: "documents/c", DisplayName: "LM317.pdf"},
+		{Name: "documents/d", DisplayName: "lm317-errata.pdf"},
+		{Name: "documents/e", DisplayName: "Manual"},
+	}
+	c := &Client{}
+
+	t.Run("exact", func(t *testing.T) {
+		got, err := c.resolve("document", "LM317.pdf", "", candidates)
+		if err != nil || got != "documents/c" {
+			t.Errorf("resolve() = %q, %v", got, err)
+		}
+	})
+
+	t.Run("case-insensitive", func(t *testing.T) {
+		got, err := c.resolve("document", "manual", "", candidates)
+		if err != nil || got != "documents/e" {
+			t.Errorf("resolve() = %q, %v", got, err)
+		}
+	})
+
+	t.Run("ambiguous", func(t *testing.T) {
+		_, err := c.resolve("document", "datasheet.pdf", "", candidates)
+		var ambiguous *AmbiguousNameError
+		if !errors.As(err, &ambiguous) {
+			t.Fatalf("expected AmbiguousNameError, got %v", err)
+		}
+		if len(ambiguous.Candidates) != 2 || ambiguous.Candidates[0].Name != "documents/b" {
+			t.Errorf("expected both candidates, newest first: %+v", ambiguous.Candidates)
+		}
+		if !strings.Contains(err.Error(), "documents/a") || !strings.Contains(err.Error(), "created") {
+			t.Errorf("error should list IDs and create times: %v", err)
+		}
+	})
+
+	t.Run("not found with suggestions", func(t *testing.T) {
+		_, err := c.resolve("document", "lm317", "fileSearchStores/s", candidates)
+		var notFound *NotFoundError
+		if !errors.As(err, &notFound) {
+			t.Fatalf("expected NotFoundError, got %v", err)
+		}
+		if len(notFound.Suggestions) != 2 || notFound.Suggestions[0].Name != "documents/c" {
+			t.Errorf("unexpected suggestions %+v", notFound.Suggestions)
+		}
+		msg := err.Error()
+		if !strings.HasPrefix(msg, "document not found in store fileSearchStores/s: lm317") || !strings.Contains(msg, "Did you mean") {
+			t.Errorf("unexpected message %q", msg)
+		}
+	})
+
+	t.Run("typo", func(t *testing.T) {
+		_, err := c.resolve("document", "manaul", "", candidates)
+		var notFound *NotFoundError
+		if !errors.As(err, &notFound) || len(notFound.Suggestions) != 1 || notFound.Suggestions[0].Name != "documents/e" {
+			t.Errorf("expected Manual to be suggested, got %v", err)
+		}
+	})
+
+	t.Run("no suggestions", func(t *testing.T) {
+		_, err := c.resolve("store", "zzzzzzzz", "", candidates)
+		if err == nil || err.Error() != "store not found: zzzzzzzz" {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("picker", func(t *testing.T) {
+		var offered []Candidate
+		picking := &Client{pick: func(kind, ref string, choices []Candidate) (Candidate, bool) {
+			offered = choices
+			return choices[len(choices)-1], true
+		}}
+		got, err := picking.resolve("document", "datasheet.pdf", "", candidates)
+		if err != nil || got != "documents/a" || len(offered) != 2 {
+			t.Errorf("resolve() = %q, %v with %d choices", got, err, len(offered))
+		}
+
+		declining := &Client{pick: func(string, string, []Candidate) (Candidate, bool) { return Candidate{}, false }}
+		if _, err := declining.resolve("document", "datasheet.pdf", "", candidates); err == nil {
+			t.Error("expected the error when the picker declines")
+		}
+	})
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"manaul", "manual", 2},
+		{"héllo", "hello", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package gemini
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mikesmitty/file-search/internal/split"
+	"google.golang.org/genai"
+)
+
+// Source is a grounding source cited by a query answer
+type Source struct {
+	// Index is the 1-based position of the grounding chunk in the response
+	Index int `json:"index"`
+	// Type is "web" or "document"
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URI   string `json:"uri,omitempty"`
+	// Document and Store are the resource names of the retrieved document
+	// and its File Search store, when the API reports them
+	Document string `json:"document,omitempty"`
+	Store    string `json:"store,omitempty"`
+	// Part is "Part i/n" for chunks from split documents
+	Part string `json:"part,omitempty"`
+	// FirstPage and LastPage are pages in the original document (0 if unknown)
+	FirstPage int    `json:"firstPage,omitempty"`
+	LastPage  int    `json:"lastPage,omitempty"`
+	Text      string `json:"text,omitempty"`
+}
+
+// pageMarker matches page markers embedded in extracted PDF text
+var pageMarker = regexp.MustCompile(`--- PAGE (\d+) ---`)
+
+// Sources returns the grounding sources of the first candidate in resp
+func Sources(resp *genai.GenerateContentResponse) []Source {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].GroundingMetadata == nil {
+		return nil
+	}
+	chunks := resp.Candidates[0].GroundingMetadata.GroundingChunks
+	sources := make([]Source, 0, len(chunks))
+	for i, chunk := range chunks {
+		if chunk.Web == nil && chunk.RetrievedContext == nil {
+			continue
+		}
+		sources = append(sources, NewSource(i+1, chunk))
+	}
+	return sources
+}
+
+// NewSource describes a grounding chunk. Chunks from split documents are
+// reported under the original document name with page numbers relative to
+// the full document.
+func NewSource(index int, chunk *genai.GroundingChunk) Source {
+	if chunk.Web != nil {
+		return Source{Index: index, Type: "web", Title: chunk.Web.Title, URI: chunk.Web.URI}
+	}
+
+	src := Source{Index: index, Type: "document"}
+	rc := chunk.RetrievedContext
+	if rc == nil {
+		return src
+	}
+	title, part, pageOffset := ResolvePartTitle(rc)
+	if title == "" {
+		title = "Unknown Document"
+	}
+	src.Title = title
+	src.URI = rc.URI
+	src.Document = rc.DocumentName
+	src.Store = rc.FileSearchStore
+	src.Part = part
+	src.Text = rc.Text
+
+	// Prefer RAGChunk page spans, then the page number, then a page marker in the text
+	if rc.RAGChunk != nil && rc.RAGChunk.PageSpan != nil {
+		span := rc.RAGChunk.PageSpan
+		if span.FirstPage > 0 {
+			src.FirstPage = int(span.FirstPage) + pageOffset
+			src.LastPage = src.FirstPage
+			if span.LastPage > span.FirstPage {
+				src.LastPage = int(span.LastPage) + pageOffset
+			}
+		}
+	} else if rc.PageNumber != nil && *rc.PageNumber > 0 {
+		src.FirstPage = int(*rc.PageNumber) + pageOffset
+		src.LastPage = src.FirstPage
+	}
+	if src.FirstPage == 0 && rc.Text != "" {
+		if matches := pageMarker.FindStringSubmatch(rc.Text); len(matches) > 1 {
+			page, _ := strconv.Atoi(matches[1])
+			src.FirstPage = page + pageOffset
+			src.LastPage = src.FirstPage
+		}
+	}
+	return src
+}
+
+// Location describes where the source is, e.g. "URI: x, Part 2/5, Pages 17-19"
+func (s Source) Location() string {
+	if s.Type == "web" {
+		return s.URI
+	}
+	var parts []string
+	if s.URI != "" {
+		parts = append(parts, fmt.Sprintf("URI: %s", s.URI))
+	}
+	if s.Part != "" {
+		parts = append(parts, s.Part)
+	}
+	if s.FirstPage > 0 {
+		if s.LastPage > s.FirstPage {
+			parts = append(parts, fmt.Sprintf("Pages %d-%d", s.FirstPage, s.LastPage))
+		} else {
+			parts = append(parts, fmt.Sprintf("Page %d", s.FirstPage))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ResolvePartTitle maps a chunk from a split document part back to the
+// original document. It returns the title to display, a "Part i/n" label
+// (empty for unsplit documents) and the page offset of the part within the
+// original PDF.
+func ResolvePartTitle(rc *genai.GroundingChunkRetrievedContext) (string, string, int) {
+	meta := make(map[string]string, len(rc.CustomMetadata))
+	for _, m := range rc.CustomMetadata {
+		meta[m.Key] = m.StringValue
+	}
+
+	title, index, total, ok := split.ParsePartDisplayName(rc.Title)
+	if original := meta[split.MetaOriginalName]; original != "" {
+		title = original
+		index, _ = strconv.Atoi(meta[split.MetaPart])
+		total, _ = strconv.Atoi(meta[split.MetaTotalParts])
+		ok = index > 0
+	}
+	if !ok {
+		return rc.Title, "", 0
+	}
+
+	// Pages within a part are relative to its first page
+	offset := 0
+	if pageRange := meta[split.MetaPageRange]; pageRange != "" {
+		first, _, _ := strings.Cut(pageRange, "-")
+		if n, err := strconv.Atoi(first); err == nil && n > 0 {
+			offset = n - 1
+		}
+	}
+	return title, fmt.Sprintf("Part %d/%d", index, total), offset
+}
@@ -0,0 +1,54 @@
+package gemini
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestResolvePartTitle(t *testing.T) {
+	tests := []struct {
+		name       string
+		rc         *genai.GroundingChunkRetrievedContext
+		wantTitle  string
+		wantLabel  string
+		wantOffset int
+	}{
+		{
+			name:      "unsplit document",
+			rc:        &genai.GroundingChunkRetrievedContext{Title: "manual.pdf"},
+			wantTitle: "manual.pdf",
+		},
+		{
+			name:      "part from display name",
+			rc:        &genai.GroundingChunkRetrievedContext{Title: "notes.md (part 2 of 3)"},
+			wantTitle: "notes.md",
+			wantLabel: "Part 2/3",
+		},
+		{
+			name: "part from metadata with page offset",
+			rc: &genai.GroundingChunkRetrievedContext{
+				Title: "manual.pdf (part 3 of 4)",
+				CustomMetadata: []*genai.GroundingChunkCustomMetadata{
+					{Key: "original_name", StringValue: "manual.pdf"},
+					{Key: "part", StringValue: "3"},
+					{Key: "total_parts", StringValue: "4"},
+					{Key: "page_range", StringValue: "101-150"},
+				},
+			},
+			wantTitle:  "manual.pdf",
+			wantLabel:  "Part 3/4",
+			wantOffset: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, label, offset := ResolvePartTitle(tt.rc)
+			if title != tt.wantTitle || label != tt.wantLabel || offset != tt.wantOffset {
+				t.Errorf("ResolvePartTitle() = %q, %q, %d; want %q, %q, %d",
+					title, label, offset, tt.wantTitle, tt.wantLabel, tt.wantOffset)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/genai"
+)
+
+// Usage operations reported to a UsageHook
+const (
+	UsageQuery = "query"
+	UsageIndex = "index"
+)
+
+// Usage is the token usage of a request
+type Usage struct {
+	PromptTokens   int32 `json:"prompt_tokens"`
+	OutputTokens   int32 `json:"output_tokens"`
+	ThinkingTokens int32 `json:"thinking_tokens"`
+	// ToolTokens are the prompt tokens of File Search results
+	ToolTokens   int32 `json:"tool_tokens"`
+	CachedTokens int32 `json:"cached_tokens,omitempty"`
+	TotalTokens  int32 `json:"total_tokens"`
+	// IndexingTokens estimates the tokens embedded when indexing a document
+	IndexingTokens int64 `json:"indexing_tokens,omitempty"`
+}
+
+// UsageEvent is the usage of one API request
+type UsageEvent struct {
+	// Operation is UsageQuery or UsageIndex
+	Operation string
+	Model     string
+	Store     string
+	Usage     Usage
+}
+
+// UsageHook receives the usage of each query and indexing request made by
+// a client. It is called with the request's context.
+type UsageHook func(ctx context.Context, e UsageEvent)
+
+// UsageOf returns the token usage reported in resp, or nil if there is none
+func UsageOf(resp *genai.GenerateContentResponse) *Usage {
+	if resp == nil || resp.UsageMetadata == nil {
+		return nil
+	}
+	u := resp.UsageMetadata
+	return &Usage{
+		PromptTokens:   u.PromptTokenCount,
+		OutputTokens:   u.CandidatesTokenCount,
+		ThinkingTokens: u.ThoughtsTokenCount,
+		ToolTokens:     u.ToolUsePromptTokenCount,
+		CachedTokens:   u.CachedContentTokenCount,
+		TotalTokens:    u.TotalTokenCount,
+	}
+}
+
+// String summarizes the token counts, e.g. for verbose output
+func (u Usage) String() string {
+	if u.IndexingTokens > 0 && u.TotalTokens == 0 {
+		return fmt.Sprintf("~%d indexing (estimated)", u.IndexingTokens)
+	}
+	return fmt.Sprintf("%d prompt, %d output, %d thinking, %d tool, %d total",
+		u.PromptTokens, u.OutputTokens, u.ThinkingTokens, u.ToolTokens, u.TotalTokens)
+}
+
+// EstimateIndexingTokens estimates the tokens embedded when indexing a
+// document of size bytes, at about four bytes per token of text. Estimates
+// for binary formats such as PDF are rougher, since their size includes
+// layout and images.
+func EstimateIndexingTokens(size int64) int64 {
+	return (size + 3) / 4
+}
+
+type usageLabelKey struct{}
+
+// WithUsageLabel labels the usage of requests made with ctx, e.g. with the
+// MCP tool that made them
+func WithUsageLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, usageLabelKey{}, label)
+}
+
+// UsageLabel returns the label set with WithUsageLabel, if any
+func UsageLabel(ctx context.Context) string {
+	label, _ := ctx.Value(usageLabelKey{}).(string)
+	return label
+}
+
+// reportUsage passes an event to the client's usage hook, if any
+func (c *Client) reportUsage(ctx context.Context, e UsageEvent) {
+	if c.onUsage != nil {
+		c.onUsage(ctx, e)
+	}
+}
@@ -0,0 +1,30 @@
+package gemini
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestUsageOf(t *testing.T) {
+	if UsageOf(nil) != nil || UsageOf(&genai.GenerateContentResponse{}) != nil {
+		t.Error("expected nil usage without metadata")
+	}
+	u := UsageOf(&genai.GenerateContentResponse{UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:        100,
+		CandidatesTokenCount:    20,
+		ThoughtsTokenCount:      30,
+		ToolUsePromptTokenCount: 400,
+		TotalTokenCount:         550,
+	}})
+	want := Usage{PromptTokens: 100, OutputTokens: 20, ThinkingTokens: 30, ToolTokens: 400, TotalTokens: 550}
+	if u == nil || *u != want {
+		t.Fatalf("UsageOf() = %+v, want %+v", u, want)
+	}
+	if got := u.String(); got != "100 prompt, 20 output, 30 thinking, 400 tool, 550 total" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := EstimateIndexingTokens(10); got != 3 {
+		t.Errorf("EstimateIndexingTokens(10) = %d, want 3", got)
+	}
+}
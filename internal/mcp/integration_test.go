@@ -13,7 +13,7 @@ func TestMCPServerIntegration(t *testing.T) {
 	mockClient := &MockGeminiClient{}
 	enabledTools := []string{"all"}
 
-	server := NewServer(mockClient, enabledTools)
+	server := NewServer(mockClient, enabledTools, nil)
 
 	// Expected tools with their required parameters
 	expectedTools := map[string][]string{
@@ -24,6 +24,7 @@ func TestMCPServerIntegration(t *testing.T) {
 		"delete_store":         {"store_name"},
 		"import_file_to_store": {"file_name", "store_name"},
 		"query_knowledge_base": {"query"},
+		"search_passages":      {"query", "store_name"},
 		"upload_file":          {"path", "name"},
 		"delete_file":          {"file_name"},
 		"delete_document":      {"store_name", "document_name"},
@@ -56,7 +57,7 @@ func TestMCPServerMetadataParameters(t *testing.T) {
 	mockClient := &MockGeminiClient{}
 	enabledTools := []string{"all"}
 
-	server := NewServer(mockClient, enabledTools)
+	server := NewServer(mockClient, enabledTools, nil)
 	tools := getRegisteredTools(server)
 
 	// Verify query_knowledge_base and upload_file are present
@@ -102,7 +103,7 @@ func TestMCPServerSelectiveTools(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			server := NewServer(mockClient, tt.enabledTools)
+			server := NewServer(mockClient, tt.enabledTools, nil)
 			tools := getRegisteredTools(server)
 
 			for _, expected := range tt.expectedTools {
@@ -122,7 +123,7 @@ func TestMCPServerNoAuth(t *testing.T) {
 	enabledTools := []string{"all"}
 
 	// This should not panic or error
-	server := NewServer(nilClient, enabledTools)
+	server := NewServer(nilClient, enabledTools, nil)
 	if server == nil {
 		t.Fatal("Server should be created even without client")
 	}
@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"google.golang.org/genai"
+)
+
+// Detail levels for query_knowledge_base results
+const (
+	// DetailBrief returns the cited answer and source titles
+	DetailBrief = "brief"
+	// DetailStandard adds a short snippet of each source
+	DetailStandard = "standard"
+	// DetailFull returns the complete GenerateContentResponse
+	DetailFull = "full"
+)
+
+const (
+	// DefaultMaxChars is the default character budget for the answer and
+	// source snippets of a query result
+	DefaultMaxChars = 8000
+	// snippetChars is the longest snippet of a source at standard detail
+	snippetChars = 300
+	// minSnippetChars is the shortest snippet worth keeping when the budget
+	// is tight
+	minSnippetChars = 40
+)
+
+// queryResult is the compact result of a query_knowledge_base call
+type queryResult struct {
+	// Answer has inline [n] markers citing Sources by index
+	Answer  string          `json:"answer"`
+	Sources []gemini.Source `json:"sources"`
+	// Truncated is set when the answer or snippets were cut to fit the budget
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// shapeQueryResult reduces a query response to the requested detail level.
+// At brief and standard detail, the answer and snippets are cut to maxChars
+// characters in total (0 for no limit), shortening snippets before the answer.
+func shapeQueryResult(resp *genai.GenerateContentResponse, detail string, maxChars int) (any, error) {
+	switch detail {
+	case DetailFull:
+		return resp, nil
+	case DetailBrief, DetailStandard:
+	default:
+		return nil, fmt.Errorf("invalid detail %q (valid: %s, %s, %s)", detail, DetailBrief, DetailStandard, DetailFull)
+	}
+
+	compact := gemini.NewCitedAnswer(resp).Compact()
+	result := &queryResult{Answer: compact.Answer, Sources: compact.Sources}
+	if detail == DetailStandard {
+		full := gemini.Sources(resp)
+		for i := range result.Sources {
+			result.Sources[i].Text = snippet(full[i].Text, snippetChars)
+		}
+	}
+	if maxChars > 0 {
+		result.fit(maxChars)
+	}
+	return result, nil
+}
+
+// fit shortens the snippets, then the answer, to fit within maxChars
+func (r *queryResult) fit(maxChars int) {
+	answerChars := utf8.RuneCountInString(r.Answer)
+	total := answerChars
+	withText := 0
+	for _, src := range r.Sources {
+		total += utf8.RuneCountInString(src.Text)
+		if src.Text != "" {
+			withText++
+		}
+	}
+	if total <= maxChars {
+		return
+	}
+	r.Truncated = true
+
+	// Share what the answer leaves over equally between the snippets
+	perSnippet := 0
+	if withText > 0 && answerChars < maxChars {
+		perSnippet = (maxChars - answerChars) / withText
+	}
+	for i := range r.Sources {
+		if perSnippet < minSnippetChars {
+			r.Sources[i].Text = ""
+		} else {
+			r.Sources[i].Text = snippet(r.Sources[i].Text, perSnippet)
+		}
+	}
+	r.Answer = truncate(r.Answer, maxChars)
+}
+
+// snippet collapses whitespace in text and truncates it to maxChars
+func snippet(text string, maxChars int) string {
+	return truncate(strings.Join(strings.Fields(text), " "), maxChars)
+}
+
+// truncate cuts s to at most maxChars characters (runes), ending in "..."
+// if it was cut
+func truncate(s string, maxChars int) string {
+	if utf8.RuneCountInString(s) <= maxChars {
+		return s
+	}
+	if maxChars <= 3 {
+		return ""
+	}
+	cut, n := 0, 0
+	for i := range s {
+		if n == maxChars-3 {
+			cut = i
+			break
+		}
+		n++
+	}
+	return s[:cut] + "..."
+}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"google.golang.org/genai"
+)
+
+func TestShapeQueryResult(t *testing.T) {
+	answer := "The limit is 40 V."
+	resp := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		Content: genai.NewContentFromText(answer, genai.RoleModel),
+		GroundingMetadata: &genai.GroundingMetadata{
+			GroundingChunks: []*genai.GroundingChunk{
+				{RetrievedContext: &genai.GroundingChunkRetrievedContext{Title: "a.pdf", Text: strings.Repeat("alpha ", 100)}},
+				{RetrievedContext: &genai.GroundingChunkRetrievedContext{Title: "b.pdf", Text: "short\n\nchunk"}},
+			},
+			GroundingSupports: []*genai.GroundingSupport{
+				{Segment: &genai.Segment{EndIndex: int32(len(answer)), Text: answer}, GroundingChunkIndices: []int32{0, 1}},
+			},
+		},
+	}}}
+
+	shape := func(t *testing.T, detail string, maxChars int) *queryResult {
+		t.Helper()
+		result, err := shapeQueryResult(resp, detail, maxChars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.(*queryResult)
+	}
+
+	t.Run("brief", func(t *testing.T) {
+		r := shape(t, DetailBrief, 0)
+		if r.Answer != "The limit is 40 V.[1][2]" || r.Sources[0].Text != "" || r.Sources[1].Title != "b.pdf" {
+			t.Errorf("unexpected brief result: %+v", r)
+		}
+	})
+
+	t.Run("standard", func(t *testing.T) {
+		r := shape(t, DetailStandard, 0)
+		if len(r.Sources[0].Text) != snippetChars || !strings.HasSuffix(r.Sources[0].Text, "...") {
+			t.Errorf("expected a truncated snippet, got %d chars", len(r.Sources[0].Text))
+		}
+		if r.Sources[1].Text != "short chunk" || r.Truncated {
+			t.Errorf("unexpected snippet: %+v", r.Sources[1])
+		}
+	})
+
+	t.Run("budget shortens snippets first", func(t *testing.T) {
+		r := shape(t, DetailStandard, 150)
+		if !r.Truncated || r.Answer != "The limit is 40 V.[1][2]" {
+			t.Errorf("unexpected answer: %+v", r)
+		}
+		total := len(r.Answer) + len(r.Sources[0].Text) + len(r.Sources[1].Text)
+		if total > 150 || r.Sources[0].Text == "" {
+			t.Errorf("expected snippets within the budget, got %d chars: %+v", total, r.Sources)
+		}
+	})
+
+	t.Run("budget smaller than the answer", func(t *testing.T) {
+		r := shape(t, DetailStandard, 10)
+		if r.Answer != "The lim..." || r.Sources[0].Text != "" {
+			t.Errorf("unexpected result: %+v", r)
+		}
+	})
+
+	t.Run("full", func(t *testing.T) {
+		result, err := shapeQueryResult(resp, DetailFull, 10)
+		if err != nil || result != resp {
+			t.Errorf("expected the full response, got %v %v", result, err)
+		}
+	})
+
+	if _, err := shapeQueryResult(resp, "verbose", 0); err == nil {
+		t.Error("expected an error for an unknown detail level")
+	}
+}
+
+func TestFitCountsCharacters(t *testing.T) {
+	// Each of these is three bytes in UTF-8
+	r := &queryResult{
+		Answer:  strings.Repeat("電", 20),
+		Sources: []gemini.Source{{Title: "a.pdf", Text: strings.Repeat("圧", 150)}},
+	}
+	r.fit(120)
+	if !r.Truncated || r.Answer != strings.Repeat("電", 20) {
+		t.Errorf("expected the answer to fit by characters, got %q", r.Answer)
+	}
+	if n := utf8.RuneCountInString(r.Sources[0].Text); n != 100 || !utf8.ValidString(r.Sources[0].Text) {
+		t.Errorf("expected a 100-character snippet, got %d: %q", n, r.Sources[0].Text)
+	}
+
+	if got := truncate("héllo wörld", 8); got != "héllo..." {
+		t.Errorf("expected a cut on a character boundary, got %q", got)
+	}
+	if got := truncate("日本語", 3); got != "日本語" {
+		t.Errorf("expected a string within the budget unchanged, got %q", got)
+	}
+}
@@ -3,12 +3,18 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/mikesmitty/file-search/internal/config"
 	"github.com/mikesmitty/file-search/internal/constants"
+	"github.com/mikesmitty/file-search/internal/convert"
 	"github.com/mikesmitty/file-search/internal/gemini"
+	"github.com/mikesmitty/file-search/internal/schema"
+	"github.com/mikesmitty/file-search/internal/split"
+	"github.com/mikesmitty/file-search/internal/upload"
 	"google.golang.org/genai"
 )
 
@@ -17,12 +23,14 @@ type GeminiClient interface {
 	ListStores(ctx context.Context) ([]*genai.FileSearchStore, error)
 	ListFiles(ctx context.Context) ([]*genai.File, error)
 	ResolveStoreName(ctx context.Context, nameOrID string) (string, error)
+	GetStore(ctx context.Context, name string) (*genai.FileSearchStore, error)
 	ListDocuments(ctx context.Context, storeID string) ([]*genai.Document, error)
 	CreateStore(ctx context.Context, displayName string) (*genai.FileSearchStore, error)
 	DeleteStore(ctx context.Context, name string, force bool) error
 	ResolveFileName(ctx context.Context, nameOrID string) (string, error)
 	ImportFile(ctx context.Context, fileID, storeID string, opts *gemini.ImportFileOptions) error
-	Query(ctx context.Context, text string, storeName string, modelName string, metadataFilter string) (*genai.GenerateContentResponse, error)
+	Query(ctx context.Context, text string, storeName string, modelName string, opts *gemini.QueryOptions) (*genai.GenerateContentResponse, error)
+	SearchPassages(ctx context.Context, text string, storeName string, modelName string, opts *gemini.QueryOptions) ([]gemini.Source, error)
 	UploadFile(ctx context.Context, path string, opts *gemini.UploadFileOptions) (*genai.File, error)
 	DeleteFile(ctx context.Context, name string) error
 	ResolveDocumentName(ctx context.Context, storeNameOrID, docNameOrID string) (string, error)
@@ -30,17 +38,47 @@ type GeminiClient interface {
 	Close()
 }
 
-func RunServer(ctx context.Context, client GeminiClient, enabledTools []string) error {
-	s := NewServer(client, enabledTools)
+// ServerOptions provides optional configuration for the MCP server.
+type ServerOptions struct {
+	// Converters pre-processes uploaded files (nil disables conversion)
+	Converters *convert.Registry
+	// StoreProfiles provides per-store upload defaults, overridden by tool arguments
+	StoreProfiles config.StoreProfiles
+	// DefaultModel is the model used for queries when none is given (default: constants.DefaultModel)
+	DefaultModel string
+	// QueryDefaults are the generation settings for queries, overridden by tool arguments
+	QueryDefaults *gemini.QueryOptions
+	// DefaultStore is used by store-scoped tools when store_name is omitted.
+	// It is never used by delete_store.
+	DefaultStore string
+}
+
+func RunServer(ctx context.Context, client GeminiClient, enabledTools []string, opts *ServerOptions) error {
+	s := NewServer(client, enabledTools, opts)
 	return server.ServeStdio(s)
 }
 
 // NewServer creates a new MCP server instance with the configured tools.
 // It is exported to allow testing of the server configuration and tool registration.
-func NewServer(client GeminiClient, enabledTools []string) *server.MCPServer {
+func NewServer(client GeminiClient, enabledTools []string, opts *ServerOptions) *server.MCPServer {
+	if opts == nil {
+		opts = &ServerOptions{}
+	}
+	if opts.DefaultModel == "" {
+		opts.DefaultModel = constants.DefaultModel
+	}
+
+	// Label requests with the tool that made them, so their token usage is
+	// attributed to it
+	serverOpts := []server.ServerOption{server.WithToolHandlerMiddleware(labelUsage)}
+	if opts.DefaultStore != "" {
+		serverOpts = append(serverOpts, server.WithInstructions(fmt.Sprintf(
+			"The default File Search Store is %q. Tools that take store_name use it when store_name is omitted.", opts.DefaultStore)))
+	}
 	s := server.NewMCPServer(
 		"Gemini File Search",
 		"1.0.0",
+		serverOpts...,
 	)
 
 	// Helper to check if a tool is enabled
@@ -71,8 +109,8 @@ func NewServer(client GeminiClient, enabledTools []string) *server.MCPServer {
 	if isToolEnabled("list_documents") || isToolEnabled("all") {
 		s.AddTool(mcp.NewTool("list_documents",
 			mcp.WithDescription("List all documents within a specified File Search Store. Returns a JSON array of document objects."),
-			mcp.WithString("store_name", mcp.Required(), mcp.Description("The resource name or display name of the store to list documents from.")),
-		), makeListDocumentsHandler(client))
+			mcp.WithString("store_name", storeNameOptions(opts, "The resource name or display name of the store to list documents from.")...),
+		), makeListDocumentsHandler(client, opts))
 	}
 
 	// Tool: create_store
@@ -122,7 +160,7 @@ func NewServer(client GeminiClient, enabledTools []string) *server.MCPServer {
 			// Resolve store name
 			storeID, err := client.ResolveStoreName(ctx, storeName)
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve store name: %v", err)), nil
+				return resolveError("store", err), nil
 			}
 
 			err = client.DeleteStore(ctx, storeID, force)
@@ -138,7 +176,7 @@ func NewServer(client GeminiClient, enabledTools []string) *server.MCPServer {
 		s.AddTool(mcp.NewTool("import_file_to_store",
 			mcp.WithDescription("Import a file from the Files API into a File Search Store. Note: This does not preserve the original display name of the file."),
 			mcp.WithString("file_name", mcp.Required(), mcp.Description("The resource name or display name of the file to import.")),
-			mcp.WithString("store_name", mcp.Required(), mcp.Description("The resource name or display name of the store to import into.")),
+			mcp.WithString("store_name", storeNameOptions(opts, "The resource name or display name of the store to import into.")...),
 		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 			args, ok := request.Params.Arguments.(map[string]interface{})
 			if !ok {
@@ -148,7 +186,7 @@ func NewServer(client GeminiClient, enabledTools []string) *server.MCPServer {
 			if !ok {
 				return mcp.NewToolResultError("file_name must be a string"), nil
 			}
-			storeName, ok := getStringArg(args, "store_name")
+			storeName, ok := getStoreArg(args, opts)
 			if !ok {
 				return mcp.NewToolResultError("store_name must be a string"), nil
 			}
@@ -156,19 +194,30 @@ func NewServer(client GeminiClient, enabledTools []string) *server.MCPServer {
 			// Resolve file name
 			fileID, err := client.ResolveFileName(ctx, fileName)
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve file name: %v", err)), nil
+				return resolveError("file", err), nil
 			}
 
 			// Resolve store name
 			storeID, err := client.ResolveStoreName(ctx, storeName)
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve store name: %v", err)), nil
+				return resolveError("store", err), nil
+			}
+
+			// Apply the store's configured chunking and metadata defaults
+			importOpts := &gemini.ImportFileOptions{Quiet: true}
+			if profile := lookupStoreProfile(ctx, client, opts, storeName, storeID); profile != nil {
+				if !profile.Allows(fileName) {
+					return mcp.NewToolResultError(fmt.Sprintf("%s is excluded by the include/exclude patterns configured for store %s", fileName, storeName)), nil
+				}
+				importOpts.MaxChunkTokens = profile.ChunkSize
+				importOpts.ChunkOverlap = profile.ChunkOverlap
+				importOpts.Metadata = profile.MergeMetadata(nil)
 			}
 
 			// Note: ImportFile now returns error only, but prints progress to stdout if not quiet.
 			// Since we are in MCP, we can't easily stream progress.
 			// We'll use Quiet=true to avoid stdout noise and just wait for completion.
-			err = client.ImportFile(ctx, fileID, storeID, &gemini.ImportFileOptions{Quiet: true})
+			err = client.ImportFile(ctx, fileID, storeID, importOpts)
 			if err != nil {
 				return mcp.NewToolResultError(err.Error()), nil
 			}
@@ -179,12 +228,34 @@ func NewServer(client GeminiClient, enabledTools []string) *server.MCPServer {
 	// Tool: query_knowledge_base
 	if isToolEnabled("query_knowledge_base") || isToolEnabled("query") || isToolEnabled("all") {
 		s.AddTool(mcp.NewTool("query_knowledge_base",
-			mcp.WithDescription("Query the knowledge base using Gemini File Search. Use this to answer questions based on uploaded documents."),
+			mcp.WithDescription("Query the knowledge base using Gemini File Search. Use this to answer questions based on uploaded documents. Returns the answer with inline [n] citation markers and the numbered sources they refer to."),
 			mcp.WithString("query", mcp.Required(), mcp.Description("The question or query to ask.")),
-			mcp.WithString("store_name", mcp.Description("The resource name or display name of the store to search. If omitted, searches all stores (if supported) or requires specific configuration.")),
-			mcp.WithString("model", mcp.Description("The model to use (default: "+constants.DefaultModel+").")),
+			mcp.WithString("store_name", mcp.Description(withDefaultStore(opts, "The resource name or display name of the store to search. If omitted, searches all stores (if supported) or requires specific configuration."))),
+			mcp.WithString("model", mcp.Description("The model to use (default: "+opts.DefaultModel+").")),
 			mcp.WithString("metadata_filter", mcp.Description("Optional metadata filter expression to narrow search results. Examples: 'category = \"research\"' for exact match, 'status = \"reviewed\" AND priority = \"high\"' for multiple conditions, 'author = \"Smith\"' for filtering by author metadata.")),
-		), makeQueryKnowledgeBaseHandler(client))
+			mcp.WithString("system_instruction", mcp.Description("Optional system instruction guiding the style of the answer, e.g. 'Answer in one sentence and quote exact values.'")),
+			mcp.WithNumber("temperature", mcp.Description("Optional sampling temperature. Use 0 for deterministic answers.")),
+			mcp.WithNumber("top_p", mcp.Description("Optional nucleus sampling probability.")),
+			mcp.WithNumber("max_output_tokens", mcp.Description("Optional maximum number of tokens in the answer.")),
+			mcp.WithNumber("thinking_budget", mcp.Description("Optional thinking token budget. 0 disables thinking on models that allow it.")),
+			mcp.WithString("thinking_level", mcp.Description("Optional thinking level: minimal, low, medium or high.")),
+			mcp.WithArray("stop_sequences", mcp.WithStringItems(), mcp.Description("Optional sequences that end the answer when generated.")),
+			mcp.WithString("detail", mcp.Enum(DetailBrief, DetailStandard, DetailFull), mcp.Description("Result detail: brief (cited answer and source titles), standard (adds short source snippets, the default) or full (the complete API response).")),
+			mcp.WithNumber("max_chars", mcp.Description(fmt.Sprintf("Character budget for the answer and snippets at brief and standard detail (default: %d, 0 for no limit). Snippets are shortened before the answer.", DefaultMaxChars))),
+			mcp.WithObject("response_schema", mcp.Description("Optional JSON Schema for the answer. When set, the answer is returned as JSON validated against the schema, together with its grounding sources.")),
+			mcp.WithObject("safety_settings", mcp.Description("Optional safety thresholds by harm category, e.g. {\"dangerous_content\": \"block_only_high\"}. Categories: harassment, hate_speech, sexually_explicit, dangerous_content, civic_integrity. Thresholds: block_low_and_above, block_medium_and_above, block_only_high, block_none, off.")),
+		), makeQueryKnowledgeBaseHandler(client, opts))
+	}
+
+	// Tool: search_passages
+	if isToolEnabled("search_passages") || isToolEnabled("search") || isToolEnabled("all") {
+		s.AddTool(mcp.NewTool("search_passages",
+			mcp.WithDescription("Retrieve the passages of a File Search Store relevant to a query, without a generated answer. Returns a ranked JSON array of passages with document title, document and store names, page span and text."),
+			mcp.WithString("query", mcp.Required(), mcp.Description("The question or search query.")),
+			mcp.WithString("store_name", storeNameOptions(opts, "The resource name or display name of the store to search.")...),
+			mcp.WithString("model", mcp.Description("The model used for retrieval (default: "+opts.DefaultModel+").")),
+			mcp.WithString("metadata_filter", mcp.Description("Optional metadata filter expression to narrow search results, e.g. 'category = \"research\"'.")),
+		), makeSearchPassagesHandler(client, opts))
 	}
 
 	// Tool: upload_file
@@ -192,11 +263,14 @@ func NewServer(client GeminiClient, enabledTools []string) *server.MCPServer {
 		s.AddTool(mcp.NewTool("upload_file",
 			mcp.WithDescription("Upload a local file to Gemini Files API and optionally add it to a store."),
 			mcp.WithString("path", mcp.Required(), mcp.Description("Absolute path to the local file.")),
-			mcp.WithString("store_name", mcp.Description("The resource name or display name of the store to add the file to.")),
+			mcp.WithString("store_name", mcp.Description(withDefaultStore(opts, "The resource name or display name of the store to add the file to."))),
+			mcp.WithBoolean("no_store", mcp.Description("Upload to the Files API only, without adding the file to the default store.")),
 			mcp.WithString("name", mcp.Description("The display name of the file (optional).")),
 			mcp.WithString("mime_type", mcp.Description("The MIME type of the file (optional).")),
+			mcp.WithBoolean("split", mcp.Description("Split large text/Markdown files on headings and large PDFs by page ranges into separate documents.")),
+			mcp.WithString("on_duplicate", mcp.Enum(gemini.DuplicatePolicies...), mcp.Description("What to do when the store already has a document with the same name or content: skip (the default; skips content already in the store, and fails for changed content under an existing name), replace (upload, then delete the old copy), keep (upload another copy) or error.")),
 			mcp.WithString("metadata", mcp.Description("Optional metadata as a JSON string. Examples: '{\"category\": \"research\", \"author\": \"Smith\"}' for multiple fields, '{\"status\": \"draft\"}' for single field, '{\"project\": \"Q4-2024\", \"priority\": \"high\"}' for project tracking. Only used if store_name is provided.")),
-		), makeUploadFileHandler(client))
+		), makeUploadFileHandler(client, opts))
 	}
 
 	// Tool: delete_file
@@ -216,7 +290,7 @@ func NewServer(client GeminiClient, enabledTools []string) *server.MCPServer {
 
 			fileID, err := client.ResolveFileName(ctx, fileName)
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve file name: %v", err)), nil
+				return resolveError("file", err), nil
 			}
 
 			err = client.DeleteFile(ctx, fileID)
@@ -231,7 +305,7 @@ func NewServer(client GeminiClient, enabledTools []string) *server.MCPServer {
 	if isToolEnabled("delete_document") || isToolEnabled("delete") || isToolEnabled("all") {
 		s.AddTool(mcp.NewTool("delete_document",
 			mcp.WithDescription("Delete a document from a File Search Store."),
-			mcp.WithString("store_name", mcp.Required(), mcp.Description("The resource name or display name of the store.")),
+			mcp.WithString("store_name", storeNameOptions(opts, "The resource name or display name of the store.")...),
 			mcp.WithString("document_name", mcp.Required(), mcp.Description("The resource name or display name of the document.")),
 			mcp.WithBoolean("force", mcp.Description("Force delete even if the document contains chunks.")),
 		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -239,7 +313,7 @@ func NewServer(client GeminiClient, enabledTools []string) *server.MCPServer {
 			if !ok {
 				return mcp.NewToolResultError("arguments must be a map"), nil
 			}
-			storeName, ok := getStringArg(args, "store_name")
+			storeName, ok := getStoreArg(args, opts)
 			if !ok {
 				return mcp.NewToolResultError("store_name must be a string"), nil
 			}
@@ -252,13 +326,13 @@ func NewServer(client GeminiClient, enabledTools []string) *server.MCPServer {
 			// Resolve store
 			storeID, err := client.ResolveStoreName(ctx, storeName)
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve store name: %v", err)), nil
+				return resolveError("store", err), nil
 			}
 
 			// Resolve document
 			docID, err := client.ResolveDocumentName(ctx, storeID, docName)
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve document name: %v", err)), nil
+				return resolveError("document", err), nil
 			}
 
 			err = client.DeleteDocument(ctx, docID, force)
@@ -282,6 +356,42 @@ func getStringArg(args map[string]interface{}, key string) (string, bool) {
 	return str, ok
 }
 
+// getStoreArg returns the store_name argument, falling back to the server's
+// default store when it is omitted or empty
+func getStoreArg(args map[string]interface{}, opts *ServerOptions) (string, bool) {
+	if val, ok := args["store_name"]; (!ok || val == "") && opts.DefaultStore != "" {
+		return opts.DefaultStore, true
+	}
+	return getStringArg(args, "store_name")
+}
+
+// storeNameOptions returns the property options for a store_name argument,
+// which is only required when there is no default store
+func storeNameOptions(opts *ServerOptions, description string) []mcp.PropertyOption {
+	if opts.DefaultStore == "" {
+		return []mcp.PropertyOption{mcp.Required(), mcp.Description(description)}
+	}
+	return []mcp.PropertyOption{mcp.Description(withDefaultStore(opts, description))}
+}
+
+// withDefaultStore appends the default store to a store_name description
+func withDefaultStore(opts *ServerOptions, description string) string {
+	if opts.DefaultStore == "" {
+		return description
+	}
+	return fmt.Sprintf("%s Defaults to %q.", description, opts.DefaultStore)
+}
+
+// Helper to get number argument (JSON numbers decode as float64)
+func getNumberArg(args map[string]interface{}, key string) (float64, bool) {
+	val, ok := args[key]
+	if !ok {
+		return 0, false
+	}
+	n, ok := val.(float64)
+	return n, ok
+}
+
 // Helper to get bool argument
 func getBoolArg(args map[string]interface{}, key string) bool {
 	val, ok := args[key]
@@ -292,7 +402,7 @@ func getBoolArg(args map[string]interface{}, key string) bool {
 	return b && ok
 }
 
-func makeQueryKnowledgeBaseHandler(client GeminiClient) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func makeQueryKnowledgeBaseHandler(client GeminiClient, opts *ServerOptions) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		if client == nil {
 			return mcp.NewToolResultError("Gemini API key not configured. Please set GEMINI_API_KEY environment variable."), nil
@@ -306,27 +416,61 @@ func makeQueryKnowledgeBaseHandler(client GeminiClient) func(ctx context.Context
 		if !ok {
 			return mcp.NewToolResultError("query must be a string"), nil
 		}
-		storeName, _ := getStringArg(args, "store_name")
+		storeName, _ := getStoreArg(args, opts)
 		model, _ := getStringArg(args, "model")
 		if model == "" {
-			model = constants.DefaultModel
+			model = opts.DefaultModel
+		}
+		queryOpts, err := queryOptions(args, opts.QueryDefaults)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		detail, _ := getStringArg(args, "detail")
+		if detail == "" {
+			detail = DetailStandard
+		}
+		maxChars := DefaultMaxChars
+		if v, ok := getNumberArg(args, "max_chars"); ok {
+			maxChars = int(v)
+		}
+
+		var responseSchema *schema.Schema
+		if raw, ok := args["response_schema"].(map[string]interface{}); ok {
+			responseSchema, err = schema.FromValue(raw)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			queryOpts.ResponseSchema = responseSchema.Document()
 		}
-		metadataFilter, _ := getStringArg(args, "metadata_filter")
 
 		var storeID string
-		var err error
 		if storeName != "" {
 			storeID, err = client.ResolveStoreName(ctx, storeName)
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve store name: %v", err)), nil
+				return resolveError("store", err), nil
 			}
 		}
 
-		resp, err := client.Query(ctx, query, storeID, model, metadataFilter)
+		resp, err := client.Query(ctx, query, storeID, model, queryOpts)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if responseSchema != nil {
+			answer, err := responseSchema.Validate(resp.Text())
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("%v\nAnswer:\n%s", err, resp.Text())), nil
+			}
+			res, err := mcp.NewToolResultJSON(&gemini.StructuredAnswer{Answer: answer, Sources: gemini.Sources(resp), Usage: gemini.UsageOf(resp)})
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return res, nil
+		}
+		result, err := shapeQueryResult(resp, detail, maxChars)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		res, err := mcp.NewToolResultJSON(resp)
+		res, err := mcp.NewToolResultJSON(result)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
@@ -334,6 +478,112 @@ func makeQueryKnowledgeBaseHandler(client GeminiClient) func(ctx context.Context
 	}
 }
 
+func makeSearchPassagesHandler(client GeminiClient, opts *ServerOptions) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if client == nil {
+			return mcp.NewToolResultError("Gemini API key not configured. Please set GEMINI_API_KEY environment variable."), nil
+		}
+
+		args, ok := request.Params.Arguments.(map[string]interface{})
+		if !ok {
+			return mcp.NewToolResultError("arguments must be a map"), nil
+		}
+		query, ok := getStringArg(args, "query")
+		if !ok {
+			return mcp.NewToolResultError("query must be a string"), nil
+		}
+		storeName, ok := getStoreArg(args, opts)
+		if !ok || storeName == "" {
+			return mcp.NewToolResultError("store_name must be a string"), nil
+		}
+		model, _ := getStringArg(args, "model")
+		if model == "" {
+			model = opts.DefaultModel
+		}
+		queryOpts, err := queryOptions(args, opts.QueryDefaults)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		storeID, err := client.ResolveStoreName(ctx, storeName)
+		if err != nil {
+			return resolveError("store", err), nil
+		}
+
+		passages, err := client.SearchPassages(ctx, query, storeID, model, queryOpts)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if passages == nil {
+			passages = []gemini.Source{}
+		}
+		res, err := mcp.NewToolResultJSON(passages)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return res, nil
+	}
+}
+
+// queryOptions applies the generation arguments of a query_knowledge_base call
+// over the server's configured defaults
+func queryOptions(args map[string]interface{}, defaults *gemini.QueryOptions) (*gemini.QueryOptions, error) {
+	var opts gemini.QueryOptions
+	if defaults != nil {
+		opts = *defaults
+	}
+	opts.MetadataFilter, _ = getStringArg(args, "metadata_filter")
+
+	if v, ok := getStringArg(args, "system_instruction"); ok && v != "" {
+		opts.SystemInstruction = v
+	}
+	if v, ok := getNumberArg(args, "temperature"); ok {
+		opts.Temperature = genai.Ptr(float32(v))
+	}
+	if v, ok := getNumberArg(args, "top_p"); ok {
+		opts.TopP = genai.Ptr(float32(v))
+	}
+	if v, ok := getNumberArg(args, "max_output_tokens"); ok {
+		opts.MaxOutputTokens = int32(v)
+	}
+	if v, ok := getNumberArg(args, "thinking_budget"); ok {
+		opts.ThinkingBudget = genai.Ptr(int32(v))
+	}
+	if v, ok := getStringArg(args, "thinking_level"); ok && v != "" {
+		if _, err := gemini.ParseThinkingLevel(v); err != nil {
+			return nil, err
+		}
+		opts.ThinkingLevel = v
+	}
+	if raw, ok := args["safety_settings"].(map[string]interface{}); ok {
+		// Arguments override matching categories from the defaults
+		merged := make(map[string]string, len(opts.SafetySettings)+len(raw))
+		for k, v := range opts.SafetySettings {
+			merged[k] = v
+		}
+		for k, v := range raw {
+			threshold, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("safety_settings.%s must be a string", k)
+			}
+			merged[k] = threshold
+		}
+		if _, err := gemini.ParseSafetySettings(merged); err != nil {
+			return nil, err
+		}
+		opts.SafetySettings = merged
+	}
+	if raw, ok := args["stop_sequences"].([]interface{}); ok {
+		opts.StopSequences = nil
+		for _, item := range raw {
+			if s, ok := item.(string); ok {
+				opts.StopSequences = append(opts.StopSequences, s)
+			}
+		}
+	}
+	return &opts, nil
+}
+
 func makeListStoresHandler(client GeminiClient) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		if client == nil {
@@ -372,7 +622,7 @@ func makeListFilesHandler(client GeminiClient) func(ctx context.Context, request
 	}
 }
 
-func makeListDocumentsHandler(client GeminiClient) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func makeListDocumentsHandler(client GeminiClient, opts *ServerOptions) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		if client == nil {
 			return mcp.NewToolResultError("Gemini API key not configured. Please set GEMINI_API_KEY environment variable."), nil
@@ -381,7 +631,7 @@ func makeListDocumentsHandler(client GeminiClient) func(ctx context.Context, req
 		if !ok {
 			return mcp.NewToolResultError("arguments must be a map"), nil
 		}
-		storeName, ok := getStringArg(args, "store_name")
+		storeName, ok := getStoreArg(args, opts)
 		if !ok {
 			return mcp.NewToolResultError("store_name must be a string"), nil
 		}
@@ -389,7 +639,7 @@ func makeListDocumentsHandler(client GeminiClient) func(ctx context.Context, req
 		// Resolve store name
 		storeID, err := client.ResolveStoreName(ctx, storeName)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve store name: %v", err)), nil
+			return resolveError("store", err), nil
 		}
 
 		docs, err := client.ListDocuments(ctx, storeID)
@@ -406,7 +656,7 @@ func makeListDocumentsHandler(client GeminiClient) func(ctx context.Context, req
 	}
 }
 
-func makeUploadFileHandler(client GeminiClient) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func makeUploadFileHandler(client GeminiClient, opts *ServerOptions) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		args, ok := request.Params.Arguments.(map[string]interface{})
 		if !ok {
@@ -417,6 +667,9 @@ func makeUploadFileHandler(client GeminiClient) func(ctx context.Context, reques
 			return mcp.NewToolResultError("path must be a string"), nil
 		}
 		storeName, _ := getStringArg(args, "store_name")
+		if !getBoolArg(args, "no_store") {
+			storeName, _ = getStoreArg(args, opts)
+		}
 		displayName, _ := getStringArg(args, "name")
 		mimeType, _ := getStringArg(args, "mime_type")
 		metadataJSON, _ := getStringArg(args, "metadata")
@@ -434,32 +687,188 @@ func makeUploadFileHandler(client GeminiClient) func(ctx context.Context, reques
 		if storeName != "" {
 			storeID, err = client.ResolveStoreName(ctx, storeName)
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve store name: %v", err)), nil
+				return resolveError("store", err), nil
+			}
+		}
+
+		// Apply the store's configured defaults; explicit arguments win
+		var chunkSize, chunkOverlap int
+		var recordSource bool
+		onDuplicate, _ := getStringArg(args, "on_duplicate")
+		if storeID != "" {
+			profile := lookupStoreProfile(ctx, client, opts, storeName, storeID)
+			if !profile.Allows(path) {
+				return mcp.NewToolResultError(fmt.Sprintf("%s is excluded by the include/exclude patterns configured for store %s", path, storeName)), nil
 			}
+			metadata = profile.MergeMetadata(metadata)
+			if mimeType == "" {
+				mimeType = profile.MIMETypeFor(path)
+			}
+			if profile != nil {
+				chunkSize, chunkOverlap = profile.ChunkSize, profile.ChunkOverlap
+				recordSource = profile.RecordSource
+				if onDuplicate == "" {
+					onDuplicate = profile.OnDuplicate
+				}
+			}
+		}
+		policy, err := gemini.ParseDuplicatePolicy(onDuplicate)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		opts := &gemini.UploadFileOptions{
-			StoreName:   storeID,
-			DisplayName: displayName,
-			MIMEType:    mimeType,
-			Metadata:    metadata,
-			Quiet:       true, // Suppress stdout progress
+		prepareOpts := &upload.Options{Converters: opts.Converters, RecordSource: recordSource}
+		if getBoolArg(args, "split") {
+			prepareOpts.Split = &split.Options{}
 		}
 
-		file, err := client.UploadFile(ctx, path, opts)
+		// Convert unsupported formats and split oversized files before upload
+		prepared, err := upload.Prepare(ctx, path, displayName, prepareOpts)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
+		defer prepared.Cleanup()
 
-		// If file is nil, it means it was uploaded to a store (UploadFile returns nil for store uploads as it handles the operation)
-		if file == nil {
-			return mcp.NewToolResultText(fmt.Sprintf("Uploaded %s to store %s", path, storeName)), nil
+		if len(prepared.Items) == 1 {
+			item := prepared.Items[0]
+			if mimeType == "" {
+				mimeType = item.MIMEType
+			}
+			file, err := client.UploadFile(ctx, item.Path, &gemini.UploadFileOptions{
+				StoreName:      storeID,
+				DisplayName:    item.DisplayName,
+				MIMEType:       mimeType,
+				MaxChunkTokens: chunkSize,
+				ChunkOverlap:   chunkOverlap,
+				Metadata:       convert.WithProvenance(metadata, item.Metadata),
+				Quiet:          true, // Suppress stdout progress
+				OnDuplicate:    policy,
+			})
+			if err != nil {
+				return duplicateResult(err), nil
+			}
+			return uploadResult(file, path, storeName)
 		}
 
-		res, err := mcp.NewToolResultJSON(file)
+		// Converted or split files are uploaded as individual documents
+		var uploaded []string
+		var skipped []*gemini.DuplicateDocumentError
+		for _, item := range prepared.Items {
+			_, err := client.UploadFile(ctx, item.Path, &gemini.UploadFileOptions{
+				StoreName:      storeID,
+				DisplayName:    item.DisplayName,
+				MIMEType:       item.MIMEType,
+				MaxChunkTokens: chunkSize,
+				ChunkOverlap:   chunkOverlap,
+				Metadata:       convert.WithProvenance(metadata, item.Metadata),
+				Quiet:          true,
+				OnDuplicate:    policy,
+			})
+			var dup *gemini.DuplicateDocumentError
+			if errors.As(err, &dup) && dup.Skipped {
+				skipped = append(skipped, dup)
+				continue
+			}
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to upload %s (uploaded %d of %d): %v", item.DisplayName, len(uploaded), len(prepared.Items), err)), nil
+			}
+			uploaded = append(uploaded, item.DisplayName)
+		}
+		result := map[string]interface{}{
+			"source":   path,
+			"store":    storeID,
+			"uploaded": uploaded,
+		}
+		if len(skipped) > 0 {
+			result["skipped"] = skipped
+		}
+		res, err := mcp.NewToolResultJSON(result)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 		return res, nil
 	}
 }
+
+// lookupStoreProfile returns the configured upload defaults for a store,
+// matched by the name given in the request or the resolved resource ID.
+// Returns nil if no profile applies.
+func lookupStoreProfile(ctx context.Context, client GeminiClient, opts *ServerOptions, storeName, storeID string) *config.StoreProfile {
+	if len(opts.StoreProfiles) == 0 {
+		return nil
+	}
+	if _, profile := opts.StoreProfiles.Lookup(storeName, storeID); profile != nil {
+		return profile
+	}
+	// The request named the store by ID, but the profile may be keyed by display name
+	store, err := client.GetStore(ctx, storeID)
+	if err != nil {
+		return nil
+	}
+	_, profile := opts.StoreProfiles.Lookup(store.DisplayName)
+	return profile
+}
+
+// uploadResult formats the result of a single file upload
+func uploadResult(file *genai.File, path, storeName string) (*mcp.CallToolResult, error) {
+	// If file is nil, it means it was uploaded to a store (UploadFile returns nil for store uploads as it handles the operation)
+	if file == nil {
+		return mcp.NewToolResultText(fmt.Sprintf("Uploaded %s to store %s", path, storeName)), nil
+	}
+
+	res, err := mcp.NewToolResultJSON(file)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return res, nil
+}
+
+// duplicateResult reports a failed upload. Uploads skipped or refused
+// because the store already has the document return the matching documents
+// as structured content; skips are not errors.
+func duplicateResult(err error) *mcp.CallToolResult {
+	var dup *gemini.DuplicateDocumentError
+	if !errors.As(err, &dup) {
+		return mcp.NewToolResultError(err.Error())
+	}
+	status := "duplicate"
+	if dup.Skipped {
+		status = "skipped"
+	}
+	result := mcp.NewToolResultStructured(map[string]any{
+		"status":     status,
+		"message":    err.Error(),
+		"duplicates": dup.Duplicates,
+	}, fmt.Sprintf("%s: %v", status, err))
+	result.IsError = !dup.Skipped
+	return result
+}
+
+// labelUsage sets the tool name as the usage label of a tool call
+func labelUsage(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return next(gemini.WithUsageLabel(ctx, request.Params.Name), request)
+	}
+}
+
+// resolveError reports a name that could not be resolved. Ambiguous and
+// unknown names also return the candidates as structured content, so the
+// model can retry with one of their IDs.
+func resolveError(kind string, err error) *mcp.CallToolResult {
+	text := fmt.Sprintf("Failed to resolve %s name: %v", kind, err)
+	var candidates []gemini.Candidate
+	var ambiguous *gemini.AmbiguousNameError
+	var notFound *gemini.NotFoundError
+	switch {
+	case errors.As(err, &ambiguous):
+		candidates = ambiguous.Candidates
+	case errors.As(err, &notFound):
+		candidates = notFound.Suggestions
+	}
+	if len(candidates) == 0 {
+		return mcp.NewToolResultError(text)
+	}
+	result := mcp.NewToolResultStructured(map[string]any{"error": text, "candidates": candidates}, text)
+	result.IsError = true
+	return result
+}
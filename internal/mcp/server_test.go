@@ -3,10 +3,17 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mikesmitty/file-search/internal/config"
+	"github.com/mikesmitty/file-search/internal/convert"
 	"github.com/mikesmitty/file-search/internal/gemini"
 	"google.golang.org/genai"
 )
@@ -16,12 +23,14 @@ type MockGeminiClient struct {
 	ListStoresFunc          func(ctx context.Context) ([]*genai.FileSearchStore, error)
 	ListFilesFunc           func(ctx context.Context) ([]*genai.File, error)
 	ResolveStoreNameFunc    func(ctx context.Context, nameOrID string) (string, error)
+	GetStoreFunc            func(ctx context.Context, name string) (*genai.FileSearchStore, error)
 	ListDocumentsFunc       func(ctx context.Context, storeID string) ([]*genai.Document, error)
 	CreateStoreFunc         func(ctx context.Context, displayName string) (*genai.FileSearchStore, error)
 	DeleteStoreFunc         func(ctx context.Context, name string, force bool) error
 	ResolveFileNameFunc     func(ctx context.Context, nameOrID string) (string, error)
 	ImportFileFunc          func(ctx context.Context, fileID, storeID string, opts *gemini.ImportFileOptions) error
-	QueryFunc               func(ctx context.Context, text string, storeName string, modelName string, metadataFilter string) (*genai.GenerateContentResponse, error)
+	QueryFunc               func(ctx context.Context, text string, storeName string, modelName string, opts *gemini.QueryOptions) (*genai.GenerateContentResponse, error)
+	SearchPassagesFunc      func(ctx context.Context, text string, storeName string, modelName string, opts *gemini.QueryOptions) ([]gemini.Source, error)
 	UploadFileFunc          func(ctx context.Context, path string, opts *gemini.UploadFileOptions) (*genai.File, error)
 	DeleteFileFunc          func(ctx context.Context, name string) error
 	ResolveDocumentNameFunc func(ctx context.Context, storeNameOrID, docNameOrID string) (string, error)
@@ -38,6 +47,9 @@ func (m *MockGeminiClient) ListFiles(ctx context.Context) ([]*genai.File, error)
 func (m *MockGeminiClient) ResolveStoreName(ctx context.Context, nameOrID string) (string, error) {
 	return m.ResolveStoreNameFunc(ctx, nameOrID)
 }
+func (m *MockGeminiClient) GetStore(ctx context.Context, name string) (*genai.FileSearchStore, error) {
+	return m.GetStoreFunc(ctx, name)
+}
 func (m *MockGeminiClient) ListDocuments(ctx context.Context, storeID string) ([]*genai.Document, error) {
 	return m.ListDocumentsFunc(ctx, storeID)
 }
@@ -53,8 +65,11 @@ func (m *MockGeminiClient) ResolveFileName(ctx context.Context, nameOrID string)
 func (m *MockGeminiClient) ImportFile(ctx context.Context, fileID, storeID string, opts *gemini.ImportFileOptions) error {
 	return m.ImportFileFunc(ctx, fileID, storeID, opts)
 }
-func (m *MockGeminiClient) Query(ctx context.Context, text string, storeName string, modelName string, metadataFilter string) (*genai.GenerateContentResponse, error) {
-	return m.QueryFunc(ctx, text, storeName, modelName, metadataFilter)
+func (m *MockGeminiClient) Query(ctx context.Context, text string, storeName string, modelName string, opts *gemini.QueryOptions) (*genai.GenerateContentResponse, error) {
+	return m.QueryFunc(ctx, text, storeName, modelName, opts)
+}
+func (m *MockGeminiClient) SearchPassages(ctx context.Context, text string, storeName string, modelName string, opts *gemini.QueryOptions) ([]gemini.Source, error) {
+	return m.SearchPassagesFunc(ctx, text, storeName, modelName, opts)
 }
 func (m *MockGeminiClient) UploadFile(ctx context.Context, path string, opts *gemini.UploadFileOptions) (*genai.File, error) {
 	return m.UploadFileFunc(ctx, path, opts)
@@ -84,7 +99,7 @@ func TestNewServer_ToolRegistration(t *testing.T) {
 	mockClient := &MockGeminiClient{}
 	enabledTools := []string{"all"}
 
-	s := NewServer(mockClient, enabledTools)
+	s := NewServer(mockClient, enabledTools, nil)
 
 	val := getReflectedToolsMap(s)
 	if !val.IsValid() {
@@ -108,6 +123,7 @@ func TestNewServer_ToolRegistration(t *testing.T) {
 		"delete_store",
 		"import_file_to_store",
 		"query_knowledge_base",
+		"search_passages",
 		"upload_file",
 		"delete_file",
 		"delete_document",
@@ -131,7 +147,7 @@ func TestNewServer_SelectiveToolRegistration(t *testing.T) {
 	mockClient := &MockGeminiClient{}
 	enabledTools := []string{"query", "upload"}
 
-	s := NewServer(mockClient, enabledTools)
+	s := NewServer(mockClient, enabledTools, nil)
 
 	val := getReflectedToolsMap(s)
 	if !val.IsValid() {
@@ -303,7 +319,7 @@ func TestListDocumentsHandler_OutputFormat(t *testing.T) {
 		},
 	}
 
-	handler := makeListDocumentsHandler(mockClient)
+	handler := makeListDocumentsHandler(mockClient, &ServerOptions{})
 
 	req := mcp.CallToolRequest{
 		Params: mcp.CallToolParams{
@@ -339,3 +355,501 @@ func TestListDocumentsHandler_OutputFormat(t *testing.T) {
 		t.Errorf("Expected 'documents' to be a non-empty array, got: %s", textContent.Text)
 	}
 }
+
+func TestListDocumentsHandler_DefaultStore(t *testing.T) {
+	var resolved string
+	mockClient := &MockGeminiClient{
+		ResolveStoreNameFunc: func(ctx context.Context, nameOrID string) (string, error) {
+			resolved = nameOrID
+			return "fileSearchStores/default-id", nil
+		},
+		ListDocumentsFunc: func(ctx context.Context, storeID string) ([]*genai.Document, error) {
+			return []*genai.Document{}, nil
+		},
+	}
+
+	handler := makeListDocumentsHandler(mockClient, &ServerOptions{DefaultStore: "Team Docs"})
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"omitted", map[string]interface{}{}, "Team Docs"},
+		{"empty", map[string]interface{}{"store_name": ""}, "Team Docs"},
+		{"explicit", map[string]interface{}{"store_name": "Other"}, "Other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved = ""
+			req := mcp.CallToolRequest{
+				Params: mcp.CallToolParams{Name: "list_documents", Arguments: tt.args},
+			}
+			result, err := handler(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Handler returned error: %v", err)
+			}
+			if result.IsError {
+				t.Fatalf("Handler returned tool error: %v", result.Content)
+			}
+			if resolved != tt.want {
+				t.Errorf("resolved store = %q, want %q", resolved, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadFileHandler_ConvertsHTML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "page.html")
+	if err := os.WriteFile(path, []byte("<h1>Spec</h1>"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotOpts *gemini.UploadFileOptions
+	var gotPath string
+	mockClient := &MockGeminiClient{
+		ResolveStoreNameFunc: func(ctx context.Context, nameOrID string) (string, error) {
+			return "fileSearchStores/123", nil
+		},
+		UploadFileFunc: func(ctx context.Context, path string, opts *gemini.UploadFileOptions) (*genai.File, error) {
+			gotPath = path
+			gotOpts = opts
+			return nil, nil
+		},
+	}
+
+	handler := makeUploadFileHandler(mockClient, &ServerOptions{Converters: convert.NewRegistry()})
+	req := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "upload_file",
+			Arguments: map[string]interface{}{
+				"path":       path,
+				"store_name": "Docs",
+				"metadata":   `{"team": "hw"}`,
+			},
+		},
+	}
+
+	result, err := handler(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Handler returned tool error: %v", result.Content)
+	}
+
+	if gotPath == path {
+		t.Error("expected converted artifact to be uploaded instead of the original HTML")
+	}
+	if gotOpts.MIMEType != "text/markdown" {
+		t.Errorf("expected text/markdown MIME type, got %q", gotOpts.MIMEType)
+	}
+	if gotOpts.Metadata["converter"] != "html" || gotOpts.Metadata["team"] != "hw" {
+		t.Errorf("expected provenance merged with user metadata, got %v", gotOpts.Metadata)
+	}
+}
+
+func TestUploadFileHandler_AppliesStoreProfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "board.log")
+	if err := os.WriteFile(path, []byte("boot ok"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotOpts *gemini.UploadFileOptions
+	mockClient := &MockGeminiClient{
+		ResolveStoreNameFunc: func(ctx context.Context, nameOrID string) (string, error) {
+			return "fileSearchStores/123", nil
+		},
+		UploadFileFunc: func(ctx context.Context, path string, opts *gemini.UploadFileOptions) (*genai.File, error) {
+			gotOpts = opts
+			return nil, nil
+		},
+	}
+
+	opts := &ServerOptions{StoreProfiles: config.StoreProfiles{
+		"datasheets": {
+			ChunkSize:    512,
+			ChunkOverlap: 64,
+			Metadata:     map[string]string{"team": "hw", "kind": "datasheet"},
+			MIMETypes:    map[string]string{".log": "text/plain"},
+			Exclude:      []string{"*.tmp"},
+		},
+	}}
+	handler := makeUploadFileHandler(mockClient, opts)
+
+	req := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "upload_file",
+			Arguments: map[string]interface{}{
+				"path":       path,
+				"store_name": "Datasheets",
+				"metadata":   `{"team": "fw"}`,
+			},
+		},
+	}
+	result, err := handler(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Handler returned tool error: %v", result.Content)
+	}
+
+	if gotOpts.MaxChunkTokens != 512 || gotOpts.ChunkOverlap != 64 {
+		t.Errorf("expected profile chunking, got %d/%d", gotOpts.MaxChunkTokens, gotOpts.ChunkOverlap)
+	}
+	if gotOpts.MIMEType != "text/plain" {
+		t.Errorf("expected profile MIME type, got %q", gotOpts.MIMEType)
+	}
+	if gotOpts.Metadata["team"] != "fw" || gotOpts.Metadata["kind"] != "datasheet" {
+		t.Errorf("expected explicit metadata to override the profile, got %v", gotOpts.Metadata)
+	}
+
+	// Excluded files are rejected before upload
+	excluded := filepath.Join(dir, "scratch.tmp")
+	os.WriteFile(excluded, []byte("x"), 0o600)
+	req.Params.Arguments = map[string]interface{}{"path": excluded, "store_name": "Datasheets"}
+	result, _ = handler(context.Background(), req)
+	if !result.IsError {
+		t.Error("expected excluded file to be rejected")
+	}
+}
+
+func TestQueryKnowledgeBaseHandler_GenerationOptions(t *testing.T) {
+	var gotModel string
+	var gotOpts *gemini.QueryOptions
+	mockClient := &MockGeminiClient{
+		QueryFunc: func(ctx context.Context, text, storeName, modelName string, opts *gemini.QueryOptions) (*genai.GenerateContentResponse, error) {
+			gotModel, gotOpts = modelName, opts
+			return &genai.GenerateContentResponse{}, nil
+		},
+	}
+
+	defaults := &gemini.QueryOptions{
+		SystemInstruction: "Be concise.",
+		MaxOutputTokens:   256,
+		SafetySettings:    map[string]string{"harassment": "block_none"},
+	}
+	handler := makeQueryKnowledgeBaseHandler(mockClient, &ServerOptions{DefaultModel: "test-model", QueryDefaults: defaults})
+
+	req := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "query_knowledge_base",
+			Arguments: map[string]interface{}{
+				"query":           "max voltage?",
+				"temperature":     float64(0),
+				"thinking_level":  "low",
+				"stop_sequences":  []interface{}{"END"},
+				"safety_settings": map[string]interface{}{"dangerous_content": "block_only_high"},
+			},
+		},
+	}
+	result, err := handler(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Handler returned tool error: %v", result.Content)
+	}
+
+	if gotModel != "test-model" {
+		t.Errorf("model = %q, want default model", gotModel)
+	}
+	if gotOpts.SystemInstruction != "Be concise." || gotOpts.MaxOutputTokens != 256 {
+		t.Errorf("defaults not applied: %+v", gotOpts)
+	}
+	if gotOpts.Temperature == nil || *gotOpts.Temperature != 0 {
+		t.Errorf("temperature not applied: %v", gotOpts.Temperature)
+	}
+	if gotOpts.ThinkingLevel != "low" || len(gotOpts.StopSequences) != 1 {
+		t.Errorf("arguments not applied: %+v", gotOpts)
+	}
+	if len(gotOpts.SafetySettings) != 2 {
+		t.Errorf("expected safety settings to be merged, got %v", gotOpts.SafetySettings)
+	}
+	if len(defaults.SafetySettings) != 1 {
+		t.Errorf("defaults were modified: %v", defaults.SafetySettings)
+	}
+
+	req.Params.Arguments = map[string]interface{}{"query": "q", "thinking_level": "extreme"}
+	result, _ = handler(context.Background(), req)
+	if !result.IsError {
+		t.Error("expected a tool error for an invalid thinking level")
+	}
+}
+
+func TestQueryKnowledgeBaseHandler_CitedAnswer(t *testing.T) {
+	mockClient := &MockGeminiClient{
+		QueryFunc: func(ctx context.Context, text, storeName, modelName string, opts *gemini.QueryOptions) (*genai.GenerateContentResponse, error) {
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{
+					Content: genai.NewContentFromText("The limit is 40 V.", genai.RoleModel),
+					GroundingMetadata: &genai.GroundingMetadata{
+						GroundingChunks: []*genai.GroundingChunk{
+							{RetrievedContext: &genai.GroundingChunkRetrievedContext{Title: "lm317.pdf", Text: "Input voltage: 40 V"}},
+						},
+						GroundingSupports: []*genai.GroundingSupport{
+							{Segment: &genai.Segment{EndIndex: 18, Text: "The limit is 40 V."}, GroundingChunkIndices: []int32{0}},
+						},
+					},
+				}},
+				UsageMetadata: &genai.GenerateContentResponseUsageMetadata{TotalTokenCount: 100},
+			}, nil
+		},
+	}
+	handler := makeQueryKnowledgeBaseHandler(mockClient, &ServerOptions{DefaultModel: "test-model"})
+
+	req := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name:      "query_knowledge_base",
+			Arguments: map[string]interface{}{"query": "max voltage?"},
+		},
+	}
+	result, err := handler(context.Background(), req)
+	if err != nil || result.IsError {
+		t.Fatalf("Handler failed: %v %v", err, result.Content)
+	}
+
+	textContent := result.Content[0].(mcp.TextContent)
+	var output map[string]interface{}
+	if err := json.Unmarshal([]byte(textContent.Text), &output); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if output["answer"] != "The limit is 40 V.[1]" {
+		t.Errorf("expected an answer with citation markers, got %v", output["answer"])
+	}
+	if _, ok := output["candidates"]; ok {
+		t.Error("expected a compact answer instead of the raw response")
+	}
+	if !strings.Contains(textContent.Text, `"text": "Input voltage: 40 V"`) && !strings.Contains(textContent.Text, `"text":"Input voltage: 40 V"`) {
+		t.Errorf("expected a source snippet at standard detail, got %s", textContent.Text)
+	}
+	if strings.Contains(textContent.Text, "totalTokenCount") {
+		t.Error("expected usage metadata to be omitted")
+	}
+}
+
+func TestQueryKnowledgeBaseHandler_ResponseSchema(t *testing.T) {
+	answer := `{"part": "LM317", "max_voltage": 40}`
+	var gotSchema any
+	mockClient := &MockGeminiClient{
+		ResolveStoreNameFunc: func(ctx context.Context, nameOrID string) (string, error) {
+			return "fileSearchStores/abc", nil
+		},
+		QueryFunc: func(ctx context.Context, text, storeName, modelName string, opts *gemini.QueryOptions) (*genai.GenerateContentResponse, error) {
+			gotSchema = opts.ResponseSchema
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{
+					Content: genai.NewContentFromText(answer, genai.RoleModel),
+					GroundingMetadata: &genai.GroundingMetadata{
+						GroundingChunks: []*genai.GroundingChunk{
+							{RetrievedContext: &genai.GroundingChunkRetrievedContext{Title: "lm317.pdf"}},
+						},
+					},
+				}},
+			}, nil
+		},
+	}
+	handler := makeQueryKnowledgeBaseHandler(mockClient, &ServerOptions{DefaultModel: "test-model"})
+
+	responseSchema := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"part", "max_voltage"},
+		"properties": map[string]interface{}{
+			"part":        map[string]interface{}{"type": "string"},
+			"max_voltage": map[string]interface{}{"type": "number"},
+		},
+	}
+	req := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "query_knowledge_base",
+			Arguments: map[string]interface{}{
+				"query":           "max voltage of the LM317?",
+				"store_name":      "Datasheets",
+				"response_schema": responseSchema,
+			},
+		},
+	}
+	result, err := handler(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Handler returned tool error: %v", result.Content)
+	}
+	if gotSchema == nil {
+		t.Error("schema was not passed to the query")
+	}
+
+	var output struct {
+		Answer  map[string]interface{} `json:"answer"`
+		Sources []gemini.Source        `json:"sources"`
+	}
+	textContent := result.Content[0].(mcp.TextContent)
+	if err := json.Unmarshal([]byte(textContent.Text), &output); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if output.Answer["part"] != "LM317" || len(output.Sources) != 1 || output.Sources[0].Title != "lm317.pdf" {
+		t.Errorf("unexpected output: %s", textContent.Text)
+	}
+
+	// Answers that do not match the schema are reported as tool errors
+	answer = `{"part": "LM317"}`
+	result, _ = handler(context.Background(), req)
+	if !result.IsError {
+		t.Error("expected a tool error for an answer missing a required field")
+	}
+}
+
+func TestSearchPassagesHandlerWithoutClient(t *testing.T) {
+	handler := makeSearchPassagesHandler(nil, &ServerOptions{DefaultModel: "test-model", DefaultStore: "Datasheets"})
+	result, err := handler(context.Background(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name:      "search_passages",
+			Arguments: map[string]interface{}{"query": "max voltage"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("expected an error result without a client")
+	}
+}
+
+func TestSearchPassagesHandler(t *testing.T) {
+	var gotStore, gotFilter string
+	mockClient := &MockGeminiClient{
+		ResolveStoreNameFunc: func(ctx context.Context, nameOrID string) (string, error) {
+			return "fileSearchStores/abc", nil
+		},
+		SearchPassagesFunc: func(ctx context.Context, text, storeName, modelName string, opts *gemini.QueryOptions) ([]gemini.Source, error) {
+			gotStore, gotFilter = storeName, opts.MetadataFilter
+			return []gemini.Source{{Index: 1, Type: "document", Title: "lm317.pdf", Store: storeName, FirstPage: 3, LastPage: 3, Text: "Input voltage: 40 V"}}, nil
+		},
+	}
+	handler := makeSearchPassagesHandler(mockClient, &ServerOptions{DefaultModel: "test-model", DefaultStore: "Datasheets"})
+
+	req := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name: "search_passages",
+			Arguments: map[string]interface{}{
+				"query":           "max voltage",
+				"metadata_filter": `team = "hw"`,
+			},
+		},
+	}
+	result, err := handler(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Handler returned error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Handler returned tool error: %v", result.Content)
+	}
+	if gotStore != "fileSearchStores/abc" || gotFilter != `team = "hw"` {
+		t.Errorf("unexpected search arguments: store %q, filter %q", gotStore, gotFilter)
+	}
+
+	var passages []gemini.Source
+	textContent := result.Content[0].(mcp.TextContent)
+	if err := json.Unmarshal([]byte(textContent.Text), &passages); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if len(passages) != 1 || passages[0].Text != "Input voltage: 40 V" || passages[0].Store != "fileSearchStores/abc" {
+		t.Errorf("unexpected passages: %s", textContent.Text)
+	}
+}
+
+func TestResolveError(t *testing.T) {
+	plain := resolveError("store", errors.New("permission denied"))
+	if !plain.IsError || plain.StructuredContent != nil {
+		t.Errorf("expected a plain error result, got %+v", plain)
+	}
+
+	err := fmt.Errorf("wrapped: %w", &gemini.AmbiguousNameError{Kind: "document", Ref: "datasheet.pdf", Candidates: []gemini.Candidate{
+		{Name: "fileSearchStores/s/documents/a", DisplayName: "datasheet.pdf"},
+		{Name: "fileSearchStores/s/documents/b", DisplayName: "datasheet.pdf"},
+	}})
+	result := resolveError("document", err)
+	if !result.IsError {
+		t.Error("expected an error result")
+	}
+	structured, ok := result.StructuredContent.(map[string]any)
+	if !ok {
+		t.Fatalf("expected structured candidates, got %T", result.StructuredContent)
+	}
+	candidates, ok := structured["candidates"].([]gemini.Candidate)
+	if !ok || len(candidates) != 2 {
+		t.Errorf("unexpected candidates %+v", structured["candidates"])
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, "documents/b") {
+		t.Errorf("expected candidates in the text fallback: %s", text)
+	}
+}
+
+func TestUploadFileHandler_OnDuplicate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "datasheet.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.4"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotPolicy gemini.DuplicatePolicy
+	dupErr := &gemini.DuplicateDocumentError{
+		Store:       "fileSearchStores/123",
+		DisplayName: "datasheet.pdf",
+		Duplicates:  []gemini.Duplicate{{Document: "fileSearchStores/123/documents/a", DisplayName: "datasheet.pdf", SameName: true, SameContent: true}},
+	}
+	mockClient := &MockGeminiClient{
+		ResolveStoreNameFunc: func(ctx context.Context, nameOrID string) (string, error) {
+			return "fileSearchStores/123", nil
+		},
+		UploadFileFunc: func(ctx context.Context, path string, opts *gemini.UploadFileOptions) (*genai.File, error) {
+			gotPolicy = opts.OnDuplicate
+			dupErr.Skipped = opts.OnDuplicate == gemini.DuplicateSkip
+			return nil, dupErr
+		},
+		GetStoreFunc: func(ctx context.Context, name string) (*genai.FileSearchStore, error) {
+			return &genai.FileSearchStore{Name: name, DisplayName: "Other"}, nil
+		},
+	}
+	opts := &ServerOptions{StoreProfiles: config.StoreProfiles{"datasheets": {OnDuplicate: "error"}}}
+	handler := makeUploadFileHandler(mockClient, opts)
+	call := func(args map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		args["path"] = path
+		result, err := handler(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "upload_file", Arguments: args}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// Skips are reported with the matching documents, but are not errors
+	result := call(map[string]interface{}{"store_name": "Other"})
+	if gotPolicy != gemini.DuplicateSkip || result.IsError {
+		t.Errorf("default: policy %q, IsError %v", gotPolicy, result.IsError)
+	}
+	structured, ok := result.StructuredContent.(map[string]any)
+	if !ok || structured["status"] != "skipped" || len(structured["duplicates"].([]gemini.Duplicate)) != 1 {
+		t.Errorf("unexpected skip result %+v", result.StructuredContent)
+	}
+
+	// The store profile sets the default, and the argument overrides it
+	result = call(map[string]interface{}{"store_name": "Datasheets"})
+	if gotPolicy != gemini.DuplicateError || !result.IsError {
+		t.Errorf("profile: policy %q, IsError %v", gotPolicy, result.IsError)
+	}
+	call(map[string]interface{}{"store_name": "Datasheets", "on_duplicate": "replace"})
+	if gotPolicy != gemini.DuplicateReplace {
+		t.Errorf("argument: policy %q", gotPolicy)
+	}
+
+	gotPolicy = ""
+	result = call(map[string]interface{}{"store_name": "Datasheets", "on_duplicate": "overwrite"})
+	if !result.IsError || gotPolicy != "" {
+		t.Error("expected an invalid policy to be rejected before upload")
+	}
+}
@@ -0,0 +1,210 @@
+// Package report writes query answers to self-contained Markdown or HTML
+// reports.
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+)
+
+// snippetChars is the longest source snippet included in a report
+const snippetChars = 300
+
+// Report is a set of questions and their cited answers
+type Report struct {
+	Model   string
+	Store   string
+	Created time.Time
+	Entries []Entry
+}
+
+// Entry is one question in a report. Err is set instead of Answer when the
+// question failed.
+type Entry struct {
+	Question string
+	Answer   *gemini.CitedAnswer
+	Err      error
+}
+
+// writer returns the report writer for the extension of path
+func writer(path string) (func(io.Writer, *Report) error, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return WriteMarkdown, nil
+	case ".html", ".htm":
+		return WriteHTML, nil
+	}
+	return nil, fmt.Errorf("unsupported report format %q (use .md or .html)", filepath.Ext(path))
+}
+
+// CheckPath reports an error if Write can't choose a format for path
+func CheckPath(path string) error {
+	_, err := writer(path)
+	return err
+}
+
+// Write writes r to path as Markdown (.md, .markdown) or HTML (.html, .htm)
+func Write(path string, r *Report) error {
+	write, err := writer(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadQuestions reads one question per line from path, skipping blank lines
+// and lines starting with #
+func ReadQuestions(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var questions []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		questions = append(questions, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("no questions in %s", path)
+	}
+	return questions, nil
+}
+
+// WriteMarkdown writes r as Markdown. Answers keep their inline [n]
+// citations; the sources of every answer are listed in an appendix.
+func WriteMarkdown(w io.Writer, r *Report) error {
+	var b strings.Builder
+	b.WriteString("# Query Report\n\n")
+	fmt.Fprintf(&b, "- **Generated:** %s\n", r.Created.Format(time.RFC1123))
+	fmt.Fprintf(&b, "- **Model:** %s\n", r.Model)
+	if r.Store != "" {
+		fmt.Fprintf(&b, "- **Store:** %s\n", r.Store)
+	}
+
+	for i, e := range r.Entries {
+		fmt.Fprintf(&b, "\n## %d. %s\n\n", i+1, e.Question)
+		if e.Err != nil {
+			fmt.Fprintf(&b, "> **Error:** %s\n", e.Err)
+			continue
+		}
+		b.WriteString(strings.TrimSpace(e.Answer.Render(nil)))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n## Sources\n")
+	for i, e := range r.Entries {
+		if e.Answer == nil || len(e.Answer.Sources) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %d. %s\n\n", i+1, e.Question)
+		for _, src := range e.Answer.Sources {
+			fmt.Fprintf(&b, "%d. **%s**", src.Index, src.Title)
+			if loc := src.Location(); loc != "" {
+				fmt.Fprintf(&b, " (%s)", loc)
+			}
+			b.WriteString("\n")
+			if text := snippet(src.Text); text != "" {
+				fmt.Fprintf(&b, "   > %s\n", text)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// citationMarker matches the [n] markers inserted by CitedAnswer.Render
+var citationMarker = regexp.MustCompile(`\[(\d+)\]`)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"answer":  answerHTML,
+	"snippet": snippet,
+	"date":    func(t time.Time) string { return t.Format(time.RFC1123) },
+	"inc":     func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Query Report</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 50rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #222; }
+.meta { color: #666; }
+.answer { white-space: pre-wrap; }
+.error { color: #b00; }
+sup a { text-decoration: none; }
+blockquote { margin: 0.25rem 0 0.75rem; padding-left: 0.75rem; border-left: 3px solid #ddd; color: #555; }
+</style>
+</head>
+<body>
+<h1>Query Report</h1>
+<p class="meta">Generated {{date .Created}} &middot; Model {{.Model}}{{if .Store}} &middot; Store {{.Store}}{{end}}</p>
+{{range $i, $e := .Entries}}
+<h2 id="q{{inc $i}}">{{inc $i}}. {{$e.Question}}</h2>
+{{if $e.Err}}<p class="error">Error: {{$e.Err}}</p>{{else}}<div class="answer">{{answer (inc $i) $e.Answer}}</div>{{end}}
+{{end}}
+<h2>Sources</h2>
+{{range $i, $e := .Entries}}{{if and $e.Answer $e.Answer.Sources}}
+<h3>{{inc $i}}. {{$e.Question}}</h3>
+<ol>
+{{range $e.Answer.Sources}}<li value="{{.Index}}" id="q{{inc $i}}-s{{.Index}}"><strong>{{.Title}}</strong>{{with .Location}} ({{.}}){{end}}
+{{with snippet .Text}}<blockquote>{{.}}</blockquote>{{end}}</li>
+{{end}}</ol>
+{{end}}{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes r as a standalone HTML page. Inline citations link to
+// their entries in the sources appendix.
+func WriteHTML(w io.Writer, r *Report) error {
+	return htmlTemplate.Execute(w, r)
+}
+
+// answerHTML renders an answer with its [n] citations linked to the sources
+// of question q
+func answerHTML(q int, a *gemini.CitedAnswer) template.HTML {
+	escaped := template.HTMLEscapeString(strings.TrimSpace(a.Render(nil)))
+	linked := citationMarker.ReplaceAllString(escaped, fmt.Sprintf(`<sup><a href="#q%d-s$1">[$1]</a></sup>`, q))
+	return template.HTML(linked)
+}
+
+// snippet collapses whitespace in a source's text and truncates it
+func snippet(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > snippetChars {
+		cut := snippetChars - 3
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "..."
+	}
+	return text
+}
@@ -0,0 +1,109 @@
+package report
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"google.golang.org/genai"
+)
+
+func testReport() *Report {
+	answer := "Up to 40 V <typ>."
+	resp := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		Content: genai.NewContentFromText(answer, genai.RoleModel),
+		GroundingMetadata: &genai.GroundingMetadata{
+			GroundingChunks: []*genai.GroundingChunk{
+				{RetrievedContext: &genai.GroundingChunkRetrievedContext{Title: "lm317.pdf", Text: "Input\nvoltage: 40 V", PageNumber: genai.Ptr[int32](3)}},
+			},
+			GroundingSupports: []*genai.GroundingSupport{
+				{Segment: &genai.Segment{EndIndex: int32(len(answer)), Text: answer}, GroundingChunkIndices: []int32{0}},
+			},
+		},
+	}}}
+	return &Report{
+		Model:   "gemini-2.5-flash",
+		Store:   "Datasheets",
+		Created: time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+		Entries: []Entry{
+			{Question: "Max voltage?", Answer: gemini.NewCitedAnswer(resp)},
+			{Question: "Dropout?", Err: errors.New("quota exceeded")},
+		},
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var b strings.Builder
+	if err := WriteMarkdown(&b, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"**Generated:** Sun, 18 Oct 2026 09:30:00 UTC",
+		"**Model:** gemini-2.5-flash",
+		"**Store:** Datasheets",
+		"## 1. Max voltage?\n\nUp to 40 V <typ>.[1]\n",
+		"## 2. Dropout?\n\n> **Error:** quota exceeded",
+		"1. **lm317.pdf** (Page 3)\n   > Input voltage: 40 V\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var b strings.Builder
+	if err := WriteHTML(&b, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`Up to 40 V &lt;typ&gt;.<sup><a href="#q1-s1">[1]</a></sup>`,
+		`<li value="1" id="q1-s1"><strong>lm317.pdf</strong> (Page 3)`,
+		`<p class="error">Error: quota exceeded</p>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	if err := Write(filepath.Join(dir, "report.pdf"), testReport()); err == nil {
+		t.Error("expected an error for an unsupported extension")
+	}
+	path := filepath.Join(dir, "report.HTML")
+	if err := Write(path, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), "<!DOCTYPE html>") {
+		t.Errorf("expected an HTML report, got %q (%v)", data, err)
+	}
+}
+
+func TestReadQuestions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "questions.txt")
+	if err := os.WriteFile(path, []byte("# design review\nMax voltage?\n\n  Dropout?  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	questions, err := ReadQuestions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(questions) != 2 || questions[1] != "Dropout?" {
+		t.Errorf("unexpected questions: %q", questions)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.txt")
+	os.WriteFile(empty, []byte("# nothing\n"), 0o644)
+	if _, err := ReadQuestions(empty); err == nil {
+		t.Error("expected an error for a file without questions")
+	}
+}
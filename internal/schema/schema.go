@@ -0,0 +1,91 @@
+// Package schema validates structured query answers against a JSON Schema.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// resourceURL names the schema within the compiler; it is never fetched
+const resourceURL = "file:///response-schema.json"
+
+// Schema is a compiled JSON Schema for query answers
+type Schema struct {
+	raw      json.RawMessage
+	compiled *jsonschema.Schema
+}
+
+// Load reads and compiles the JSON Schema in path
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Parse compiles a JSON Schema document
+func Parse(data []byte) (*Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema JSON: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(resourceURL, doc); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	compiled, err := c.Compile(resourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &Schema{raw: json.RawMessage(data), compiled: compiled}, nil
+}
+
+// FromValue compiles a schema given as a decoded JSON value, e.g. a tool argument
+func FromValue(v any) (*Schema, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Document returns the schema as sent to the model
+func (s *Schema) Document() any {
+	return s.raw
+}
+
+// Validate parses an answer as JSON and checks it against the schema.
+// A surrounding Markdown code fence is ignored.
+func (s *Schema) Validate(answer string) (any, error) {
+	v, err := jsonschema.UnmarshalJSON(strings.NewReader(stripCodeFence(answer)))
+	if err != nil {
+		return nil, fmt.Errorf("answer is not valid JSON: %w", err)
+	}
+	if err := s.compiled.Validate(v); err != nil {
+		return nil, fmt.Errorf("answer does not match the schema: %w", err)
+	}
+	return v, nil
+}
+
+// stripCodeFence removes a ```json ... ``` fence around text, if present
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
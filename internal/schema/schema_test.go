@@ -0,0 +1,79 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const voltageSchema = `{
+  "type": "object",
+  "properties": {
+    "part": {"type": "string"},
+    "max_voltage": {"type": "number", "minimum": 0}
+  },
+  "required": ["part", "max_voltage"]
+}`
+
+func TestValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, []byte(voltageSchema), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		answer  string
+		wantErr string
+	}{
+		{"valid", `{"part": "LM317", "max_voltage": 40}`, ""},
+		{"code fence", "```json\n{\"part\": \"LM317\", \"max_voltage\": 40}\n```", ""},
+		{"missing field", `{"part": "LM317"}`, "does not match"},
+		{"wrong type", `{"part": "LM317", "max_voltage": "forty"}`, "does not match"},
+		{"not json", `The maximum voltage is 40V.`, "not valid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := s.Validate(tt.answer)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if m, ok := v.(map[string]any); !ok || m["part"] != "LM317" {
+					t.Errorf("unexpected value %#v", v)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseInvalidSchema(t *testing.T) {
+	if _, err := Parse([]byte(`{"type": 12}`)); err == nil {
+		t.Error("expected an error for an invalid schema")
+	}
+	if _, err := Parse([]byte(`{not json`)); err == nil {
+		t.Error("expected an error for malformed JSON")
+	}
+}
+
+func TestFromValue(t *testing.T) {
+	s, err := FromValue(map[string]any{"type": "array", "items": map[string]any{"type": "string"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Validate(`["a", "b"]`); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.Validate(`[1]`); err == nil {
+		t.Error("expected a validation error")
+	}
+}
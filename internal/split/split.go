@@ -0,0 +1,307 @@
+package split
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// Metadata keys recorded on each part of a split document
+const (
+	MetaPart         = "part"
+	MetaTotalParts   = "total_parts"
+	MetaOriginalName = "original_name"
+	MetaPageRange    = "page_range"
+)
+
+func init() {
+	// Keep pdfcpu from writing its configuration directory under the user's config dir
+	api.DisableConfigDir()
+}
+
+// DefaultMaxBytes is the default size threshold above which files are split
+const DefaultMaxBytes = 20 << 20
+
+// Options configures how files are split
+type Options struct {
+	// MaxBytes is the maximum size of each part (default: DefaultMaxBytes)
+	MaxBytes int64
+	// MaxPages is the maximum number of pages per PDF part (0 derives it from MaxBytes)
+	MaxPages int
+}
+
+// Part is a single piece of a split file
+type Part struct {
+	Path        string
+	DisplayName string
+	Index       int // 1-based
+	Total       int
+	PageRange   string // PDF pages in this part (e.g. "51-100"), empty for text
+}
+
+// Metadata returns the custom metadata that identifies this part
+func (p *Part) Metadata(originalName string) map[string]string {
+	meta := map[string]string{
+		MetaPart:         strconv.Itoa(p.Index),
+		MetaTotalParts:   strconv.Itoa(p.Total),
+		MetaOriginalName: originalName,
+	}
+	if p.PageRange != "" {
+		meta[MetaPageRange] = p.PageRange
+	}
+	return meta
+}
+
+// PartDisplayName returns the display name used for a part of a split document
+func PartDisplayName(originalName string, index, total int) string {
+	return fmt.Sprintf("%s (part %d of %d)", originalName, index, total)
+}
+
+var partNameRe = regexp.MustCompile(`^(.*) \(part (\d+) of (\d+)\)$`)
+
+// ParsePartDisplayName extracts the original name and part numbers from a
+// display name produced by PartDisplayName. ok is false for unsplit documents.
+func ParsePartDisplayName(name string) (original string, index, total int, ok bool) {
+	m := partNameRe.FindStringSubmatch(name)
+	if m == nil {
+		return name, 0, 0, false
+	}
+	index, _ = strconv.Atoi(m[2])
+	total, _ = strconv.Atoi(m[3])
+	return m[1], index, total, true
+}
+
+// Supported reports whether files of this MIME type / path can be split
+func Supported(path, mimeType string) bool {
+	return isPDF(path, mimeType) || isText(path, mimeType)
+}
+
+// File splits path into parts written below outDir if it exceeds the limits
+// in opts. A file within the limits is returned as a single part with the
+// original path and display name.
+func File(path, displayName, mimeType, outDir string, opts *Options) ([]*Part, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case isPDF(path, mimeType):
+		return splitPDF(path, displayName, outDir, info.Size(), opts)
+	case isText(path, mimeType):
+		if info.Size() <= opts.MaxBytes {
+			return []*Part{{Path: path, DisplayName: displayName, Index: 1, Total: 1}}, nil
+		}
+		return splitTextFile(path, displayName, outDir, opts.MaxBytes)
+	default:
+		return nil, fmt.Errorf("cannot split %s: only text, Markdown and PDF files are supported", displayName)
+	}
+}
+
+func isPDF(path, mimeType string) bool {
+	return mimeType == "application/pdf" || strings.EqualFold(filepath.Ext(path), ".pdf")
+}
+
+func isText(path, mimeType string) bool {
+	if strings.HasPrefix(mimeType, "text/") {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".txt", ".rst", ".adoc":
+		return true
+	}
+	return false
+}
+
+func splitTextFile(path, displayName, outDir string, maxBytes int64) ([]*Part, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := SplitText(string(data), int(maxBytes))
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+
+	parts := make([]*Part, 0, len(chunks))
+	for i, chunk := range chunks {
+		partPath := filepath.Join(outDir, fmt.Sprintf("%s.part%03d%s", base, i+1, ext))
+		if err := os.WriteFile(partPath, []byte(chunk), 0o600); err != nil {
+			return nil, err
+		}
+		parts = append(parts, &Part{
+			Path:        partPath,
+			DisplayName: PartDisplayName(displayName, i+1, len(chunks)),
+			Index:       i + 1,
+			Total:       len(chunks),
+		})
+	}
+	return parts, nil
+}
+
+var headingRe = regexp.MustCompile(`^#{1,6}\s`)
+
+// SplitText splits text into chunks of at most maxBytes, preferring Markdown
+// heading boundaries, then blank lines, then line breaks.
+func SplitText(text string, maxBytes int) []string {
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return []string{text}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, section := range splitSections(text) {
+		if current.Len()+len(section) <= maxBytes {
+			current.WriteString(section)
+			continue
+		}
+		flush()
+		if len(section) <= maxBytes {
+			current.WriteString(section)
+			continue
+		}
+		// Oversized section: fall back to paragraph and line boundaries
+		for _, piece := range splitOversized(section, maxBytes) {
+			if current.Len()+len(piece) > maxBytes {
+				flush()
+			}
+			current.WriteString(piece)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitSections breaks text into sections that each start at a Markdown heading
+func splitSections(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	var sections []string
+	var current strings.Builder
+	inFence := false
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence && headingRe.MatchString(line) && current.Len() > 0 {
+			sections = append(sections, current.String())
+			current.Reset()
+		}
+		current.WriteString(line)
+	}
+	if current.Len() > 0 {
+		sections = append(sections, current.String())
+	}
+	return sections
+}
+
+// splitOversized splits a section on blank lines, then lines, then bytes
+func splitOversized(section string, maxBytes int) []string {
+	var pieces []string
+	for _, para := range strings.SplitAfter(section, "\n\n") {
+		if len(para) <= maxBytes {
+			pieces = append(pieces, para)
+			continue
+		}
+		for _, line := range strings.SplitAfter(para, "\n") {
+			for len(line) > maxBytes {
+				cut := maxBytes
+				// Avoid splitting in the middle of a UTF-8 sequence
+				for cut > 0 && line[cut]&0xC0 == 0x80 {
+					cut--
+				}
+				if cut == 0 {
+					cut = maxBytes
+				}
+				pieces = append(pieces, line[:cut])
+				line = line[cut:]
+			}
+			pieces = append(pieces, line)
+		}
+	}
+	return pieces
+}
+
+func splitPDF(path, displayName, outDir string, size int64, opts *Options) ([]*Part, error) {
+	pageCount, err := api.PageCountFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF %s: %w", displayName, err)
+	}
+
+	ranges := PageRanges(pageCount, size, opts)
+	if len(ranges) <= 1 {
+		return []*Part{{Path: path, DisplayName: displayName, Index: 1, Total: 1}}, nil
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	parts := make([]*Part, 0, len(ranges))
+	for i, r := range ranges {
+		partPath := filepath.Join(outDir, fmt.Sprintf("%s.part%03d.pdf", base, i+1))
+		if err := api.TrimFile(path, partPath, []string{r}, nil); err != nil {
+			return nil, fmt.Errorf("failed to extract pages %s from %s: %w", r, displayName, err)
+		}
+		parts = append(parts, &Part{
+			Path:        partPath,
+			DisplayName: PartDisplayName(displayName, i+1, len(ranges)),
+			Index:       i + 1,
+			Total:       len(ranges),
+			PageRange:   r,
+		})
+	}
+	return parts, nil
+}
+
+// PageRanges divides pageCount pages into contiguous ranges (e.g. "1-50")
+// so each part stays within opts.MaxPages and, assuming pages of roughly
+// equal size, within opts.MaxBytes.
+func PageRanges(pageCount int, size int64, opts *Options) []string {
+	if pageCount <= 0 {
+		return nil
+	}
+
+	perPart := pageCount
+	if opts.MaxBytes > 0 && size > opts.MaxBytes {
+		numParts := int((size + opts.MaxBytes - 1) / opts.MaxBytes)
+		perPart = (pageCount + numParts - 1) / numParts
+	}
+	if opts.MaxPages > 0 && opts.MaxPages < perPart {
+		perPart = opts.MaxPages
+	}
+	if perPart < 1 {
+		perPart = 1
+	}
+
+	var ranges []string
+	for start := 1; start <= pageCount; start += perPart {
+		end := start + perPart - 1
+		if end > pageCount {
+			end = pageCount
+		}
+		if start == end {
+			ranges = append(ranges, strconv.Itoa(start))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", start, end))
+		}
+	}
+	return ranges
+}
@@ -0,0 +1,140 @@
+package split
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitTextOnHeadings(t *testing.T) {
+	text := "# One\nalpha alpha\n\n# Two\nbeta beta\n\n# Three\ngamma gamma\n"
+
+	chunks := SplitText(text, 30)
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d: %q", len(chunks), chunks)
+	}
+	for i, want := range []string{"# One", "# Two", "# Three"} {
+		if !strings.HasPrefix(chunks[i], want) {
+			t.Errorf("chunk %d should start at heading %q, got %q", i, want, chunks[i])
+		}
+	}
+	if strings.Join(chunks, "") != text {
+		t.Error("chunks should reassemble into the original text")
+	}
+}
+
+func TestSplitTextPacksSmallSections(t *testing.T) {
+	text := "# A\na\n# B\nb\n# C\nc\n"
+
+	chunks := SplitText(text, 12)
+	if len(chunks) != 2 {
+		t.Fatalf("expected small sections to be packed into 2 chunks, got %q", chunks)
+	}
+}
+
+func TestSplitTextIgnoresHeadingsInCodeFences(t *testing.T) {
+	text := "# Real\n```\n# not a heading\n```\n"
+	sections := splitSections(text)
+	if len(sections) != 1 {
+		t.Errorf("expected fenced comment not to start a section, got %q", sections)
+	}
+}
+
+func TestSplitTextOversizedSection(t *testing.T) {
+	text := "# Big\n" + strings.Repeat("word ", 20) + "\n\n" + strings.Repeat("more ", 20) + "\n"
+
+	chunks := SplitText(text, 64)
+	for i, c := range chunks {
+		if len(c) > 64 {
+			t.Errorf("chunk %d exceeds limit: %d bytes", i, len(c))
+		}
+	}
+	if strings.Join(chunks, "") != text {
+		t.Error("chunks should reassemble into the original text")
+	}
+}
+
+func TestSplitTextWithinLimit(t *testing.T) {
+	chunks := SplitText("short", 100)
+	if len(chunks) != 1 || chunks[0] != "short" {
+		t.Errorf("expected text within limit to be returned whole, got %q", chunks)
+	}
+}
+
+func TestPageRanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		pageCount int
+		size      int64
+		opts      *Options
+		want      []string
+	}{
+		{"within limits", 10, 100, &Options{MaxBytes: 1000}, []string{"1-10"}},
+		{"split by size", 10, 250, &Options{MaxBytes: 100}, []string{"1-4", "5-8", "9-10"}},
+		{"split by pages", 10, 100, &Options{MaxBytes: 1000, MaxPages: 5}, []string{"1-5", "6-10"}},
+		{"single page parts", 3, 300, &Options{MaxBytes: 100}, []string{"1", "2", "3"}},
+		{"no pages", 0, 100, &Options{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PageRanges(tt.pageCount, tt.size, tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PageRanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPartDisplayNameRoundTrip(t *testing.T) {
+	name := PartDisplayName("datasheet (rev B).md", 2, 5)
+
+	original, index, total, ok := ParsePartDisplayName(name)
+	if !ok || original != "datasheet (rev B).md" || index != 2 || total != 5 {
+		t.Errorf("ParsePartDisplayName(%q) = %q, %d, %d, %v", name, original, index, total, ok)
+	}
+
+	if _, _, _, ok := ParsePartDisplayName("plain.pdf"); ok {
+		t.Error("expected unsplit name not to parse as a part")
+	}
+}
+
+func TestFileText(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "notes.md")
+	content := "# One\n" + strings.Repeat("a", 40) + "\n# Two\n" + strings.Repeat("b", 40) + "\n"
+	if err := os.WriteFile(src, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	parts, err := File(src, "notes.md", "", dir, &Options{MaxBytes: 60})
+	if err != nil {
+		t.Fatalf("File failed: %v", err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(parts))
+	}
+	if parts[1].DisplayName != "notes.md (part 2 of 2)" {
+		t.Errorf("unexpected part display name: %s", parts[1].DisplayName)
+	}
+
+	meta := parts[0].Metadata("notes.md")
+	if meta[MetaPart] != "1" || meta[MetaTotalParts] != "2" || meta[MetaOriginalName] != "notes.md" {
+		t.Errorf("unexpected part metadata: %v", meta)
+	}
+	if _, ok := meta[MetaPageRange]; ok {
+		t.Error("text parts should not carry a page range")
+	}
+}
+
+func TestFileUnsupported(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "image.png")
+	os.WriteFile(src, []byte("png"), 0o600)
+
+	if _, err := File(src, "image.png", "image/png", dir, nil); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,137 @@
+package upload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/mikesmitty/file-search/internal/convert"
+	"github.com/mikesmitty/file-search/internal/gemini"
+	"github.com/mikesmitty/file-search/internal/split"
+)
+
+// MetaSourcePath is the metadata key recording the absolute local path an
+// item was prepared from, so a failed document can be uploaded again. It is
+// only set with Options.RecordSource.
+const MetaSourcePath = "source_path"
+
+// Item is a single local file ready to be uploaded
+type Item struct {
+	Path        string
+	DisplayName string
+	MIMEType    string
+	// Metadata records provenance and split part information. It should be
+	// merged with user metadata (see convert.WithProvenance) before upload.
+	Metadata map[string]string
+}
+
+// Options configures the pre-processing applied before upload
+type Options struct {
+	// Converters converts unsupported formats (nil disables conversion)
+	Converters *convert.Registry
+	// Split breaks oversized files into parts (nil disables splitting)
+	Split *split.Options
+	// RecordSource adds the absolute local path to each item's metadata.
+	// It is off by default, since it exposes local usernames and directory
+	// layout to anyone with access to the store.
+	RecordSource bool
+}
+
+// Prepared holds the items produced for one input path
+type Prepared struct {
+	Items    []*Item
+	cleanups []func()
+}
+
+// Cleanup removes temporary files created while preparing the upload
+func (p *Prepared) Cleanup() {
+	for _, fn := range p.cleanups {
+		fn()
+	}
+}
+
+// Prepare runs path through conversion and splitting, returning the files to upload.
+func Prepare(ctx context.Context, path, displayName string, opts *Options) (*Prepared, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	p := &Prepared{}
+
+	artifacts := []*convert.Artifact{{Path: path, DisplayName: displayName}}
+	if opts.Converters != nil {
+		conv, err := opts.Converters.Convert(ctx, path, displayName)
+		if err != nil {
+			return nil, err
+		}
+		p.cleanups = append(p.cleanups, conv.Cleanup)
+		artifacts = conv.Artifacts
+	}
+
+	if opts.Split == nil {
+		for _, a := range artifacts {
+			p.Items = append(p.Items, &Item{
+				Path:        a.Path,
+				DisplayName: a.DisplayName,
+				MIMEType:    a.MIMEType,
+				Metadata:    a.Provenance,
+			})
+		}
+		if opts.RecordSource {
+			p.recordSource(path)
+		}
+		return p, nil
+	}
+
+	splitDir, err := os.MkdirTemp("", "file-search-split-")
+	if err != nil {
+		p.Cleanup()
+		return nil, err
+	}
+	p.cleanups = append(p.cleanups, func() { os.RemoveAll(splitDir) })
+
+	for _, a := range artifacts {
+		mimeType := a.MIMEType
+		if mimeType == "" {
+			mimeType = gemini.DetectMIMEType(a.Path)
+		}
+
+		// Formats that cannot be split are uploaded whole
+		if !split.Supported(a.Path, mimeType) {
+			p.Items = append(p.Items, &Item{Path: a.Path, DisplayName: a.DisplayName, MIMEType: a.MIMEType, Metadata: a.Provenance})
+			continue
+		}
+
+		parts, err := split.File(a.Path, a.DisplayName, mimeType, splitDir, opts.Split)
+		if err != nil {
+			p.Cleanup()
+			return nil, err
+		}
+		for _, part := range parts {
+			metadata := a.Provenance
+			if part.Total > 1 {
+				metadata = convert.WithProvenance(part.Metadata(a.DisplayName), a.Provenance)
+			}
+			p.Items = append(p.Items, &Item{
+				Path:        part.Path,
+				DisplayName: part.DisplayName,
+				MIMEType:    a.MIMEType,
+				Metadata:    metadata,
+			})
+		}
+	}
+	if opts.RecordSource {
+		p.recordSource(path)
+	}
+	return p, nil
+}
+
+// recordSource adds the source path to the metadata of every item
+func (p *Prepared) recordSource(path string) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	for _, item := range p.Items {
+		item.Metadata = convert.WithProvenance(item.Metadata, map[string]string{MetaSourcePath: abs})
+	}
+}
@@ -0,0 +1,94 @@
+package upload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mikesmitty/file-search/internal/convert"
+	"github.com/mikesmitty/file-search/internal/split"
+)
+
+func TestPreparePassthrough(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doc.txt")
+	os.WriteFile(path, []byte("hello"), 0o600)
+
+	p, err := Prepare(context.Background(), path, "doc.txt", nil)
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	defer p.Cleanup()
+
+	if len(p.Items) != 1 || p.Items[0].Path != path || p.Items[0].DisplayName != "doc.txt" {
+		t.Errorf("unexpected items: %+v", p.Items[0])
+	}
+	if _, ok := p.Items[0].Metadata[MetaSourcePath]; ok {
+		t.Errorf("expected no source path without RecordSource, got %v", p.Items[0].Metadata)
+	}
+
+	p, err = Prepare(context.Background(), path, "doc.txt", &Options{RecordSource: true})
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	defer p.Cleanup()
+	if p.Items[0].Metadata[MetaSourcePath] != path {
+		t.Errorf("expected the source path in metadata, got %v", p.Items[0].Metadata)
+	}
+}
+
+func TestPrepareConvertAndSplit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "page.html")
+	html := "<h1>One</h1><p>" + strings.Repeat("a", 50) + "</p><h1>Two</h1><p>" + strings.Repeat("b", 50) + "</p>"
+	os.WriteFile(path, []byte(html), 0o600)
+
+	p, err := Prepare(context.Background(), path, "page.html", &Options{
+		Converters:   convert.NewRegistry(),
+		Split:        &split.Options{MaxBytes: 64},
+		RecordSource: true,
+	})
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+
+	if len(p.Items) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(p.Items))
+	}
+	item := p.Items[1]
+	if item.DisplayName != "page.html (part 2 of 2)" {
+		t.Errorf("unexpected display name: %s", item.DisplayName)
+	}
+	if item.MIMEType != "text/markdown" {
+		t.Errorf("expected converted MIME type to carry through, got %s", item.MIMEType)
+	}
+	if item.Metadata[split.MetaOriginalName] != "page.html" || item.Metadata[convert.MetaConverter] != "html" {
+		t.Errorf("expected part and provenance metadata, got %v", item.Metadata)
+	}
+	if item.Metadata[MetaSourcePath] != path {
+		t.Errorf("expected parts to record the original path, got %v", item.Metadata)
+	}
+
+	p.Cleanup()
+	if _, err := os.Stat(item.Path); !os.IsNotExist(err) {
+		t.Error("expected Cleanup to remove split parts")
+	}
+}
+
+func TestPrepareSplitSkipsUnsupported(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "image.png")
+	os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n"), 0o600)
+
+	p, err := Prepare(context.Background(), path, "image.png", &Options{Split: &split.Options{MaxBytes: 1}})
+	if err != nil {
+		t.Fatalf("Prepare failed: %v", err)
+	}
+	defer p.Cleanup()
+
+	if len(p.Items) != 1 || p.Items[0].Path != path {
+		t.Errorf("expected unsplittable file to pass through, got %+v", p.Items)
+	}
+}
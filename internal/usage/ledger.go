@@ -0,0 +1,122 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/config"
+	"github.com/mikesmitty/file-search/internal/gemini"
+)
+
+// FileName is the name of the ledger file in the config directory
+const FileName = "usage.jsonl"
+
+// Record is the usage of one API request
+type Record struct {
+	Time time.Time `json:"time"`
+	// Command is the CLI command or MCP tool that made the request
+	Command string `json:"command"`
+	// Operation is gemini.UsageQuery or gemini.UsageIndex
+	Operation string `json:"operation"`
+	Model     string `json:"model,omitempty"`
+	Store     string `json:"store,omitempty"`
+	gemini.Usage
+}
+
+// Path returns the per-user ledger file
+func Path(home string) string {
+	return filepath.Join(config.Dir(home), FileName)
+}
+
+// Ledger appends usage records to a JSONL file. It is safe for concurrent
+// use, e.g. by batch queries.
+type Ledger struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLedger returns a ledger writing to path
+func NewLedger(path string) *Ledger {
+	return &Ledger{path: path}
+}
+
+// Append writes r to the ledger, creating the file if needed
+func (l *Ledger) Append(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns the records in the ledger at path made at or after since.
+// A missing ledger has no records; malformed lines are skipped.
+func Read(path string, since time.Time) ([]Record, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		if !r.Time.Before(since) {
+			records = append(records, r)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// ParseSince parses the start of a report period relative to now: a number
+// of days ("7d") or weeks ("2w"), a Go duration ("12h") or a date
+// ("2025-06-01", local time)
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil && strings.HasSuffix(s, "d") && n >= 0 {
+		return now.AddDate(0, 0, -n), nil
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(s, "w")); err == nil && strings.HasSuffix(s, "w") && n >= 0 {
+		return now.AddDate(0, 0, -7*n), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use e.g. 7d, 2w, 12h or 2025-06-01)", s)
+}
@@ -0,0 +1,178 @@
+package usage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Report groupings
+const (
+	ByStore   = "store"
+	ByModel   = "model"
+	ByCommand = "command"
+)
+
+// Rate is the price of a model in USD per million tokens
+type Rate struct {
+	Input  float64 `mapstructure:"input" json:"input"`
+	Output float64 `mapstructure:"output" json:"output"`
+}
+
+// Rates prices usage records. Models are matched by the longest configured
+// prefix of the model name, so "gemini-2.5-flash" also prices its dated
+// versions.
+type Rates struct {
+	Models map[string]Rate
+	// Indexing is the price of embedding documents in USD per million tokens
+	Indexing float64
+}
+
+// DefaultRates are list prices at the time of writing. They are estimates;
+// check current pricing and override them with usage.rates in the config.
+func DefaultRates() Rates {
+	return Rates{
+		Models: map[string]Rate{
+			"gemini-2.5-pro":        {Input: 1.25, Output: 10.00},
+			"gemini-2.5-flash":      {Input: 0.30, Output: 2.50},
+			"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40},
+		},
+		Indexing: 0.15,
+	}
+}
+
+// rate returns the rate of model and whether one is configured
+func (r Rates) rate(model string) (Rate, bool) {
+	model = strings.TrimPrefix(model, "models/")
+	var best string
+	for prefix := range r.Models {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return Rate{}, false
+	}
+	return r.Models[best], true
+}
+
+// Cost returns the estimated cost of rec in USD. Prompt and File Search
+// tokens are charged at the input rate, output and thinking tokens at the
+// output rate. The second result is false if the model has no rate.
+func (r Rates) Cost(rec Record) (float64, bool) {
+	cost := float64(rec.IndexingTokens) * r.Indexing / 1e6
+	if rec.TotalTokens == 0 && rec.PromptTokens == 0 {
+		return cost, true
+	}
+	rate, ok := r.rate(rec.Model)
+	if !ok {
+		return cost, false
+	}
+	input := float64(rec.PromptTokens) + float64(rec.ToolTokens)
+	output := float64(rec.OutputTokens) + float64(rec.ThinkingTokens)
+	return cost + (input*rate.Input+output*rate.Output)/1e6, true
+}
+
+// Row is the usage of one group in a report
+type Row struct {
+	Key            string  `json:"key"`
+	Requests       int     `json:"requests"`
+	PromptTokens   int64   `json:"prompt_tokens"`
+	OutputTokens   int64   `json:"output_tokens"`
+	ThinkingTokens int64   `json:"thinking_tokens"`
+	ToolTokens     int64   `json:"tool_tokens"`
+	IndexingTokens int64   `json:"indexing_tokens"`
+	Cost           float64 `json:"cost_usd"`
+	// Unpriced counts requests whose model has no rate, which are left out
+	// of Cost
+	Unpriced int `json:"unpriced,omitempty"`
+}
+
+// Report totals records grouped by store, model or command, most expensive
+// first
+func Report(records []Record, by string, rates Rates) ([]Row, error) {
+	var key func(Record) string
+	switch by {
+	case ByStore:
+		key = func(r Record) string { return r.Store }
+	case ByModel:
+		key = func(r Record) string { return strings.TrimPrefix(r.Model, "models/") }
+	case ByCommand:
+		key = func(r Record) string { return r.Command }
+	default:
+		return nil, fmt.Errorf("invalid grouping %q (use %s, %s or %s)", by, ByStore, ByModel, ByCommand)
+	}
+
+	groups := make(map[string]*Row)
+	for _, rec := range records {
+		k := key(rec)
+		if k == "" {
+			k = "(none)"
+		}
+		row := groups[k]
+		if row == nil {
+			row = &Row{Key: k}
+			groups[k] = row
+		}
+		row.Requests++
+		row.PromptTokens += int64(rec.PromptTokens)
+		row.OutputTokens += int64(rec.OutputTokens)
+		row.ThinkingTokens += int64(rec.ThinkingTokens)
+		row.ToolTokens += int64(rec.ToolTokens)
+		row.IndexingTokens += rec.IndexingTokens
+		cost, ok := rates.Cost(rec)
+		row.Cost += cost
+		if !ok {
+			row.Unpriced++
+		}
+	}
+
+	rows := make([]Row, 0, len(groups))
+	for _, row := range groups {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Cost != rows[j].Cost {
+			return rows[i].Cost > rows[j].Cost
+		}
+		return rows[i].Key < rows[j].Key
+	})
+	return rows, nil
+}
+
+// WriteReport writes rows as a table with a total line
+func WriteReport(w io.Writer, by string, rows []Row) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\tREQUESTS\tPROMPT\tOUTPUT\tTHINKING\tTOOL\tINDEXING\tEST. COST\t\n", strings.ToUpper(by))
+	var total Row
+	total.Key = "Total"
+	unpriced := 0
+	for _, r := range rows {
+		writeRow(tw, r)
+		total.Requests += r.Requests
+		total.PromptTokens += r.PromptTokens
+		total.OutputTokens += r.OutputTokens
+		total.ThinkingTokens += r.ThinkingTokens
+		total.ToolTokens += r.ToolTokens
+		total.IndexingTokens += r.IndexingTokens
+		total.Cost += r.Cost
+		unpriced += r.Unpriced
+	}
+	if len(rows) > 1 {
+		writeRow(tw, total)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if unpriced > 0 {
+		fmt.Fprintf(w, "\n%d request(s) used models without a rate and are not included in the cost; add them to usage.rates\n", unpriced)
+	}
+	return nil
+}
+
+func writeRow(w io.Writer, r Row) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t$%.4f\t\n",
+		r.Key, r.Requests, r.PromptTokens, r.OutputTokens, r.ThinkingTokens, r.ToolTokens, r.IndexingTokens, r.Cost)
+}
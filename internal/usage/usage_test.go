@@ -0,0 +1,169 @@
+package usage
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mikesmitty/file-search/internal/gemini"
+)
+
+func TestLedger_AppendRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", FileName)
+	l := NewLedger(path)
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Append(Record{Time: now, Command: "file-search query", Usage: gemini.Usage{PromptTokens: 1}}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := l.Append(Record{Time: now.Add(-48 * time.Hour), Command: "old"}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := Read(path, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 10 {
+		t.Fatalf("got %d records, want 10", len(records))
+	}
+	if records[0].PromptTokens != 1 || records[0].Command != "file-search query" {
+		t.Errorf("unexpected record %+v", records[0])
+	}
+
+	records, err = Read(filepath.Join(t.TempDir(), "missing.jsonl"), time.Time{})
+	if err != nil || records != nil {
+		t.Errorf("missing ledger: got %v, %v", records, err)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "", want: time.Time{}},
+		{in: "7d", want: now.AddDate(0, 0, -7)},
+		{in: "2w", want: now.AddDate(0, 0, -14)},
+		{in: "12h", want: now.Add(-12 * time.Hour)},
+		{in: "2025-06-01", want: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{in: "yesterday", wantErr: true},
+		{in: "-3d", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSince(tt.in, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSince(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseSince(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRates_Cost(t *testing.T) {
+	rates := Rates{
+		Models: map[string]Rate{
+			"gemini-2.5-flash":      {Input: 0.30, Output: 2.50},
+			"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40},
+		},
+		Indexing: 0.15,
+	}
+	tests := []struct {
+		name   string
+		rec    Record
+		want   float64
+		priced bool
+	}{
+		{
+			name:   "input and output rates",
+			rec:    Record{Model: "models/gemini-2.5-flash", Usage: gemini.Usage{PromptTokens: 600_000, ToolTokens: 400_000, OutputTokens: 100_000, ThinkingTokens: 100_000, TotalTokens: 1_200_000}},
+			want:   0.30 + 0.50,
+			priced: true,
+		},
+		{
+			name:   "longest prefix",
+			rec:    Record{Model: "gemini-2.5-flash-lite-preview", Usage: gemini.Usage{PromptTokens: 1_000_000, TotalTokens: 1_000_000}},
+			want:   0.10,
+			priced: true,
+		},
+		{
+			name:   "indexing",
+			rec:    Record{Operation: gemini.UsageIndex, Usage: gemini.Usage{IndexingTokens: 2_000_000}},
+			want:   0.30,
+			priced: true,
+		},
+		{
+			name:   "unknown model",
+			rec:    Record{Model: "other", Usage: gemini.Usage{PromptTokens: 10, TotalTokens: 10}},
+			priced: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := rates.Cost(tt.rec)
+			if ok != tt.priced || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Cost() = %v, %v; want %v, %v", got, ok, tt.want, tt.priced)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	rates := DefaultRates()
+	records := []Record{
+		{Command: "file-search query", Model: "gemini-2.5-flash", Store: "Docs", Usage: gemini.Usage{PromptTokens: 1000, OutputTokens: 100, TotalTokens: 1100}},
+		{Command: "mcp query_knowledge_base", Model: "gemini-2.5-pro", Store: "Docs", Usage: gemini.Usage{PromptTokens: 1000, OutputTokens: 100, TotalTokens: 1100}},
+		{Command: "file-search upload", Operation: gemini.UsageIndex, Store: "Specs", Usage: gemini.Usage{IndexingTokens: 5000}},
+		{Command: "file-search query", Model: "mystery", Usage: gemini.Usage{PromptTokens: 5, TotalTokens: 5}},
+	}
+
+	rows, err := Report(records, ByStore, rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0].Key != "Docs" || rows[0].Requests != 2 || rows[0].PromptTokens != 2000 {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+	if rows[2].Key != "(none)" || rows[2].Unpriced != 1 {
+		t.Errorf("unexpected unpriced row %+v", rows[2])
+	}
+
+	rows, err = Report(records, ByModel, rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].Key != "gemini-2.5-pro" {
+		t.Errorf("most expensive model = %q, want gemini-2.5-pro", rows[0].Key)
+	}
+
+	if _, err := Report(records, "day", rates); err == nil {
+		t.Error("expected error for invalid grouping")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, ByModel, rows); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"MODEL", "gemini-2.5-pro", "Total", "1 request(s) used models without a rate"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+}